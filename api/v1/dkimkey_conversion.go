@@ -17,27 +17,106 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/conversion"
 
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+const (
+	// ed25519SelectorAnnotation preserves the v2-only ed25519Selector field when converting to v1.
+	ed25519SelectorAnnotation = "dkim-manager.atelierhsn.com/ed25519-selector"
+	// dnsEndpointAnnotation preserves the v2-only dnsEndpoint field, as JSON, when converting to v1.
+	dnsEndpointAnnotation = "dkim-manager.atelierhsn.com/dns-endpoint"
+	// tagsAnnotation preserves the v2-only tags field, as JSON, when converting to v1.
+	tagsAnnotation = "dkim-manager.atelierhsn.com/tags"
+	// delegationAnnotation preserves the v2-only delegation field, as JSON, when converting to v1.
+	delegationAnnotation = "dkim-manager.atelierhsn.com/delegation"
+	// purposeAnnotation preserves the v2-only purpose field of ARC keys when converting to v1.
+	purposeAnnotation = "dkim-manager.atelierhsn.com/purpose"
+	// revokedAnnotation preserves the v2-only revoked field when converting to v1.
+	revokedAnnotation = "dkim-manager.atelierhsn.com/revoked"
+	// deletionPolicyAnnotation preserves the v2-only deletionPolicy field when converting to v1.
+	deletionPolicyAnnotation = "dkim-manager.atelierhsn.com/deletion-policy"
 )
 
+// unmarshalAnnotation decodes the JSON annotation key into v, if present.
+func unmarshalAnnotation(annotations map[string]string, key string, v any) error {
+	data, ok := annotations[key]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", key, err)
+	}
+	return nil
+}
+
+// withoutAnnotations returns annotations without the given keys, copying it only if needed.
+func withoutAnnotations(annotations map[string]string, keys ...string) map[string]string {
+	found := false
+	for _, key := range keys {
+		if _, ok := annotations[key]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return annotations
+	}
+	res := maps.Clone(annotations)
+	for _, key := range keys {
+		delete(res, key)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
 // ConvertTo converts this DKIMKey (v1) to the Hub version (v2).
 func (src *DKIMKey) ConvertTo(dstRaw conversion.Hub) error {
 	dst := dstRaw.(*dkimmanagerv2.DKIMKey)
 
 	// ObjectMeta
 	dst.ObjectMeta = src.ObjectMeta
+	ed25519Selector := src.Annotations[ed25519SelectorAnnotation]
+	purpose := dkim.KeyPurpose(src.Annotations[purposeAnnotation])
+	revoked := src.Annotations[revokedAnnotation] == "true"
+	deletionPolicy := dkimmanagerv2.DeletionPolicy(src.Annotations[deletionPolicyAnnotation])
+	var dnsEndpoint *dkimmanagerv2.DNSEndpointOptions
+	if err := unmarshalAnnotation(src.Annotations, dnsEndpointAnnotation, &dnsEndpoint); err != nil {
+		return err
+	}
+	var tags *dkimmanagerv2.DKIMRecordTags
+	if err := unmarshalAnnotation(src.Annotations, tagsAnnotation, &tags); err != nil {
+		return err
+	}
+	var delegation *dkimmanagerv2.DKIMDelegation
+	if err := unmarshalAnnotation(src.Annotations, delegationAnnotation, &delegation); err != nil {
+		return err
+	}
+	dst.Annotations = withoutAnnotations(src.Annotations, ed25519SelectorAnnotation, dnsEndpointAnnotation, tagsAnnotation, delegationAnnotation, purposeAnnotation, revokedAnnotation, deletionPolicyAnnotation)
 
 	// Spec
 	dst.Spec = dkimmanagerv2.DKIMKeySpec{
-		SecretName: src.Spec.SecretName,
-		Selector:   src.Spec.Selector,
-		Domain:     src.Spec.Domain,
-		TTL:        src.Spec.TTL,
-		KeyLength:  src.Spec.KeyLength,
-		KeyType:    src.Spec.KeyType,
+		SecretName:      src.Spec.SecretName,
+		Selector:        src.Spec.Selector,
+		Domain:          src.Spec.Domain,
+		TTL:             src.Spec.TTL,
+		KeyLength:       src.Spec.KeyLength,
+		KeyType:         src.Spec.KeyType,
+		Purpose:         purpose,
+		ED25519Selector: ed25519Selector,
+		DNSEndpoint:     dnsEndpoint,
+		Tags:            tags,
+		Delegation:      delegation,
+		Revoked:         revoked,
+		DeletionPolicy:  deletionPolicy,
 	}
 
 	// Status: convert string -> conditions
@@ -84,6 +163,47 @@ func (dst *DKIMKey) ConvertFrom(srcRaw conversion.Hub) error {
 
 	// ObjectMeta
 	dst.ObjectMeta = src.ObjectMeta
+	preserved := map[string]string{}
+	if src.Spec.ED25519Selector != "" {
+		preserved[ed25519SelectorAnnotation] = src.Spec.ED25519Selector
+	}
+	if src.IsARC() {
+		preserved[purposeAnnotation] = string(src.Spec.Purpose)
+	}
+	if src.Spec.Revoked {
+		preserved[revokedAnnotation] = "true"
+	}
+	if src.Spec.DeletionPolicy != "" && src.Spec.DeletionPolicy != dkimmanagerv2.DeletionPolicyDelete {
+		preserved[deletionPolicyAnnotation] = string(src.Spec.DeletionPolicy)
+	}
+	if src.Spec.DNSEndpoint != nil {
+		data, err := json.Marshal(src.Spec.DNSEndpoint)
+		if err != nil {
+			return err
+		}
+		preserved[dnsEndpointAnnotation] = string(data)
+	}
+	if src.Spec.Tags != nil {
+		data, err := json.Marshal(src.Spec.Tags)
+		if err != nil {
+			return err
+		}
+		preserved[tagsAnnotation] = string(data)
+	}
+	if src.Spec.Delegation != nil {
+		data, err := json.Marshal(src.Spec.Delegation)
+		if err != nil {
+			return err
+		}
+		preserved[delegationAnnotation] = string(data)
+	}
+	if len(preserved) > 0 {
+		dst.Annotations = maps.Clone(src.Annotations)
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		maps.Copy(dst.Annotations, preserved)
+	}
 
 	// Spec
 	dst.Spec = DKIMKeySpec{
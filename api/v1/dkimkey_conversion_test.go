@@ -231,3 +231,183 @@ func TestRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestRoundTripED25519Selector(t *testing.T) {
+	t.Parallel()
+	original := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-key",
+			Namespace:   "default",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName:      "my-secret",
+			Selector:        "selector1",
+			Domain:          "example.com",
+			TTL:             3600,
+			KeyLength:       dkim.KeyLength2048,
+			KeyType:         dkim.KeyTypeRSA,
+			ED25519Selector: "selector1-ed25519",
+		},
+	}
+
+	spoke := &DKIMKey{}
+	err := spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Equal(t, "selector1-ed25519", spoke.Annotations[ed25519SelectorAnnotation])
+	assert.NotContains(t, original.Annotations, ed25519SelectorAnnotation)
+
+	hub := &dkimmanagerv2.DKIMKey{}
+	err = spoke.ConvertTo(hub)
+	require.NoError(t, err)
+	assert.Equal(t, original.Spec, hub.Spec)
+	assert.Equal(t, original.Annotations, hub.Annotations)
+}
+
+func TestRoundTripPurpose(t *testing.T) {
+	t.Parallel()
+	original := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-key",
+			Namespace: "default",
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: "my-secret",
+			Selector:   "arc1",
+			Domain:     "example.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			Purpose:    dkim.KeyPurposeARC,
+		},
+	}
+
+	spoke := &DKIMKey{}
+	err := spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Equal(t, "arc", spoke.Annotations[purposeAnnotation])
+
+	hub := &dkimmanagerv2.DKIMKey{}
+	err = spoke.ConvertTo(hub)
+	require.NoError(t, err)
+	assert.Equal(t, original.Spec, hub.Spec)
+	assert.Nil(t, hub.Annotations)
+}
+
+func TestRoundTripRevoked(t *testing.T) {
+	t.Parallel()
+	original := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-key",
+			Namespace: "default",
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: "my-secret",
+			Selector:   "selector1",
+			Domain:     "example.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			Revoked:    true,
+		},
+	}
+
+	spoke := &DKIMKey{}
+	err := spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Equal(t, "true", spoke.Annotations[revokedAnnotation])
+
+	hub := &dkimmanagerv2.DKIMKey{}
+	err = spoke.ConvertTo(hub)
+	require.NoError(t, err)
+	assert.Equal(t, original.Spec, hub.Spec)
+	assert.Nil(t, hub.Annotations)
+}
+
+func TestRoundTripDeletionPolicy(t *testing.T) {
+	t.Parallel()
+	original := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-key",
+			Namespace: "default",
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName:     "my-secret",
+			Selector:       "selector1",
+			Domain:         "example.com",
+			TTL:            3600,
+			KeyLength:      dkim.KeyLength2048,
+			KeyType:        dkim.KeyTypeRSA,
+			DeletionPolicy: dkimmanagerv2.DeletionPolicyRetain,
+		},
+	}
+
+	spoke := &DKIMKey{}
+	err := spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Equal(t, "Retain", spoke.Annotations[deletionPolicyAnnotation])
+
+	hub := &dkimmanagerv2.DKIMKey{}
+	err = spoke.ConvertTo(hub)
+	require.NoError(t, err)
+	assert.Equal(t, original.Spec, hub.Spec)
+	assert.Nil(t, hub.Annotations)
+
+	original.Spec.DeletionPolicy = dkimmanagerv2.DeletionPolicyDelete
+	spoke = &DKIMKey{}
+	err = spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Nil(t, spoke.Annotations, "the default policy must not be preserved")
+}
+
+func TestRoundTripJSONAnnotations(t *testing.T) {
+	t.Parallel()
+	original := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-key",
+			Namespace: "default",
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: "my-secret",
+			Selector:   "selector1",
+			Domain:     "example.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			DNSEndpoint: &dkimmanagerv2.DNSEndpointOptions{
+				Labels:        map[string]string{"dns": "internal"},
+				Annotations:   map[string]string{"external-dns.alpha.kubernetes.io/class": "internal"},
+				SetIdentifier: "primary",
+				ProviderSpecific: []dkimmanagerv2.ProviderSpecificProperty{
+					{Name: "aws/weight", Value: "100"},
+				},
+			},
+			Tags: &dkimmanagerv2.DKIMRecordTags{
+				Testing:        true,
+				ServiceTypes:   []string{"email"},
+				HashAlgorithms: []string{"sha256"},
+				Notes:          "onboarding",
+			},
+			Delegation: &dkimmanagerv2.DKIMDelegation{
+				Zone: "dkim.example.net",
+			},
+		},
+	}
+
+	spoke := &DKIMKey{}
+	err := spoke.ConvertFrom(original)
+	require.NoError(t, err)
+	assert.Contains(t, spoke.Annotations, dnsEndpointAnnotation)
+	assert.Contains(t, spoke.Annotations, tagsAnnotation)
+	assert.Contains(t, spoke.Annotations, delegationAnnotation)
+	assert.Nil(t, original.Annotations)
+
+	hub := &dkimmanagerv2.DKIMKey{}
+	err = spoke.ConvertTo(hub)
+	require.NoError(t, err)
+	assert.Equal(t, original.Spec, hub.Spec)
+	assert.Nil(t, hub.Annotations)
+
+	spoke.Annotations[tagsAnnotation] = "{"
+	assert.Error(t, spoke.ConvertTo(&dkimmanagerv2.DKIMKey{}))
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hsn723/dkim-manager/pkg/bimi"
+)
+
+// ConfigMapKeyReference references a key of a ConfigMap in the same namespace.
+type ConfigMapKeyReference struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Key of the ConfigMap entry.
+	Key string `json:"key"`
+}
+
+// BIMIRecordSpec defines the desired BIMI record of a domain.
+type BIMIRecordSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain is immutable"
+
+	// Domain is the domain to which the BIMI record will be associated.
+	Domain string `json:"domain"`
+
+	// +kubebuilder:default=3600
+
+	// TTL for the BIMI record.
+	TTL uint `json:"ttl,omitempty"`
+
+	// LogoURL is the https URL of the SVG Tiny PS logo (l=).
+	LogoURL string `json:"logoURL"`
+
+	// AuthorityURL is the https URL of the PEM-encoded Verified Mark Certificate (a=).
+	// +optional
+	AuthorityURL string `json:"authorityURL,omitempty"`
+
+	// Logo references the ConfigMap entry holding the logo served at LogoURL,
+	// which is validated against the SVG Tiny PS profile before the record is published.
+	Logo ConfigMapKeyReference `json:"logo"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the BIMI record,
+	// e.g. to route it to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+}
+
+// BIMIRecordStatus defines the observed state of BIMIRecord.
+type BIMIRecordStatus struct {
+	// ObservedGeneration is the last observed generation of the BIMIRecord.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the BIMIRecord's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// BIMIRecord is the Schema for the bimirecords API.
+type BIMIRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BIMIRecordSpec   `json:"spec"`
+	Status BIMIRecordStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the DNS name under which the BIMI record is published.
+func (r *BIMIRecord) RecordName() string {
+	return bimi.RecordName(r.Spec.Domain)
+}
+
+// RecordSetName returns the name of the DNSEndpoint holding the BIMI record,
+// which must differ from the one of a DKIMKey with the same name.
+func (r *BIMIRecord) RecordSetName() string {
+	return r.Name + "-bimi"
+}
+
+// Record returns the BIMI record described by the BIMIRecord.
+func (r *BIMIRecord) Record() bimi.Record {
+	return bimi.Record{
+		LogoURL:      r.Spec.LogoURL,
+		AuthorityURL: r.Spec.AuthorityURL,
+	}
+}
+
+// IsReady returns true if the BIMIRecord has a Ready condition with status True.
+func (r *BIMIRecord) IsReady() bool {
+	for _, c := range r.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain returns the domain of the BIMIRecord.
+func (r *BIMIRecord) GetDomain() string {
+	return r.Spec.Domain
+}
+
+// GetTTL returns the TTL of the BIMI record.
+func (r *BIMIRecord) GetTTL() uint {
+	return r.Spec.TTL
+}
+
+// GetDNSEndpoint returns the options of the DNSEndpoint holding the BIMI record.
+func (r *BIMIRecord) GetDNSEndpoint() *DNSEndpointOptions {
+	return r.Spec.DNSEndpoint
+}
+
+// RecordValue returns the TXT value of the BIMI record.
+func (r *BIMIRecord) RecordValue() string {
+	return r.Record().GenTXTValue()
+}
+
+// GetConditions returns the conditions of the BIMIRecord.
+func (r *BIMIRecord) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the conditions of the BIMIRecord.
+func (r *BIMIRecord) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+// SetObservedGeneration sets the last observed generation of the BIMIRecord.
+func (r *BIMIRecord) SetObservedGeneration(generation int64) {
+	r.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// BIMIRecordList contains a list of BIMIRecord.
+type BIMIRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BIMIRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BIMIRecord{}, &BIMIRecordList{})
+}
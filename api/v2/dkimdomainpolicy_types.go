@@ -0,0 +1,105 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DKIMDomainPolicySpec defines which namespaces may create DKIMKeys for which domains.
+type DKIMDomainPolicySpec struct {
+	// Namespaces is a list of namespace names to which this policy applies.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces to which this policy applies by label.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// +kubebuilder:validation:MinItems=1
+
+	// Domains is the list of domains for which matching namespaces may create DKIMKeys.
+	Domains []string `json:"domains"`
+
+	// AllowSubdomains additionally allows any subdomain of the listed domains.
+	// +optional
+	AllowSubdomains bool `json:"allowSubdomains,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Domains",type="string",JSONPath=".spec.domains"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DKIMDomainPolicy is the Schema for the dkimdomainpolicies API.
+type DKIMDomainPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DKIMDomainPolicySpec `json:"spec"`
+}
+
+// MatchesNamespace returns true if the policy applies to a namespace with the given name and labels.
+func (p *DKIMDomainPolicy) MatchesNamespace(name string, nsLabels map[string]string) (bool, error) {
+	if slices.Contains(p.Spec.Namespaces, name) {
+		return true, nil
+	}
+	if p.Spec.NamespaceSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(nsLabels)), nil
+}
+
+// AllowsDomain returns true if the policy allows DKIMKeys for the given domain.
+func (p *DKIMDomainPolicy) AllowsDomain(domain string) bool {
+	domain = normalizeDomain(domain)
+	for _, d := range p.Spec.Domains {
+		d = normalizeDomain(d)
+		if domain == d {
+			return true
+		}
+		if p.Spec.AllowSubdomains && strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+//+kubebuilder:object:root=true
+
+// DKIMDomainPolicyList contains a list of DKIMDomainPolicy.
+type DKIMDomainPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DKIMDomainPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DKIMDomainPolicy{}, &DKIMDomainPolicyList{})
+}
@@ -24,10 +24,14 @@ import (
 // DKIMKeySpec defines the desired state of DKIMKey.
 type DKIMKeySpec struct {
 	// SecretName represents the name for the Secret resource containing the private key.
-	SecretName string `json:"secretName"`
+	// When omitted, it is generated by the mutating webhook from the configured template.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
 
 	// Selector is the name to use as a DKIM selector.
-	Selector string `json:"selector"`
+	// When omitted, it is generated by the mutating webhook from the configured template.
+	// +optional
+	Selector string `json:"selector,omitempty"`
 
 	// Domain is the domain to which the DKIM record will be associated.
 	Domain string `json:"domain"`
@@ -48,6 +52,168 @@ type DKIMKeySpec struct {
 
 	// KeyType represents the DKIM key type.
 	KeyType dkim.KeyType `json:"keyType,omitempty"`
+
+	// +kubebuilder:validation:Enum=dkim;arc
+	// +kubebuilder:default=dkim
+
+	// Purpose is what the key is used for. ARC keys (RFC 8617) are published like DKIM keys,
+	// but are used by forwarders to seal messages and follow their own selector naming and rotation.
+	Purpose dkim.KeyPurpose `json:"purpose,omitempty"`
+
+	// ED25519Selector, when set, publishes an additional Ed25519 key under this selector
+	// alongside the RSA key, so that messages can be dual-signed as recommended by RFC 8463.
+	// Only valid with the rsa key type.
+	// +optional
+	ED25519Selector string `json:"ed25519Selector,omitempty"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the DKIM records,
+	// e.g. to route them to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+
+	// Tags are optional tags added to the DKIM records.
+	// +optional
+	Tags *DKIMRecordTags `json:"tags,omitempty"`
+
+	// Delegation, when set, publishes the DKIM records under a zone managed by dkim-manager,
+	// and a CNAME record pointing to them under the domain. The owner of the domain only
+	// needs to create the CNAME records once, and keys can then be rotated without their intervention.
+	// +optional
+	Delegation *DKIMDelegation `json:"delegation,omitempty"`
+
+	// Revoked, when set, publishes the DKIM records with an empty public key as defined
+	// in RFC 6376 section 3.6.1, and deletes the private keys. A revoked key cannot be restored.
+	// +optional
+	Revoked bool `json:"revoked,omitempty"`
+
+	// +kubebuilder:validation:Enum=Retain;Delete;Revoke
+	// +kubebuilder:default=Delete
+
+	// DeletionPolicy is what happens to the private keys and DKIM records when the DKIMKey is deleted.
+	// Retain keeps the Secret and the DKIM records, so that mail signing keeps working and the keys are
+	// adopted again if the DKIMKey is recreated. Delete deletes them. Revoke publishes the DKIM records
+	// with an empty public key, which then stay published, and deletes the private keys.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DeletionPolicy is what happens to the private keys and DKIM records of a deleted DKIMKey.
+type DeletionPolicy string
+
+const (
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	DeletionPolicyRevoke DeletionPolicy = "Revoke"
+)
+
+// AdoptSecretAnnotation opts an existing Secret holding private keys in to being adopted by a DKIMKey
+// referencing it, when set to "true". The adopted Secret is then owned by the DKIMKey.
+const AdoptSecretAnnotation = "dkim-manager.atelierhsn.com/adopt"
+
+// DKIMDelegation configures the delegation of DKIM records to another zone through CNAME records.
+type DKIMDelegation struct {
+	// Zone is the zone under which the DKIM records are published,
+	// as <selector>._domainkey.<domain>.<zone>.
+	Zone string `json:"zone"`
+}
+
+// DKIMRecordTags are optional tags of DKIM records, as defined in RFC 6376 section 3.6.1.
+type DKIMRecordTags struct {
+	// Testing marks the domain as testing DKIM (t=y), so that verifiers treat signature failures leniently.
+	// +optional
+	Testing bool `json:"testing,omitempty"`
+
+	// Strict forbids using the key for subdomains of the signing domain (t=s).
+	// +optional
+	Strict bool `json:"strict,omitempty"`
+
+	// +kubebuilder:validation:items:Enum=email;*
+
+	// ServiceTypes are the service types the key applies to (s=).
+	// +optional
+	ServiceTypes []string `json:"serviceTypes,omitempty"`
+
+	// Notes are human-readable notes (n=).
+	// +optional
+	Notes string `json:"notes,omitempty"`
+
+	// +kubebuilder:validation:items:Enum=sha1;sha256
+
+	// HashAlgorithms are the acceptable hash algorithms (h=). Defaults to sha256 for RSA keys.
+	// +optional
+	HashAlgorithms []string `json:"hashAlgorithms,omitempty"`
+}
+
+// DNSEndpointOptions customizes the external-dns DNSEndpoint created for a DKIMKey.
+type DNSEndpointOptions struct {
+	// Labels are added to the DNSEndpoint, e.g. to match the --label-filter of an external-dns instance.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the DNSEndpoint, e.g. to match the --annotation-filter of an external-dns instance.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// SetIdentifier is set on every endpoint, for providers supporting routing policies.
+	// +optional
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+
+	// ProviderSpecific properties are set on every endpoint.
+	// +optional
+	ProviderSpecific []ProviderSpecificProperty `json:"providerSpecific,omitempty"`
+}
+
+// ProviderSpecificProperty is a provider-specific endpoint property understood by external-dns.
+type ProviderSpecificProperty struct {
+	// Name of the property.
+	Name string `json:"name"`
+
+	// Value of the property.
+	Value string `json:"value"`
+}
+
+// RecordTags returns the optional tags of the DKIM records.
+func (s DKIMKeySpec) RecordTags() dkim.Tags {
+	if s.Tags == nil {
+		return dkim.Tags{}
+	}
+	return dkim.Tags{
+		HashAlgorithms: s.Tags.HashAlgorithms,
+		ServiceTypes:   s.Tags.ServiceTypes,
+		Testing:        s.Tags.Testing,
+		Strict:         s.Tags.Strict,
+		Notes:          s.Tags.Notes,
+	}
+}
+
+// +kubebuilder:object:generate=false
+
+// DKIMKeyEntry describes a single key pair managed by a DKIMKey.
+type DKIMKeyEntry struct {
+	Selector  string
+	Domain    string
+	KeyType   dkim.KeyType
+	KeyLength dkim.KeyLength
+	// DelegationZone is the zone the DKIM record is delegated to, if any.
+	DelegationZone string
+}
+
+// RecordName returns the DNS name under which the key is published.
+func (e DKIMKeyEntry) RecordName() string {
+	return dkim.RecordName(e.Selector, e.Domain)
+}
+
+// TXTRecordName returns the DNS name of the TXT record holding the public key.
+// It differs from RecordName when the record is delegated, RecordName then being a CNAME record.
+func (e DKIMKeyEntry) TXTRecordName() string {
+	if e.DelegationZone == "" {
+		return e.RecordName()
+	}
+	return dkim.DelegatedRecordName(e.Selector, e.Domain, e.DelegationZone)
+}
+
+// PrivateKeyFilename returns the name of the Secret entry containing the private key.
+func (e DKIMKeyEntry) PrivateKeyFilename() string {
+	return dkim.PrivateKeyFilename(e.Selector, e.Domain)
 }
 
 // DKIMKeyStatus defines the observed state of DKIMKey.
@@ -65,18 +231,29 @@ type DKIMKeyStatus struct {
 const (
 	// ConditionReady indicates the DKIMKey has been successfully reconciled.
 	ConditionReady string = "Ready"
+	// ConditionPolicyCompliant indicates whether the DKIMKey complies with the key policy.
+	ConditionPolicyCompliant string = "PolicyCompliant"
+	// ConditionDNSPublished indicates whether the configured nameservers serve the DKIM records.
+	ConditionDNSPublished string = "DNSPublished"
 )
 
 // Condition reasons for DKIMKey.
 const (
-	ReasonSucceeded string = "Succeeded"
-	ReasonFailed    string = "Failed"
-	ReasonInvalid   string = "Invalid"
+	ReasonSucceeded          string = "Succeeded"
+	ReasonFailed             string = "Failed"
+	ReasonInvalid            string = "Invalid"
+	ReasonCompliant          string = "Compliant"
+	ReasonKeyPolicyViolation string = "KeyPolicyViolation"
+	ReasonRotationRequired   string = "RotationRequired"
+	ReasonPublished          string = "Published"
+	ReasonNotPublished       string = "NotPublished"
+	ReasonRevoked            string = "Revoked"
 )
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Purpose",type="string",JSONPath=".spec.purpose"
 //+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -99,6 +276,64 @@ func (d *DKIMKey) IsReady() bool {
 	return false
 }
 
+// IsARC returns true if the DKIMKey holds ARC sealing keys rather than DKIM signing keys.
+func (d *DKIMKey) IsARC() bool {
+	return d.Spec.Purpose == dkim.KeyPurposeARC
+}
+
+// RecordName returns the DNS name under which the DKIM record is published.
+func (d *DKIMKey) RecordName() string {
+	return dkim.RecordName(d.Spec.Selector, d.Spec.Domain)
+}
+
+// Keys returns the key pairs managed by the DKIMKey, starting with the primary key.
+func (d *DKIMKey) Keys() []DKIMKeyEntry {
+	keys := []DKIMKeyEntry{
+		{
+			Selector:  d.Spec.Selector,
+			Domain:    d.Spec.Domain,
+			KeyType:   d.Spec.KeyType,
+			KeyLength: d.Spec.KeyLength,
+		},
+	}
+	if d.Spec.ED25519Selector != "" {
+		keys = append(keys, DKIMKeyEntry{
+			Selector: d.Spec.ED25519Selector,
+			Domain:   d.Spec.Domain,
+			KeyType:  dkim.KeyTypeED25519,
+		})
+	}
+	if d.Spec.Delegation != nil {
+		for i := range keys {
+			keys[i].DelegationZone = d.Spec.Delegation.Zone
+		}
+	}
+	return keys
+}
+
+// RecordValue returns the value of the TXT record publishing the public key pub of k,
+// or the record of a revoked key, with an empty public key, if the DKIMKey is revoked.
+func (d *DKIMKey) RecordValue(k DKIMKeyEntry, pub string) string {
+	if d.Spec.Revoked {
+		return dkim.GenRevokedTXTValue(k.KeyType, d.Spec.RecordTags())
+	}
+	return dkim.GenTXTValueWithTags(pub, k.KeyType, d.Spec.RecordTags())
+}
+
+// RecordNames returns the DNS names under which the DKIM records of all keys are published,
+// including the names of the delegated TXT records.
+func (d *DKIMKey) RecordNames() []string {
+	keys := d.Keys()
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.RecordName())
+		if k.DelegationZone != "" {
+			names = append(names, k.TXTRecordName())
+		}
+	}
+	return names
+}
+
 // Hub marks this type as a conversion hub.
 func (*DKIMKey) Hub() {}
 
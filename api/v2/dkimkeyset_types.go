@@ -0,0 +1,224 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// DKIMKeySetSpec defines the DKIMKeys provisioned by a DKIMKeySet.
+type DKIMKeySetSpec struct {
+	// Domains are the domains a DKIMKey is provisioned for.
+	// +optional
+	Domains []string `json:"domains,omitempty"`
+
+	// DomainsFrom selects ConfigMaps listing additional domains.
+	// +optional
+	DomainsFrom *DomainsFromConfigMaps `json:"domainsFrom,omitempty"`
+
+	// Template describes the DKIMKey provisioned for each domain.
+	Template DKIMKeyTemplate `json:"template"`
+}
+
+// DomainsFromConfigMaps selects ConfigMaps, in the namespace of the DKIMKeySet, listing domains.
+type DomainsFromConfigMaps struct {
+	// Selector selects the ConfigMaps by label.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// +kubebuilder:default=domains
+
+	// Key is the ConfigMap entry listing the domains, one per line.
+	// Empty lines and lines starting with # are ignored.
+	Key string `json:"key,omitempty"`
+}
+
+// DKIMKeyTemplate describes the DKIMKeys provisioned by a DKIMKeySet.
+type DKIMKeyTemplate struct {
+	// Labels are added to the DKIMKeys.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the DKIMKeys.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Spec is the spec of the DKIMKeys, the domain and secret name being set for each domain.
+	// +optional
+	Spec DKIMKeyTemplateSpec `json:"spec,omitempty"`
+}
+
+// DKIMKeyTemplateSpec is the part of a DKIMKeySpec shared by the DKIMKeys of a DKIMKeySet.
+type DKIMKeyTemplateSpec struct {
+	// Selector is the name to use as a DKIM selector.
+	// When omitted, it is generated by the mutating webhook from the configured template.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// +kubebuilder:default=86400
+
+	// TTL for the DKIM records.
+	TTL uint `json:"ttl,omitempty"`
+
+	// +kubebuilder:validation:Enum=1024;2048;4096
+	// +kubebuilder:default=2048
+
+	// KeyLength represents the bit size for RSA keys.
+	KeyLength dkim.KeyLength `json:"keyLength,omitempty"`
+
+	// +kubebuilder:validation:Enum=rsa;ed25519
+	// +kubebuilder:default=rsa
+
+	// KeyType represents the DKIM key type.
+	KeyType dkim.KeyType `json:"keyType,omitempty"`
+
+	// +kubebuilder:validation:Enum=dkim;arc
+	// +kubebuilder:default=dkim
+
+	// Purpose is what the keys are used for.
+	Purpose dkim.KeyPurpose `json:"purpose,omitempty"`
+
+	// ED25519Selector, when set, publishes an additional Ed25519 key under this selector
+	// alongside the RSA key. Only valid with the rsa key type.
+	// +optional
+	ED25519Selector string `json:"ed25519Selector,omitempty"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoints created for the DKIM records.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+
+	// Tags are optional tags added to the DKIM records.
+	// +optional
+	Tags *DKIMRecordTags `json:"tags,omitempty"`
+
+	// Delegation, when set, publishes the DKIM records under a zone managed by dkim-manager,
+	// and CNAME records pointing to them under the domains.
+	// +optional
+	Delegation *DKIMDelegation `json:"delegation,omitempty"`
+
+	// +kubebuilder:validation:Enum=Retain;Delete;Revoke
+	// +kubebuilder:default=Delete
+
+	// DeletionPolicy is what happens to the private keys and DKIM records when a DKIMKey is deleted.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// DKIMKeySetStatus defines the observed state of DKIMKeySet.
+type DKIMKeySetStatus struct {
+	// ObservedGeneration is the last observed generation of the DKIMKeySet.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Domains is the number of domains of the DKIMKeySet.
+	// +optional
+	Domains int32 `json:"domains,omitempty"`
+
+	// ReadyKeys is the number of ready DKIMKeys provisioned by the DKIMKeySet.
+	// +optional
+	ReadyKeys int32 `json:"readyKeys,omitempty"`
+
+	// Conditions represent the latest available observations of the DKIMKeySet's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Condition reasons for DKIMKeySet.
+const (
+	// ReasonProgressing indicates that some DKIMKeys of the DKIMKeySet are not ready yet.
+	ReasonProgressing string = "Progressing"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domains",type="integer",JSONPath=".status.domains"
+//+kubebuilder:printcolumn:name="Ready Keys",type="integer",JSONPath=".status.readyKeys"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DKIMKeySet is the Schema for the dkimkeysets API.
+type DKIMKeySet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DKIMKeySetSpec   `json:"spec"`
+	Status DKIMKeySetStatus `json:"status,omitempty"`
+}
+
+// IsReady returns true if the DKIMKeySet has a Ready condition with status True.
+func (s *DKIMKeySet) IsReady() bool {
+	for _, c := range s.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyName returns the name of the DKIMKey, and of its Secret, provisioned for the domain.
+// Names that would be too long are shortened with a hash of the domain, truncating the name
+// of the DKIMKeySet if needed.
+func (s *DKIMKeySet) KeyName(domain string) string {
+	name := s.Name + "-" + domain
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(domain))
+	suffix := "-" + hex.EncodeToString(sum[:8])
+	prefix := s.Name
+	if len(prefix)+len(suffix) > validation.DNS1123SubdomainMaxLength {
+		prefix = strings.TrimRight(prefix[:validation.DNS1123SubdomainMaxLength-len(suffix)], "-.")
+	}
+	return prefix + suffix
+}
+
+// KeySpec returns the spec of the DKIMKey provisioned for the domain.
+func (s *DKIMKeySet) KeySpec(domain string) DKIMKeySpec {
+	t := s.Spec.Template.Spec
+	return DKIMKeySpec{
+		SecretName:      s.KeyName(domain),
+		Selector:        t.Selector,
+		Domain:          domain,
+		TTL:             t.TTL,
+		KeyLength:       t.KeyLength,
+		KeyType:         t.KeyType,
+		Purpose:         t.Purpose,
+		ED25519Selector: t.ED25519Selector,
+		DNSEndpoint:     t.DNSEndpoint.DeepCopy(),
+		Tags:            t.Tags.DeepCopy(),
+		Delegation:      t.Delegation.DeepCopy(),
+		DeletionPolicy:  t.DeletionPolicy,
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// DKIMKeySetList contains a list of DKIMKeySet.
+type DKIMKeySetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DKIMKeySet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DKIMKeySet{}, &DKIMKeySetList{})
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hsn723/dkim-manager/pkg/dmarc"
+)
+
+// DMARCPolicySpec defines the desired DMARC record of a domain.
+type DMARCPolicySpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain is immutable"
+
+	// Domain is the domain to which the DMARC record will be associated.
+	Domain string `json:"domain"`
+
+	// +kubebuilder:default=86400
+
+	// TTL for the DMARC record.
+	TTL uint `json:"ttl,omitempty"`
+
+	// +kubebuilder:validation:Enum=none;quarantine;reject
+
+	// Policy is the action requested from receivers for messages failing DMARC checks (p=).
+	Policy dmarc.Policy `json:"policy"`
+
+	// +kubebuilder:validation:Enum=none;quarantine;reject
+
+	// SubdomainPolicy is the policy for subdomains of the domain (sp=). Defaults to policy.
+	// +optional
+	SubdomainPolicy dmarc.Policy `json:"subdomainPolicy,omitempty"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+
+	// Percentage is the percentage of failing messages the policy applies to (pct=). Defaults to 100.
+	// +optional
+	Percentage *int32 `json:"percentage,omitempty"`
+
+	// AggregateReportURIs are the mailto URIs aggregate reports are sent to (rua=).
+	// +optional
+	AggregateReportURIs []string `json:"aggregateReportURIs,omitempty"`
+
+	// FailureReportURIs are the mailto URIs failure reports are sent to (ruf=).
+	// +optional
+	FailureReportURIs []string `json:"failureReportURIs,omitempty"`
+
+	// +kubebuilder:validation:Enum=relaxed;strict
+
+	// DKIMAlignment is the DKIM identifier alignment mode (adkim=). Defaults to relaxed.
+	// +optional
+	DKIMAlignment dmarc.Alignment `json:"dkimAlignment,omitempty"`
+
+	// +kubebuilder:validation:Enum=relaxed;strict
+
+	// SPFAlignment is the SPF identifier alignment mode (aspf=). Defaults to relaxed.
+	// +optional
+	SPFAlignment dmarc.Alignment `json:"spfAlignment,omitempty"`
+
+	// +kubebuilder:validation:items:Enum="0";"1";d;s
+
+	// FailureReportingOptions select the failures that trigger failure reports (fo=). Defaults to 0.
+	// +optional
+	FailureReportingOptions []string `json:"failureReportingOptions,omitempty"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the DMARC record,
+	// e.g. to route it to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+}
+
+// DMARCPolicyStatus defines the observed state of DMARCPolicy.
+type DMARCPolicyStatus struct {
+	// ObservedGeneration is the last observed generation of the DMARCPolicy.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the DMARCPolicy's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Policy",type="string",JSONPath=".spec.policy"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// DMARCPolicy is the Schema for the dmarcpolicies API.
+type DMARCPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DMARCPolicySpec   `json:"spec"`
+	Status DMARCPolicyStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the DNS name under which the DMARC record is published.
+func (p *DMARCPolicy) RecordName() string {
+	return dmarc.RecordName(p.Spec.Domain)
+}
+
+// RecordSetName returns the name of the DNSEndpoint holding the DMARC record,
+// which must differ from the one of a DKIMKey with the same name.
+func (p *DMARCPolicy) RecordSetName() string {
+	return p.Name + "-dmarc"
+}
+
+// Record returns the DMARC record described by the DMARCPolicy.
+func (p *DMARCPolicy) Record() dmarc.Record {
+	var percentage *int
+	if p.Spec.Percentage != nil {
+		v := int(*p.Spec.Percentage)
+		percentage = &v
+	}
+	return dmarc.Record{
+		Policy:                  p.Spec.Policy,
+		SubdomainPolicy:         p.Spec.SubdomainPolicy,
+		Percentage:              percentage,
+		AggregateReportURIs:     p.Spec.AggregateReportURIs,
+		FailureReportURIs:       p.Spec.FailureReportURIs,
+		DKIMAlignment:           p.Spec.DKIMAlignment,
+		SPFAlignment:            p.Spec.SPFAlignment,
+		FailureReportingOptions: p.Spec.FailureReportingOptions,
+	}
+}
+
+// IsReady returns true if the DMARCPolicy has a Ready condition with status True.
+func (p *DMARCPolicy) IsReady() bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain returns the domain of the DMARCPolicy.
+func (p *DMARCPolicy) GetDomain() string {
+	return p.Spec.Domain
+}
+
+// GetTTL returns the TTL of the DMARC record.
+func (p *DMARCPolicy) GetTTL() uint {
+	return p.Spec.TTL
+}
+
+// GetDNSEndpoint returns the options of the DNSEndpoint holding the DMARC record.
+func (p *DMARCPolicy) GetDNSEndpoint() *DNSEndpointOptions {
+	return p.Spec.DNSEndpoint
+}
+
+// RecordValue returns the TXT value of the DMARC record.
+func (p *DMARCPolicy) RecordValue() string {
+	return p.Record().GenTXTValue()
+}
+
+// GetConditions returns the conditions of the DMARCPolicy.
+func (p *DMARCPolicy) GetConditions() []metav1.Condition {
+	return p.Status.Conditions
+}
+
+// SetConditions sets the conditions of the DMARCPolicy.
+func (p *DMARCPolicy) SetConditions(conditions []metav1.Condition) {
+	p.Status.Conditions = conditions
+}
+
+// SetObservedGeneration sets the last observed generation of the DMARCPolicy.
+func (p *DMARCPolicy) SetObservedGeneration(generation int64) {
+	p.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// DMARCPolicyList contains a list of DMARCPolicy.
+type DMARCPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DMARCPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DMARCPolicy{}, &DMARCPolicyList{})
+}
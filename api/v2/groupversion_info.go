@@ -36,4 +36,7 @@ var (
 
 	// DKIMKeyKind is the kind for DKIMKey.
 	DKIMKeyKind = "DKIMKey"
+
+	// DKIMKeySetKind is the kind for DKIMKeySet.
+	DKIMKeySetKind = "DKIMKeySet"
 )
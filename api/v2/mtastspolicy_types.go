@@ -0,0 +1,167 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hsn723/dkim-manager/pkg/mtasts"
+)
+
+// MTASTSPolicySpec defines the desired MTA-STS policy of a domain.
+type MTASTSPolicySpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain is immutable"
+
+	// Domain is the domain to which the MTA-STS policy will be associated.
+	Domain string `json:"domain"`
+
+	// +kubebuilder:default=86400
+
+	// TTL for the MTA-STS record.
+	TTL uint `json:"ttl,omitempty"`
+
+	// +kubebuilder:validation:Enum=enforce;testing;none
+
+	// Mode is the mode of the policy.
+	Mode mtasts.Mode `json:"mode"`
+
+	// MX are the MX host patterns allowed to receive mail for the domain, e.g. mail.example.com or *.example.net.
+	// Required unless mode is none.
+	// +optional
+	MX []string `json:"mx,omitempty"`
+
+	// +kubebuilder:default=604800
+	// +kubebuilder:validation:Maximum=31557600
+
+	// MaxAge is the number of seconds senders may cache the policy for.
+	MaxAge uint `json:"maxAge,omitempty"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the MTA-STS record,
+	// e.g. to route it to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+}
+
+// MTASTSPolicyStatus defines the observed state of MTASTSPolicy.
+type MTASTSPolicyStatus struct {
+	// ObservedGeneration is the last observed generation of the MTASTSPolicy.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// PolicyID is the id of the published policy.
+	// +optional
+	PolicyID string `json:"policyID,omitempty"`
+
+	// Conditions represent the latest available observations of the MTASTSPolicy's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.mode"
+//+kubebuilder:printcolumn:name="Policy ID",type="string",JSONPath=".status.policyID"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// MTASTSPolicy is the Schema for the mtastspolicies API.
+type MTASTSPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MTASTSPolicySpec   `json:"spec"`
+	Status MTASTSPolicyStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the DNS name under which the MTA-STS record is published.
+func (p *MTASTSPolicy) RecordName() string {
+	return mtasts.RecordName(p.Spec.Domain)
+}
+
+// RecordSetName returns the name of the DNSEndpoint holding the MTA-STS record,
+// which must differ from the one of a DKIMKey with the same name.
+func (p *MTASTSPolicy) RecordSetName() string {
+	return p.Name + "-mta-sts"
+}
+
+// Policy returns the MTA-STS policy described by the MTASTSPolicy.
+func (p *MTASTSPolicy) Policy() mtasts.Policy {
+	return mtasts.Policy{
+		Mode:   p.Spec.Mode,
+		MX:     p.Spec.MX,
+		MaxAge: p.Spec.MaxAge,
+	}
+}
+
+// IsReady returns true if the MTASTSPolicy has a Ready condition with status True.
+func (p *MTASTSPolicy) IsReady() bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain returns the domain of the MTASTSPolicy.
+func (p *MTASTSPolicy) GetDomain() string {
+	return p.Spec.Domain
+}
+
+// GetTTL returns the TTL of the MTA-STS record.
+func (p *MTASTSPolicy) GetTTL() uint {
+	return p.Spec.TTL
+}
+
+// GetDNSEndpoint returns the options of the DNSEndpoint holding the MTA-STS record.
+func (p *MTASTSPolicy) GetDNSEndpoint() *DNSEndpointOptions {
+	return p.Spec.DNSEndpoint
+}
+
+// RecordValue returns the TXT value of the MTA-STS record.
+func (p *MTASTSPolicy) RecordValue() string {
+	return p.Policy().GenTXTValue()
+}
+
+// GetConditions returns the conditions of the MTASTSPolicy.
+func (p *MTASTSPolicy) GetConditions() []metav1.Condition {
+	return p.Status.Conditions
+}
+
+// SetConditions sets the conditions of the MTASTSPolicy.
+func (p *MTASTSPolicy) SetConditions(conditions []metav1.Condition) {
+	p.Status.Conditions = conditions
+}
+
+// SetObservedGeneration sets the last observed generation of the MTASTSPolicy.
+func (p *MTASTSPolicy) SetObservedGeneration(generation int64) {
+	p.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// MTASTSPolicyList contains a list of MTASTSPolicy.
+type MTASTSPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MTASTSPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MTASTSPolicy{}, &MTASTSPolicyList{})
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hsn723/dkim-manager/pkg/spf"
+)
+
+// SPFRecordSpec defines the desired SPF record of a domain.
+type SPFRecordSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain is immutable"
+
+	// Domain is the domain to which the SPF record will be associated.
+	Domain string `json:"domain"`
+
+	// +kubebuilder:default=3600
+
+	// TTL for the SPF record.
+	TTL uint `json:"ttl,omitempty"`
+
+	// Mechanisms are the mechanisms listed first in the record, e.g. ip4:192.0.2.0/24 or mx,
+	// optionally prefixed by a qualifier. Includes and the all mechanism have fields of their own.
+	// +optional
+	Mechanisms []string `json:"mechanisms,omitempty"`
+
+	// Includes are the domains whose SPF records are included, e.g. _spf.google.com.
+	// +optional
+	Includes []string `json:"includes,omitempty"`
+
+	// +kubebuilder:default="~all"
+	// +kubebuilder:validation:Enum="-all";"~all";"?all"
+
+	// All is the all mechanism ending the record.
+	All string `json:"all,omitempty"`
+
+	// Flatten replaces the includes with the networks they authorize, so that the record stays under
+	// the limit of 10 DNS lookups. Flattened records are refreshed periodically.
+	// +optional
+	Flatten bool `json:"flatten,omitempty"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the SPF record,
+	// e.g. to route it to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+}
+
+// SPFRecordStatus defines the observed state of SPFRecord.
+type SPFRecordStatus struct {
+	// ObservedGeneration is the last observed generation of the SPFRecord.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Lookups is the number of DNS lookups caused by the published record, including
+	// the ones of the records it includes.
+	// +optional
+	Lookups int `json:"lookups,omitempty"`
+
+	// Conditions represent the latest available observations of the SPFRecord's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Condition types for SPFRecord.
+const (
+	// ConditionRefreshed indicates whether the included records of a flattened SPFRecord were last resolved successfully.
+	// A failed refresh keeps the previously published record, and the SPFRecord ready.
+	ConditionRefreshed string = "Refreshed"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Flatten",type="boolean",JSONPath=".spec.flatten"
+//+kubebuilder:printcolumn:name="Lookups",type="integer",JSONPath=".status.lookups"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// SPFRecord is the Schema for the spfrecords API.
+type SPFRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SPFRecordSpec   `json:"spec"`
+	Status SPFRecordStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the DNS name under which the SPF record is published.
+func (r *SPFRecord) RecordName() string {
+	return r.Spec.Domain
+}
+
+// RecordSetName returns the name of the DNSEndpoint holding the SPF record,
+// which must differ from the one of a DKIMKey with the same name.
+func (r *SPFRecord) RecordSetName() string {
+	return r.Name + "-spf"
+}
+
+// Record returns the SPF record described by the SPFRecord, before flattening.
+func (r *SPFRecord) Record() spf.Record {
+	return spf.Record{
+		Mechanisms: r.Spec.Mechanisms,
+		Includes:   r.Spec.Includes,
+		All:        r.Spec.All,
+	}
+}
+
+// IsReady returns true if the SPFRecord has a Ready condition with status True.
+func (r *SPFRecord) IsReady() bool {
+	for _, c := range r.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain returns the domain of the SPFRecord.
+func (r *SPFRecord) GetDomain() string {
+	return r.Spec.Domain
+}
+
+// GetTTL returns the TTL of the SPF record.
+func (r *SPFRecord) GetTTL() uint {
+	return r.Spec.TTL
+}
+
+// GetDNSEndpoint returns the options of the DNSEndpoint holding the SPF record.
+func (r *SPFRecord) GetDNSEndpoint() *DNSEndpointOptions {
+	return r.Spec.DNSEndpoint
+}
+
+// RecordValue returns the TXT value of the SPF record, before flattening.
+func (r *SPFRecord) RecordValue() string {
+	return r.Record().GenTXTValue()
+}
+
+// GetConditions returns the conditions of the SPFRecord.
+func (r *SPFRecord) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the conditions of the SPFRecord.
+func (r *SPFRecord) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+// SetObservedGeneration sets the last observed generation of the SPFRecord.
+func (r *SPFRecord) SetObservedGeneration(generation int64) {
+	r.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// SPFRecordList contains a list of SPFRecord.
+type SPFRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SPFRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SPFRecord{}, &SPFRecordList{})
+}
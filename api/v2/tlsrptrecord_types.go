@@ -0,0 +1,146 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/hsn723/dkim-manager/pkg/tlsrpt"
+)
+
+// TLSRPTRecordSpec defines the desired TLS-RPT record of a domain.
+type TLSRPTRecordSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="domain is immutable"
+
+	// Domain is the domain to which the TLS-RPT record will be associated.
+	Domain string `json:"domain"`
+
+	// +kubebuilder:default=86400
+
+	// TTL for the TLS-RPT record.
+	TTL uint `json:"ttl,omitempty"`
+
+	// +kubebuilder:validation:MinItems=1
+
+	// ReportURIs are the mailto or https URIs reports are sent to (rua=).
+	ReportURIs []string `json:"reportURIs"`
+
+	// DNSEndpoint customizes the external-dns DNSEndpoint created for the TLS-RPT record,
+	// e.g. to route it to a specific external-dns instance.
+	// +optional
+	DNSEndpoint *DNSEndpointOptions `json:"dnsEndpoint,omitempty"`
+}
+
+// TLSRPTRecordStatus defines the observed state of TLSRPTRecord.
+type TLSRPTRecordStatus struct {
+	// ObservedGeneration is the last observed generation of the TLSRPTRecord.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the TLSRPTRecord's state.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Domain",type="string",JSONPath=".spec.domain"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// TLSRPTRecord is the Schema for the tlsrptrecords API.
+type TLSRPTRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TLSRPTRecordSpec   `json:"spec"`
+	Status TLSRPTRecordStatus `json:"status,omitempty"`
+}
+
+// RecordName returns the DNS name under which the TLS-RPT record is published.
+func (r *TLSRPTRecord) RecordName() string {
+	return tlsrpt.RecordName(r.Spec.Domain)
+}
+
+// RecordSetName returns the name of the DNSEndpoint holding the TLS-RPT record,
+// which must differ from the one of a DKIMKey with the same name.
+func (r *TLSRPTRecord) RecordSetName() string {
+	return r.Name + "-tlsrpt"
+}
+
+// Record returns the TLS-RPT record described by the TLSRPTRecord.
+func (r *TLSRPTRecord) Record() tlsrpt.Record {
+	return tlsrpt.Record{ReportURIs: r.Spec.ReportURIs}
+}
+
+// IsReady returns true if the TLSRPTRecord has a Ready condition with status True.
+func (r *TLSRPTRecord) IsReady() bool {
+	for _, c := range r.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == metav1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain returns the domain of the TLSRPTRecord.
+func (r *TLSRPTRecord) GetDomain() string {
+	return r.Spec.Domain
+}
+
+// GetTTL returns the TTL of the TLS-RPT record.
+func (r *TLSRPTRecord) GetTTL() uint {
+	return r.Spec.TTL
+}
+
+// GetDNSEndpoint returns the options of the DNSEndpoint holding the TLS-RPT record.
+func (r *TLSRPTRecord) GetDNSEndpoint() *DNSEndpointOptions {
+	return r.Spec.DNSEndpoint
+}
+
+// RecordValue returns the TXT value of the TLS-RPT record.
+func (r *TLSRPTRecord) RecordValue() string {
+	return r.Record().GenTXTValue()
+}
+
+// GetConditions returns the conditions of the TLSRPTRecord.
+func (r *TLSRPTRecord) GetConditions() []metav1.Condition {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the conditions of the TLSRPTRecord.
+func (r *TLSRPTRecord) SetConditions(conditions []metav1.Condition) {
+	r.Status.Conditions = conditions
+}
+
+// SetObservedGeneration sets the last observed generation of the TLSRPTRecord.
+func (r *TLSRPTRecord) SetObservedGeneration(generation int64) {
+	r.Status.ObservedGeneration = generation
+}
+
+//+kubebuilder:object:root=true
+
+// TLSRPTRecordList contains a list of TLSRPTRecord.
+type TLSRPTRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TLSRPTRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TLSRPTRecord{}, &TLSRPTRecordList{})
+}
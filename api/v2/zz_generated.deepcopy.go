@@ -25,12 +25,232 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIRecord) DeepCopyInto(out *BIMIRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIRecord.
+func (in *BIMIRecord) DeepCopy() *BIMIRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BIMIRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIRecordList) DeepCopyInto(out *BIMIRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BIMIRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIRecordList.
+func (in *BIMIRecordList) DeepCopy() *BIMIRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BIMIRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIRecordSpec) DeepCopyInto(out *BIMIRecordSpec) {
+	*out = *in
+	out.Logo = in.Logo
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIRecordSpec.
+func (in *BIMIRecordSpec) DeepCopy() *BIMIRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIMIRecordStatus) DeepCopyInto(out *BIMIRecordStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIMIRecordStatus.
+func (in *BIMIRecordStatus) DeepCopy() *BIMIRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BIMIRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMDelegation) DeepCopyInto(out *DKIMDelegation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMDelegation.
+func (in *DKIMDelegation) DeepCopy() *DKIMDelegation {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMDelegation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMDomainPolicy) DeepCopyInto(out *DKIMDomainPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMDomainPolicy.
+func (in *DKIMDomainPolicy) DeepCopy() *DKIMDomainPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMDomainPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DKIMDomainPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMDomainPolicyList) DeepCopyInto(out *DKIMDomainPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DKIMDomainPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMDomainPolicyList.
+func (in *DKIMDomainPolicyList) DeepCopy() *DKIMDomainPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMDomainPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DKIMDomainPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMDomainPolicySpec) DeepCopyInto(out *DKIMDomainPolicySpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMDomainPolicySpec.
+func (in *DKIMDomainPolicySpec) DeepCopy() *DKIMDomainPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMDomainPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DKIMKey) DeepCopyInto(out *DKIMKey) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -84,9 +304,131 @@ func (in *DKIMKeyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeySet) DeepCopyInto(out *DKIMKeySet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeySet.
+func (in *DKIMKeySet) DeepCopy() *DKIMKeySet {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeySet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DKIMKeySet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeySetList) DeepCopyInto(out *DKIMKeySetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DKIMKeySet, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeySetList.
+func (in *DKIMKeySetList) DeepCopy() *DKIMKeySetList {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeySetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DKIMKeySetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeySetSpec) DeepCopyInto(out *DKIMKeySetSpec) {
+	*out = *in
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DomainsFrom != nil {
+		in, out := &in.DomainsFrom, &out.DomainsFrom
+		*out = new(DomainsFromConfigMaps)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeySetSpec.
+func (in *DKIMKeySetSpec) DeepCopy() *DKIMKeySetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeySetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeySetStatus) DeepCopyInto(out *DKIMKeySetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeySetStatus.
+func (in *DKIMKeySetStatus) DeepCopy() *DKIMKeySetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeySetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DKIMKeySpec) DeepCopyInto(out *DKIMKeySpec) {
 	*out = *in
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(DKIMRecordTags)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delegation != nil {
+		in, out := &in.Delegation, &out.Delegation
+		*out = new(DKIMDelegation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeySpec.
@@ -120,3 +462,597 @@ func (in *DKIMKeyStatus) DeepCopy() *DKIMKeyStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeyTemplate) DeepCopyInto(out *DKIMKeyTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeyTemplate.
+func (in *DKIMKeyTemplate) DeepCopy() *DKIMKeyTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeyTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMKeyTemplateSpec) DeepCopyInto(out *DKIMKeyTemplateSpec) {
+	*out = *in
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = new(DKIMRecordTags)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delegation != nil {
+		in, out := &in.Delegation, &out.Delegation
+		*out = new(DKIMDelegation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMKeyTemplateSpec.
+func (in *DKIMKeyTemplateSpec) DeepCopy() *DKIMKeyTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMKeyTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DKIMRecordTags) DeepCopyInto(out *DKIMRecordTags) {
+	*out = *in
+	if in.ServiceTypes != nil {
+		in, out := &in.ServiceTypes, &out.ServiceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HashAlgorithms != nil {
+		in, out := &in.HashAlgorithms, &out.HashAlgorithms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DKIMRecordTags.
+func (in *DKIMRecordTags) DeepCopy() *DKIMRecordTags {
+	if in == nil {
+		return nil
+	}
+	out := new(DKIMRecordTags)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCPolicy) DeepCopyInto(out *DMARCPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCPolicy.
+func (in *DMARCPolicy) DeepCopy() *DMARCPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DMARCPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCPolicyList) DeepCopyInto(out *DMARCPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DMARCPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCPolicyList.
+func (in *DMARCPolicyList) DeepCopy() *DMARCPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DMARCPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCPolicySpec) DeepCopyInto(out *DMARCPolicySpec) {
+	*out = *in
+	if in.Percentage != nil {
+		in, out := &in.Percentage, &out.Percentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AggregateReportURIs != nil {
+		in, out := &in.AggregateReportURIs, &out.AggregateReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureReportURIs != nil {
+		in, out := &in.FailureReportURIs, &out.FailureReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailureReportingOptions != nil {
+		in, out := &in.FailureReportingOptions, &out.FailureReportingOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCPolicySpec.
+func (in *DMARCPolicySpec) DeepCopy() *DMARCPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DMARCPolicyStatus) DeepCopyInto(out *DMARCPolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DMARCPolicyStatus.
+func (in *DMARCPolicyStatus) DeepCopy() *DMARCPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DMARCPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSEndpointOptions) DeepCopyInto(out *DNSEndpointOptions) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ProviderSpecific != nil {
+		in, out := &in.ProviderSpecific, &out.ProviderSpecific
+		*out = make([]ProviderSpecificProperty, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSEndpointOptions.
+func (in *DNSEndpointOptions) DeepCopy() *DNSEndpointOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSEndpointOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DomainsFromConfigMaps) DeepCopyInto(out *DomainsFromConfigMaps) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DomainsFromConfigMaps.
+func (in *DomainsFromConfigMaps) DeepCopy() *DomainsFromConfigMaps {
+	if in == nil {
+		return nil
+	}
+	out := new(DomainsFromConfigMaps)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSPolicy) DeepCopyInto(out *MTASTSPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSPolicy.
+func (in *MTASTSPolicy) DeepCopy() *MTASTSPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MTASTSPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSPolicyList) DeepCopyInto(out *MTASTSPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MTASTSPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSPolicyList.
+func (in *MTASTSPolicyList) DeepCopy() *MTASTSPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MTASTSPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSPolicySpec) DeepCopyInto(out *MTASTSPolicySpec) {
+	*out = *in
+	if in.MX != nil {
+		in, out := &in.MX, &out.MX
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSPolicySpec.
+func (in *MTASTSPolicySpec) DeepCopy() *MTASTSPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MTASTSPolicyStatus) DeepCopyInto(out *MTASTSPolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MTASTSPolicyStatus.
+func (in *MTASTSPolicyStatus) DeepCopy() *MTASTSPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MTASTSPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderSpecificProperty) DeepCopyInto(out *ProviderSpecificProperty) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderSpecificProperty.
+func (in *ProviderSpecificProperty) DeepCopy() *ProviderSpecificProperty {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderSpecificProperty)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFRecord) DeepCopyInto(out *SPFRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFRecord.
+func (in *SPFRecord) DeepCopy() *SPFRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPFRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFRecordList) DeepCopyInto(out *SPFRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SPFRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFRecordList.
+func (in *SPFRecordList) DeepCopy() *SPFRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SPFRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFRecordSpec) DeepCopyInto(out *SPFRecordSpec) {
+	*out = *in
+	if in.Mechanisms != nil {
+		in, out := &in.Mechanisms, &out.Mechanisms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Includes != nil {
+		in, out := &in.Includes, &out.Includes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFRecordSpec.
+func (in *SPFRecordSpec) DeepCopy() *SPFRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SPFRecordStatus) DeepCopyInto(out *SPFRecordStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SPFRecordStatus.
+func (in *SPFRecordStatus) DeepCopy() *SPFRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SPFRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTRecord) DeepCopyInto(out *TLSRPTRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTRecord.
+func (in *TLSRPTRecord) DeepCopy() *TLSRPTRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TLSRPTRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTRecordList) DeepCopyInto(out *TLSRPTRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TLSRPTRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTRecordList.
+func (in *TLSRPTRecordList) DeepCopy() *TLSRPTRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TLSRPTRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTRecordSpec) DeepCopyInto(out *TLSRPTRecordSpec) {
+	*out = *in
+	if in.ReportURIs != nil {
+		in, out := &in.ReportURIs, &out.ReportURIs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSEndpoint != nil {
+		in, out := &in.DNSEndpoint, &out.DNSEndpoint
+		*out = new(DNSEndpointOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTRecordSpec.
+func (in *TLSRPTRecordSpec) DeepCopy() *TLSRPTRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSRPTRecordStatus) DeepCopyInto(out *TLSRPTRecordStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSRPTRecordStatus.
+func (in *TLSRPTRecordStatus) DeepCopy() *TLSRPTRecordStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSRPTRecordStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hsn723/dkim-manager/pkg/backup"
+)
+
+const (
+	passphraseEnv       = "DKIM_MANAGER_BACKUP_PASSPHRASE"
+	backupPrefix        = "dkim-manager-backup-"
+	backupSuffix        = ".enc"
+	backupTimeFormat    = "20060102T150405Z"
+	subcommandTimeout   = 5 * time.Minute
+	passphraseFileUsage = "The file holding the passphrase of the backup. Defaults to the " + passphraseEnv + " environment variable."
+)
+
+// newClient returns a client of the cluster of the given kubeconfig file, or of the default
+// configuration if empty.
+func newClient(kubeconfig string) (client.Client, error) {
+	var cfg *rest.Config
+	var err error
+	if kubeconfig != "" {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		cfg, err = ctrl.GetConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+// readPassphrase reads the passphrase from a file, or from the environment if path is empty.
+func readPassphrase(path string) ([]byte, error) {
+	passphrase := []byte(os.Getenv(passphraseEnv))
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		passphrase = []byte(strings.TrimRight(string(data), "\r\n"))
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("a passphrase is required, through --passphrase-file or %s", passphraseEnv)
+	}
+	return passphrase, nil
+}
+
+// backupOptions holds the flags of the backup subcommand.
+type backupOptions struct {
+	namespaces     []string
+	passphraseFile string
+	output         string
+	outputDir      string
+	keep           int
+}
+
+func runBackup(args []string, stdout, stderr io.Writer) error {
+	var o backupOptions
+	var kubeconfig string
+	fs := pflag.NewFlagSet("backup", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "The kubeconfig file of the cluster. Defaults to the in-cluster configuration or the KUBECONFIG environment variable.")
+	fs.StringSliceVar(&o.namespaces, "namespaces", nil, "The namespaces to back up. Empty backs up all namespaces.")
+	fs.StringVar(&o.passphraseFile, "passphrase-file", "", passphraseFileUsage)
+	fs.StringVar(&o.output, "output", "", "The file the backup is written to. Defaults to "+backupPrefix+"<timestamp>"+backupSuffix+" in --output-dir.")
+	fs.StringVar(&o.outputDir, "output-dir", ".", "The directory timestamped backups are written to.")
+	fs.IntVar(&o.keep, "keep", 0, "The number of timestamped backups kept in --output-dir, removing older ones. 0 keeps all backups.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Write an encrypted backup of DKIMKeys, their private keys and DKIMKeySets.\n\nUsage:\n  dkim-manager backup [flags]\n\nFlags:\n%s", fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	passphrase, err := readPassphrase(o.passphraseFile)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), subcommandTimeout)
+	defer cancel()
+	return o.run(ctx, c, passphrase, time.Now(), stdout, stderr)
+}
+
+func (o backupOptions) run(ctx context.Context, c client.Reader, passphrase []byte, now time.Time, stdout, stderr io.Writer) error {
+	a, err := backup.Collect(ctx, c, o.namespaces, now)
+	if err != nil {
+		return err
+	}
+	withKeys := 0
+	for _, k := range a.Keys {
+		if k.Secret != nil {
+			withKeys++
+		} else if !k.DKIMKey.Spec.Revoked {
+			fmt.Fprintf(stderr, "warning: the private keys of dkimkey %s/%s were not found, and will be generated again on restore\n", k.DKIMKey.Namespace, k.DKIMKey.Name)
+		}
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	encrypted, err := backup.Encrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+	path := o.output
+	if path == "" {
+		path = filepath.Join(o.outputDir, backupPrefix+now.UTC().Format(backupTimeFormat)+backupSuffix)
+	}
+	if err := writeNewFile(path, encrypted); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "backed up %d DKIMKeys, %d with private keys, and %d DKIMKeySets to %s\n", len(a.Keys), withKeys, len(a.KeySets), path)
+	if o.output == "" && o.keep > 0 {
+		return pruneBackups(o.outputDir, o.keep, stdout)
+	}
+	return nil
+}
+
+// pruneBackups removes the oldest timestamped backups of dir, keeping the given number of backups.
+func pruneBackups(dir string, keep int, stdout io.Writer) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasPrefix(e.Name(), backupPrefix) && strings.HasSuffix(e.Name(), backupSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	// Timestamps sort chronologically.
+	slices.Sort(names)
+	for len(names) > keep {
+		path := filepath.Join(dir, names[0])
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "removed %s\n", path)
+		names = names[1:]
+	}
+	return nil
+}
+
+func runRestore(args []string, stdout, stderr io.Writer) error {
+	var kubeconfig, passphraseFile string
+	var dryRun bool
+	fs := pflag.NewFlagSet("restore", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&kubeconfig, "kubeconfig", "", "The kubeconfig file of the cluster. Defaults to the in-cluster configuration or the KUBECONFIG environment variable.")
+	fs.StringVar(&passphraseFile, "passphrase-file", "", passphraseFileUsage)
+	fs.BoolVar(&dryRun, "dry-run", false, "Only list the contents of the backup.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Recreate the DKIMKeys, private keys and DKIMKeySets of an encrypted backup.\n\nUsage:\n  dkim-manager restore FILE [flags]\n\nFlags:\n%s", fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected the backup file as the only argument")
+	}
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return err
+	}
+	a, err := readBackup(fs.Arg(0), passphrase)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		printBackup(a, stdout)
+		return nil
+	}
+	c, err := newClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), subcommandTimeout)
+	defer cancel()
+	return restore(ctx, c, a, stdout)
+}
+
+// readBackup decrypts and decodes a backup file.
+func readBackup(path string, passphrase []byte) (*backup.Archive, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = backup.Decrypt(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	a := &backup.Archive{}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+	return a, nil
+}
+
+// printBackup lists the contents of a backup.
+func printBackup(a *backup.Archive, stdout io.Writer) {
+	fmt.Fprintf(stdout, "backup created at %s\n", a.Created.UTC().Format(time.RFC3339))
+	for _, k := range a.Keys {
+		keys := "without private keys"
+		if k.Secret != nil {
+			keys = "with private keys in secret " + k.Secret.Name
+		}
+		fmt.Fprintf(stdout, "dkimkey %s/%s (%s) %s\n", k.DKIMKey.Namespace, k.DKIMKey.Name, strings.Join(k.DKIMKey.RecordNames(), ", "), keys)
+	}
+	for _, ks := range a.KeySets {
+		fmt.Fprintf(stdout, "dkimkeyset %s/%s\n", ks.Namespace, ks.Name)
+	}
+}
+
+func restore(ctx context.Context, c client.Client, a *backup.Archive, stdout io.Writer) error {
+	results, err := backup.Restore(ctx, c, a)
+	for _, r := range results {
+		status := "restored"
+		if !r.Restored {
+			status = "unchanged, already exists"
+		}
+		fmt.Fprintf(stdout, "%s %s %s\n", strings.ToLower(r.Kind), r.ObjectKey, status)
+	}
+	return err
+}
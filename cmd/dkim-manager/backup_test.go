@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0o600))
+	passphrase, err := readPassphrase(passphraseFile)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("correct horse battery staple"), passphrase)
+
+	priv, _, err := dkim.GenED25519()
+	require.NoError(t, err)
+	dk := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "mail", UID: "uid-example"},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: "example",
+			Selector:   "sel1",
+			Domain:     "example.com",
+			TTL:        86400,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeED25519,
+			Purpose:    dkim.KeyPurposeDKIM,
+		},
+	}
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "mail",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(dk, dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeyKind))}},
+		Data: map[string][]byte{"example.com.sel1.key": priv},
+	}
+	pending := dk.DeepCopy()
+	pending.Name = "pending"
+	pending.Spec.SecretName = "pending"
+	pending.Spec.Selector = "sel2"
+	src := fake.NewClientBuilder().WithScheme(scheme).WithObjects(dk, s, pending).Build()
+
+	o := backupOptions{outputDir: dir, keep: 2}
+	var stdout, stderr bytes.Buffer
+	for i := range 3 {
+		now := time.Date(2026, 10, 18, 3, i, 0, 0, time.UTC)
+		require.NoError(t, o.run(ctx, src, passphrase, now, &stdout, &stderr))
+	}
+	assert.Contains(t, stdout.String(), "backed up 2 DKIMKeys, 1 with private keys, and 0 DKIMKeySets to "+filepath.Join(dir, "dkim-manager-backup-20261018T030000Z.enc"))
+	assert.Contains(t, stdout.String(), "removed "+filepath.Join(dir, "dkim-manager-backup-20261018T030000Z.enc"))
+	assert.Contains(t, stderr.String(), "warning: the private keys of dkimkey mail/pending were not found")
+	matches, err := filepath.Glob(filepath.Join(dir, "dkim-manager-backup-*.enc"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "dkim-manager-backup-20261018T030100Z.enc"),
+		filepath.Join(dir, "dkim-manager-backup-20261018T030200Z.enc"),
+	}, matches)
+	data, err := os.ReadFile(matches[1])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "example.com")
+
+	stdout.Reset()
+	require.NoError(t, runRestore([]string{"--passphrase-file", passphraseFile, "--dry-run", matches[1]}, &stdout, &stderr))
+	assert.Equal(t, `backup created at 2026-10-18T03:02:00Z
+dkimkey mail/example (sel1._domainkey.example.com) with private keys in secret example
+dkimkey mail/pending (sel2._domainkey.example.com) without private keys
+`, stdout.String())
+
+	a, err := readBackup(matches[1], passphrase)
+	require.NoError(t, err)
+	dst := fake.NewClientBuilder().WithScheme(scheme).Build()
+	stdout.Reset()
+	require.NoError(t, restore(ctx, dst, a, &stdout))
+	assert.Equal(t, `secret mail/example restored
+dkimkey mail/example restored
+dkimkey mail/pending restored
+`, stdout.String())
+	restored := &corev1.Secret{}
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "mail", Name: "example"}, restored))
+	assert.Equal(t, priv, restored.Data["example.com.sel1.key"])
+
+	_, err = readBackup(matches[1], []byte("wrong passphrase"))
+	assert.Error(t, err)
+	assert.Error(t, o.run(ctx, src, passphrase, time.Date(2026, 10, 18, 3, 2, 0, 0, time.UTC), &stdout, &stderr), "existing backups must not be overwritten")
+}
+
+func TestBackupInvalid(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0o600))
+	_, err := readPassphrase(empty)
+	assert.Error(t, err)
+	_, err = readPassphrase(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+
+	var stdout, stderr bytes.Buffer
+	assert.Error(t, runBackup([]string{"extra"}, &stdout, &stderr))
+	assert.Error(t, runRestore(nil, &stdout, &stderr))
+	assert.Error(t, runRestore([]string{"--passphrase-file", empty, filepath.Join(dir, "missing")}, &stdout, &stderr))
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/hooks"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+)
+
+// keygenOptions holds the flags of the keygen subcommand.
+type keygenOptions struct {
+	name            string
+	namespace       string
+	secretName      string
+	domain          string
+	selector        string
+	ed25519Selector string
+	keyType         string
+	keyLength       uint
+	purpose         string
+	ttl             uint
+	delegationZone  string
+	tags            dkimmanagerv2.DKIMRecordTags
+	outputDir       string
+	manifests       bool
+}
+
+// dkimKey returns the DKIMKey requested by the options, with defaults applied as by the API server.
+func (o keygenOptions) dkimKey() *dkimmanagerv2.DKIMKey {
+	domain := policy.NormalizeRecordName(o.domain)
+	dk := &dkimmanagerv2.DKIMKey{}
+	dk.SetGroupVersionKind(dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeyKind))
+	dk.Name = o.name
+	if dk.Name == "" {
+		dk.Name = o.selector + "-" + strings.ReplaceAll(domain, ".", "-")
+	}
+	dk.Namespace = o.namespace
+	dk.Spec = dkimmanagerv2.DKIMKeySpec{
+		SecretName:      o.secretName,
+		Selector:        o.selector,
+		Domain:          domain,
+		TTL:             o.ttl,
+		KeyLength:       dkim.KeyLength(o.keyLength),
+		KeyType:         dkim.KeyType(o.keyType),
+		Purpose:         dkim.KeyPurpose(o.purpose),
+		ED25519Selector: o.ed25519Selector,
+	}
+	if dk.Spec.SecretName == "" {
+		dk.Spec.SecretName = dk.Name
+	}
+	if o.delegationZone != "" {
+		dk.Spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: policy.NormalizeRecordName(o.delegationZone)}
+	}
+	if o.tags.Testing || o.tags.Strict || len(o.tags.ServiceTypes) > 0 || o.tags.Notes != "" || len(o.tags.HashAlgorithms) > 0 {
+		dk.Spec.Tags = o.tags.DeepCopy()
+	}
+	return dk
+}
+
+// validateKeygen checks the DKIMKey as the CRD schema and the validating webhook would,
+// except for the checks depending on the state of the cluster.
+func validateKeygen(dk *dkimmanagerv2.DKIMKey) error {
+	if dk.Spec.Selector == "" {
+		return fmt.Errorf("--selector is required")
+	}
+	if !slices.Contains([]dkim.KeyType{dkim.KeyTypeRSA, dkim.KeyTypeED25519}, dk.Spec.KeyType) {
+		return fmt.Errorf("unsupported key type %q", dk.Spec.KeyType)
+	}
+	if !slices.Contains([]dkim.KeyLength{dkim.KeyLength1024, dkim.KeyLength2048, dkim.KeyLength4096}, dk.Spec.KeyLength) {
+		return fmt.Errorf("unsupported key length %d", dk.Spec.KeyLength)
+	}
+	if !slices.Contains([]dkim.KeyPurpose{dkim.KeyPurposeDKIM, dkim.KeyPurposeARC}, dk.Spec.Purpose) {
+		return fmt.Errorf("unsupported purpose %q", dk.Spec.Purpose)
+	}
+	return hooks.ValidateDKIMKey(dk)
+}
+
+// runKeygen generates the key pairs of a DKIMKey without a cluster. The private keys are written
+// to the output directory, optionally along with Secret and DKIMKey manifests for the controller
+// to adopt, and the DNS records publishing the public keys are printed as the controller publishes them.
+func runKeygen(args []string, stdout, stderr io.Writer) error {
+	var o keygenOptions
+	fs := pflag.NewFlagSet("keygen", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&o.domain, "domain", "", "The domain to which the DKIM record will be associated.")
+	fs.StringVar(&o.selector, "selector", "", "The DKIM selector.")
+	fs.StringVar(&o.ed25519Selector, "ed25519-selector", "", "The selector of an additional Ed25519 key, for rsa keys.")
+	fs.StringVar(&o.keyType, "key-type", string(dkim.KeyTypeRSA), "The key type, rsa or ed25519.")
+	fs.UintVar(&o.keyLength, "key-length", uint(dkim.KeyLength2048), "The bit size of RSA keys, 1024, 2048 or 4096.")
+	fs.StringVar(&o.purpose, "purpose", string(dkim.KeyPurposeDKIM), "What the key is used for, dkim or arc.")
+	fs.UintVar(&o.ttl, "ttl", 86400, "The TTL of the DKIM records.")
+	fs.StringVar(&o.delegationZone, "delegation-zone", "", "The zone the DKIM records are delegated to through CNAME records.")
+	fs.BoolVar(&o.tags.Testing, "testing", false, "Mark the domain as testing DKIM (t=y).")
+	fs.BoolVar(&o.tags.Strict, "strict", false, "Forbid using the key for subdomains (t=s).")
+	fs.StringSliceVar(&o.tags.ServiceTypes, "service-types", nil, "The service types the key applies to (s=).")
+	fs.StringSliceVar(&o.tags.HashAlgorithms, "hash-algorithms", nil, "The acceptable hash algorithms (h=).")
+	fs.StringVar(&o.tags.Notes, "notes", "", "Human-readable notes (n=).")
+	fs.StringVar(&o.outputDir, "output-dir", ".", "The directory the private keys or manifests are written to.")
+	fs.BoolVar(&o.manifests, "manifests", false, "Write Secret and DKIMKey manifests holding the private keys, for the controller to adopt, instead of the private keys alone.")
+	fs.StringVar(&o.name, "name", "", "The name of the DKIMKey manifest. Defaults to <selector>-<domain>, with dots replaced by dashes.")
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace of the manifests. Empty omits it.")
+	fs.StringVar(&o.secretName, "secret-name", "", "The name of the Secret manifest. Defaults to the name of the DKIMKey.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Generate DKIM key pairs offline, and print their DNS records.\n\nUsage:\n  dkim-manager keygen --domain DOMAIN --selector SELECTOR [flags]\n\nFlags:\n%s", fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	dk := o.dkimKey()
+	if err := validateKeygen(dk); err != nil {
+		return err
+	}
+
+	keys := dk.Keys()
+	privs := make(map[string][]byte, len(keys))
+	var records []string
+	for _, k := range keys {
+		priv, pub, err := dkim.GenKeyPair(k.KeyType, k.KeyLength)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		privs[k.PrivateKeyFilename()] = priv
+		records = append(records, dkim.GenZoneRecord(k.TXTRecordName(), dk.Spec.TTL, dk.RecordValue(k, pub)))
+		if k.DelegationZone != "" {
+			records = append(records, dkim.GenCNAMEZoneRecord(k.RecordName(), dk.Spec.TTL, k.TXTRecordName()))
+		}
+	}
+
+	files := map[string][]byte{}
+	if o.manifests {
+		data, err := keygenManifests(dk, privs)
+		if err != nil {
+			return err
+		}
+		files[dk.Name+".yaml"] = data
+	} else {
+		files = privs
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		path := filepath.Join(o.outputDir, name)
+		if err := writeNewFile(path, files[name]); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "wrote %s\n", path)
+	}
+	for _, r := range records {
+		fmt.Fprintln(stdout, r)
+	}
+	return nil
+}
+
+// keygenManifests returns the Secret and DKIMKey manifests of the generated keys.
+func keygenManifests(dk *dkimmanagerv2.DKIMKey, privs map[string][]byte) ([]byte, error) {
+	s := &corev1.Secret{}
+	s.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	s.Name = dk.Spec.SecretName
+	s.Namespace = dk.Namespace
+	s.Annotations = map[string]string{dkimmanagerv2.AdoptSecretAnnotation: "true"}
+	s.Type = corev1.SecretTypeOpaque
+	s.Immutable = ptr.To(true)
+	s.Data = privs
+
+	var b bytes.Buffer
+	for i, obj := range []runtime.Object{s, dk} {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		unstructured.RemoveNestedField(u, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(u, "status")
+		data, err := yaml.Marshal(u)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		b.Write(data)
+	}
+	return b.Bytes(), nil
+}
+
+// writeNewFile writes a file readable only by its owner, refusing to overwrite an existing file.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
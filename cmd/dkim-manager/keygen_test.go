@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+func TestKeygen(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	args := []string{"--domain", "Example.com.", "--selector", "sel1", "--ed25519-selector", "sel1-ed25519", "--ttl", "3600", "--testing", "--output-dir", dir}
+	require.NoError(t, runKeygen(args, &stdout, &stderr))
+
+	rsaPriv, err := os.ReadFile(filepath.Join(dir, "example.com.sel1.key"))
+	require.NoError(t, err)
+	rsaPub, err := dkim.DeriveRSAPublicKey(rsaPriv, dkim.KeyLength2048)
+	require.NoError(t, err)
+	edPriv, err := os.ReadFile(filepath.Join(dir, "example.com.sel1-ed25519.key"))
+	require.NoError(t, err)
+	edPub, err := dkim.DeriveED25519PublicKey(edPriv)
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "example.com.sel1.key"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	tags := dkim.Tags{Testing: true}
+	assert.Equal(t, strings.Join([]string{
+		dkim.GenZoneRecord("sel1._domainkey.example.com", 3600, dkim.GenTXTValueWithTags(rsaPub, dkim.KeyTypeRSA, tags)),
+		dkim.GenZoneRecord("sel1-ed25519._domainkey.example.com", 3600, dkim.GenTXTValueWithTags(edPub, dkim.KeyTypeED25519, tags)),
+	}, "\n")+"\n", stdout.String())
+
+	assert.Error(t, runKeygen(args, &stdout, &stderr), "existing keys must not be overwritten")
+}
+
+func TestKeygenManifests(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	args := []string{"--domain", "example.com", "--selector", "sel1", "--key-type", "ed25519", "--delegation-zone", "dkim.example.net",
+		"--namespace", "mail", "--manifests", "--output-dir", dir}
+	require.NoError(t, runKeygen(args, &stdout, &stderr))
+
+	data, err := os.ReadFile(filepath.Join(dir, "sel1-example-com.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "creationTimestamp")
+	assert.NotContains(t, string(data), "status")
+	docs := strings.Split(string(data), "---\n")
+	require.Len(t, docs, 2)
+
+	s := &corev1.Secret{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[0]), s))
+	assert.Equal(t, "Secret", s.Kind)
+	assert.Equal(t, "sel1-example-com", s.Name)
+	assert.Equal(t, "mail", s.Namespace)
+	assert.Equal(t, "true", s.Annotations[dkimmanagerv2.AdoptSecretAnnotation])
+	pub, err := dkim.DeriveED25519PublicKey(s.Data["example.com.sel1.key"])
+	require.NoError(t, err)
+
+	dk := &dkimmanagerv2.DKIMKey{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[1]), dk))
+	assert.Equal(t, dkimmanagerv2.GroupVersion.String(), dk.APIVersion)
+	assert.Equal(t, dkimmanagerv2.DKIMKeySpec{
+		SecretName: "sel1-example-com",
+		Selector:   "sel1",
+		Domain:     "example.com",
+		TTL:        86400,
+		KeyLength:  dkim.KeyLength2048,
+		KeyType:    dkim.KeyTypeED25519,
+		Purpose:    dkim.KeyPurposeDKIM,
+		Delegation: &dkimmanagerv2.DKIMDelegation{Zone: "dkim.example.net"},
+	}, dk.Spec)
+
+	assert.Equal(t, dkim.GenZoneRecord("sel1._domainkey.example.com.dkim.example.net", 86400, dkim.GenTXTValue(pub, dkim.KeyTypeED25519))+"\n"+
+		"sel1._domainkey.example.com.\t86400\tIN\tCNAME\tsel1._domainkey.example.com.dkim.example.net.\n", stdout.String())
+	_, err = os.Stat(filepath.Join(dir, "example.com.sel1.key"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestKeygenInvalid(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title string
+		args  []string
+	}{
+		{title: "MissingSelector", args: []string{"--domain", "example.com"}},
+		{title: "InvalidDomain", args: []string{"--domain", "example", "--selector", "sel1"}},
+		{title: "InvalidKeyType", args: []string{"--domain", "example.com", "--selector", "sel1", "--key-type", "dsa"}},
+		{title: "InvalidKeyLength", args: []string{"--domain", "example.com", "--selector", "sel1", "--key-length", "512"}},
+		{title: "ED25519SelectorForED25519Key", args: []string{"--domain", "example.com", "--selector", "sel1", "--key-type", "ed25519", "--ed25519-selector", "sel2"}},
+		{title: "InvalidServiceType", args: []string{"--domain", "example.com", "--selector", "sel1", "--service-types", "web"}},
+		{title: "UnexpectedArgument", args: []string{"--domain", "example.com", "--selector", "sel1", "extra"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			assert.Error(t, runKeygen(append(tc.args, "--output-dir", dir), &stdout, &stderr))
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
@@ -17,9 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,12 +33,15 @@ import (
 
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -42,11 +51,18 @@ import (
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
 	"github.com/hsn723/dkim-manager/controllers"
 	"github.com/hsn723/dkim-manager/hooks"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/dnsprobe"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/mtasts"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
 	//+kubebuilder:scaffold:imports
 )
 
 const (
 	fallbackServiceAccount = "system:serviceaccount:dkim-manager:dkim-manager-controller-manager"
+	mtaSTSReadTimeout      = 10 * time.Second
 )
 
 var (
@@ -90,7 +106,64 @@ func getNamespace() string {
 	return string(data)
 }
 
+// rfc2136Config holds the flags configuring the RFC 2136 publisher.
+type rfc2136Config struct {
+	server         string
+	zone           string
+	tsigKeyName    string
+	tsigAlgorithm  string
+	tsigSecretFile string
+	timeout        time.Duration
+}
+
+func newRFC2136Publisher(cfg rfc2136Config) (publisher.Publisher, error) {
+	opts := publisher.RFC2136Options{
+		Server:  cfg.server,
+		Zone:    cfg.zone,
+		Timeout: cfg.timeout,
+	}
+	if cfg.tsigKeyName != "" {
+		data, err := os.ReadFile(cfg.tsigSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TSIG secret: %w", err)
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("could not decode TSIG secret: %w", err)
+		}
+		opts.TSIGKey = &publisher.TSIGKey{
+			Name:      cfg.tsigKeyName,
+			Algorithm: cfg.tsigAlgorithm,
+			Secret:    secret,
+		}
+	}
+	return publisher.NewRFC2136Publisher(opts)
+}
+
+// subcommands are run instead of the manager when named by the first argument.
+var subcommands = map[string]func(args []string, stdout, stderr io.Writer) error{
+	"keygen":           runKeygen,
+	"migrate-opendkim": runMigrateOpenDKIM,
+	"backup":           runBackup,
+	"restore":          runRestore,
+}
+
+func runSubcommand(run func(args []string, stdout, stderr io.Writer) error, args []string) {
+	err := run(args, os.Stdout, os.Stderr)
+	if err != nil && !errors.Is(err, pflag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			runSubcommand(run, os.Args[2:])
+		}
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
@@ -99,7 +172,28 @@ func main() {
 	var namespace string
 	var namespaced bool
 	var webhooksEnabled bool
+	var validatorOpts hooks.DKIMKeyValidatorOptions
+	var minRSAKeyLength uint
+	var allowedKeyTypes []string
+	var maxKeyAge time.Duration
+	var maxARCKeyAge time.Duration
+	var selectorTemplate string
+	var arcSelectorTemplate string
+	var secretNameTemplate string
+	var dnsVerifyNameservers []string
+	var dnsVerifyTimeout time.Duration
+	var publisherName string
+	var rfc2136 rfc2136Config
+	var configMapName string
+	var configMapNamespace string
+	var coreDNSOpts publisher.CoreDNSOptions
+	var dnsEndpointGroup string
+	var dnsEndpointVersion string
+	var dnsEndpointKind string
+	var spfRefreshInterval time.Duration
+	var mtaSTSAddr string
 	pflag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	pflag.StringVar(&mtaSTSAddr, "mta-sts-bind-address", "", "The address the MTA-STS policy endpoint binds to, e.g. :8082. Empty disables serving MTA-STS policies.")
 	pflag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	pflag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
@@ -109,6 +203,34 @@ func main() {
 	pflag.StringSliceVar(&namespaces, "namespaces", nil, "The namespaces the controller should manage.")
 	pflag.BoolVar(&namespaced, "namespaced", false, "Only manage resources in the same namespace as the controller. The --namespaces parameter, if defined, takes precedence.")
 	pflag.BoolVar(&webhooksEnabled, "webhooks", true, "Enable webhooks")
+	pflag.UintVar(&validatorOpts.MinTTL, "min-ttl", 60, "The lowest TTL allowed for DKIM records. 0 disables the lower bound.")
+	pflag.UintVar(&validatorOpts.MaxTTL, "max-ttl", 604800, "The highest TTL allowed for DKIM records. 0 disables the upper bound.")
+	pflag.UintVar(&minRSAKeyLength, "min-rsa-key-length", 0, "The smallest RSA key length allowed for DKIM keys. 0 allows any key length.")
+	pflag.StringSliceVar(&allowedKeyTypes, "allowed-key-types", nil, "The key types allowed for DKIM keys. Empty allows any key type.")
+	pflag.DurationVar(&maxKeyAge, "max-key-age", 0, "The age after which DKIM keys are reported as requiring rotation. 0 disables the check.")
+	pflag.DurationVar(&maxARCKeyAge, "max-arc-key-age", 0, "The age after which ARC keys are reported as requiring rotation. 0 applies --max-key-age.")
+	pflag.StringVar(&selectorTemplate, "selector-template", "", "The template used to generate omitted DKIMKey selectors, e.g. {{.Domain}}-{{.Date}}. Empty disables defaulting.")
+	pflag.StringVar(&arcSelectorTemplate, "arc-selector-template", "", "The template used to generate omitted selectors of ARC keys, e.g. arc-{{.Date}}. Empty applies --selector-template.")
+	pflag.StringVar(&secretNameTemplate, "secret-name-template", "", "The template used to generate omitted DKIMKey secret names, e.g. dkim-{{.Name}}. Empty disables defaulting.")
+	pflag.StringSliceVar(&dnsVerifyNameservers, "dns-verify-nameservers", nil, "The nameservers, as host or host:port, queried to verify that DKIM records are published. Empty disables verification.")
+	pflag.DurationVar(&dnsVerifyTimeout, "dns-verify-timeout", 5*time.Second, "The timeout for each DNS verification query.")
+	pflag.StringVar(&publisherName, "publisher", "externaldns", "How DKIM records are published, one of externaldns, rfc2136, configmap or coredns.")
+	pflag.StringVar(&dnsEndpointGroup, "dnsendpoint-group", externaldns.DNSEndpointGroup, "The API group of external-dns DNSEndpoints.")
+	pflag.StringVar(&dnsEndpointVersion, "dnsendpoint-version", "", "The API version of external-dns DNSEndpoints. Empty selects the preferred version served by the API server.")
+	pflag.StringVar(&dnsEndpointKind, "dnsendpoint-kind", externaldns.DNSEndpointKind, "The kind of external-dns DNSEndpoints.")
+	pflag.DurationVar(&spfRefreshInterval, "spf-refresh-interval", time.Hour, "The interval at which flattened SPF records are resolved again.")
+	pflag.StringVar(&rfc2136.server, "rfc2136-server", "", "The nameserver, as host or host:port, receiving dynamic DNS updates.")
+	pflag.StringVar(&rfc2136.zone, "rfc2136-zone", "", "The zone updated through dynamic DNS updates.")
+	pflag.StringVar(&rfc2136.tsigKeyName, "rfc2136-tsig-key-name", "", "The name of the TSIG key signing dynamic DNS updates. Empty sends unsigned updates.")
+	pflag.StringVar(&rfc2136.tsigAlgorithm, "rfc2136-tsig-algorithm", "hmac-sha256", "The algorithm of the TSIG key.")
+	pflag.StringVar(&rfc2136.tsigSecretFile, "rfc2136-tsig-secret-file", "", "The file containing the base64-encoded TSIG secret.")
+	pflag.DurationVar(&rfc2136.timeout, "rfc2136-timeout", 10*time.Second, "The timeout for each dynamic DNS update.")
+	pflag.StringVar(&configMapName, "configmap-name", "dkim-records", "The name of the ConfigMap DKIM records, or the CoreDNS zone file, are written to.")
+	pflag.StringVar(&configMapNamespace, "configmap-namespace", "", "The namespace of the ConfigMap DKIM records, or the CoreDNS zone file, are written to. Defaults to the namespace of the controller.")
+	pflag.StringVar(&coreDNSOpts.Zone, "coredns-zone", "", "The zone served by CoreDNS, e.g. _domainkey.example.com.")
+	pflag.StringSliceVar(&coreDNSOpts.Nameservers, "coredns-nameservers", nil, "The hostnames of the nameservers the CoreDNS zone is delegated to.")
+	pflag.StringVar(&coreDNSOpts.Hostmaster, "coredns-hostmaster", "", "The mailbox of the zone administrator, in domain name format. Defaults to hostmaster.<zone>.")
+	pflag.UintVar(&coreDNSOpts.TTL, "coredns-ttl", 3600, "The TTL of the SOA and NS records of the CoreDNS zone.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -118,6 +240,37 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	keyPolicy := policy.KeyPolicy{
+		MinRSAKeyLength: dkim.KeyLength(minRSAKeyLength),
+		MaxKeyAge:       maxKeyAge,
+		MaxARCKeyAge:    maxARCKeyAge,
+	}
+	for _, t := range allowedKeyTypes {
+		keyPolicy.AllowedKeyTypes = append(keyPolicy.AllowedKeyTypes, dkim.KeyType(t))
+	}
+	validatorOpts.KeyPolicy = keyPolicy
+
+	selectorTmpl, err := hooks.ParseNameTemplate("selector", selectorTemplate)
+	if err != nil {
+		setupLog.Error(err, "invalid selector template")
+		os.Exit(1)
+	}
+	arcSelectorTmpl, err := hooks.ParseNameTemplate("ARC selector", arcSelectorTemplate)
+	if err != nil {
+		setupLog.Error(err, "invalid ARC selector template")
+		os.Exit(1)
+	}
+	secretNameTmpl, err := hooks.ParseNameTemplate("secret name", secretNameTemplate)
+	if err != nil {
+		setupLog.Error(err, "invalid secret name template")
+		os.Exit(1)
+	}
+	defaulterOpts := hooks.DKIMKeyDefaulterOptions{
+		SelectorTemplate:    selectorTmpl,
+		ARCSelectorTemplate: arcSelectorTmpl,
+		SecretNameTemplate:  secretNameTmpl,
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Client: client.Options{
@@ -147,21 +300,146 @@ func main() {
 		namespaces = append(namespaces, namespace)
 	}
 
+	var dnsProber *dnsprobe.Prober
+	if len(dnsVerifyNameservers) > 0 {
+		dnsProber = dnsprobe.NewProber(dnsVerifyNameservers, dnsVerifyTimeout)
+	}
+
+	if configMapNamespace == "" && (publisherName == "configmap" || publisherName == "coredns") {
+		configMapNamespace = getNamespace()
+	}
+	configMapKey := types.NamespacedName{Namespace: configMapNamespace, Name: configMapName}
+	var recordPublisher publisher.Publisher
+	var dnsEndpointGVK schema.GroupVersionKind
+	switch publisherName {
+	case "externaldns":
+		// The reconciler defaults to the external-dns publisher.
+		dnsEndpointGVK, err = externaldns.Discover(mgr.GetRESTMapper(), dnsEndpointGroup, dnsEndpointVersion, dnsEndpointKind)
+		if err != nil {
+			setupLog.Error(err, "unable to find DNSEndpoint API")
+			os.Exit(1)
+		}
+		setupLog.Info("using DNSEndpoint API", "gvk", dnsEndpointGVK.String())
+	case "rfc2136":
+		recordPublisher, err = newRFC2136Publisher(rfc2136)
+		if err != nil {
+			setupLog.Error(err, "unable to configure RFC 2136 publisher")
+			os.Exit(1)
+		}
+	case "configmap":
+		recordPublisher = publisher.NewConfigMapPublisher(mgr.GetClient(), mgr.GetAPIReader(), configMapKey)
+	case "coredns":
+		coreDNSOpts.ConfigMap = configMapKey
+		recordPublisher, err = publisher.NewCoreDNSPublisher(mgr.GetClient(), mgr.GetAPIReader(), coreDNSOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to configure CoreDNS publisher")
+			os.Exit(1)
+		}
+	default:
+		setupLog.Error(fmt.Errorf("unknown publisher %s", publisherName), "invalid publisher")
+		os.Exit(1)
+	}
+
 	if err := (&controllers.DKIMKeyReconciler{
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("DKIMKey"),
+		Scheme:         mgr.GetScheme(),
+		Namespaces:     namespaces,
+		ReadClient:     mgr.GetAPIReader(),
+		KeyPolicy:      keyPolicy,
+		DNSProber:      dnsProber,
+		Publisher:      recordPublisher,
+		DNSEndpointGVK: dnsEndpointGVK,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DKIMKey")
+		os.Exit(1)
+	}
+	if err := (&controllers.DMARCPolicyReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Namespaces:     namespaces,
+		ReadClient:     mgr.GetAPIReader(),
+		Publisher:      recordPublisher,
+		DNSEndpointGVK: dnsEndpointGVK,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DMARCPolicy")
+		os.Exit(1)
+	}
+	if err := (&controllers.SPFRecordReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Namespaces:      namespaces,
+		ReadClient:      mgr.GetAPIReader(),
+		Publisher:       recordPublisher,
+		DNSEndpointGVK:  dnsEndpointGVK,
+		RefreshInterval: spfRefreshInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SPFRecord")
+		os.Exit(1)
+	}
+	mtaSTSReconciler := &controllers.MTASTSPolicyReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Namespaces:     namespaces,
+		ReadClient:     mgr.GetAPIReader(),
+		Publisher:      recordPublisher,
+		DNSEndpointGVK: dnsEndpointGVK,
+	}
+	if err := mtaSTSReconciler.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MTASTSPolicy")
+		os.Exit(1)
+	}
+	if err := (&controllers.TLSRPTRecordReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Namespaces:     namespaces,
+		ReadClient:     mgr.GetAPIReader(),
+		Publisher:      recordPublisher,
+		DNSEndpointGVK: dnsEndpointGVK,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TLSRPTRecord")
+		os.Exit(1)
+	}
+	if err := (&controllers.BIMIRecordReconciler{
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Namespaces:     namespaces,
+		ReadClient:     mgr.GetAPIReader(),
+		Publisher:      recordPublisher,
+		DNSEndpointGVK: dnsEndpointGVK,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BIMIRecord")
+		os.Exit(1)
+	}
+	if err := (&controllers.DKIMKeySetReconciler{
 		Client:     mgr.GetClient(),
-		Log:        ctrl.Log.WithName("controllers").WithName("DKIMKey"),
 		Scheme:     mgr.GetScheme(),
 		Namespaces: namespaces,
 		ReadClient: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "DKIMKey")
+		setupLog.Error(err, "unable to create controller", "controller", "DKIMKeySet")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	if mtaSTSAddr != "" {
+		if err := mgr.Add(&manager.Server{
+			Name: "mta-sts",
+			Server: &http.Server{
+				Addr:              mtaSTSAddr,
+				Handler:           mtasts.NewHandler(mtaSTSReconciler),
+				ReadHeaderTimeout: mtaSTSReadTimeout,
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to set up MTA-STS policy server")
+			os.Exit(1)
+		}
+	}
+
 	if webhooksEnabled {
-		hooks.SetupDKIMKeyWebhook(mgr, &dec)
-		hooks.SetupDKIMKeyV2Webhook(mgr, &dec)
+		hooks.SetupDKIMKeyWebhook(mgr, &dec, validatorOpts)
+		hooks.SetupDKIMKeyV2Webhook(mgr, &dec, validatorOpts)
+		hooks.SetupDKIMKeyV2Defaulter(mgr, &dec, defaulterOpts)
 		hooks.SetupDNSEndpointWebhook(mgr, &dec, serviceAccount)
 		hooks.SetupSecretWebhook(mgr, &dec, serviceAccount)
 
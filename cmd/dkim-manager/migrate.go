@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// openDKIMKey is a private key found in an OpenDKIM configuration.
+type openDKIMKey struct {
+	domain   string
+	selector string
+	// source describes where the key was found, for error messages.
+	source string
+	// path is the file holding the key, empty for keys inlined in the KeyTable.
+	path string
+	priv []byte
+}
+
+var txtStringRe = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+// readKeyTable reads the keys listed in an OpenDKIM KeyTable, whose entries map a key name to
+// domain:selector:key, the key being either the path to a file or the base64-encoded key itself.
+func readKeyTable(path string) ([]openDKIMKey, error) {
+	path = strings.TrimPrefix(path, "file:")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []openDKIMKey
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		source := fmt.Sprintf("%s:%d", path, line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: expected a key name and a domain:selector:key value", source)
+		}
+		value := strings.SplitN(fields[1], ":", 3)
+		if len(value) != 3 {
+			return nil, fmt.Errorf("%s: expected a domain:selector:key value", source)
+		}
+		if value[0] == "%" {
+			return nil, fmt.Errorf("%s: the %% domain placeholder is not supported", source)
+		}
+		k := openDKIMKey{domain: value[0], selector: value[1], source: source}
+		if strings.HasPrefix(value[2], "/") || strings.HasPrefix(value[2], ".") {
+			k.path = value[2]
+			k.source = value[2]
+			if k.priv, err = os.ReadFile(k.path); err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+		} else {
+			k.priv = []byte(value[2])
+		}
+		keys = append(keys, k)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// readKeyDir reads the keys of a directory tree laid out as by opendkim-genkey, with the key of
+// each selector of a domain stored in <dir>/<domain>/<selector>.private.
+func readKeyDir(dir string) ([]openDKIMKey, error) {
+	domains, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []openDKIMKey
+	for _, d := range domains {
+		if !d.IsDir() {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, d.Name(), "*.private"))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			priv, err := os.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, openDKIMKey{
+				domain:   d.Name(),
+				selector: strings.TrimSuffix(filepath.Base(p), ".private"),
+				source:   p,
+				path:     p,
+				priv:     priv,
+			})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no <domain>/<selector>.private key found in %s", dir)
+	}
+	return keys, nil
+}
+
+// publishedKey returns the public key of the record written by opendkim-genkey next to a key file,
+// or an empty string if there is none.
+func publishedKey(keyPath string) (string, error) {
+	if !strings.HasSuffix(keyPath, ".private") {
+		return "", nil
+	}
+	data, err := os.ReadFile(strings.TrimSuffix(keyPath, ".private") + ".txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var value strings.Builder
+	for _, m := range txtStringRe.FindAllSubmatch(data, -1) {
+		value.Write(m[1])
+	}
+	for _, tag := range strings.Split(value.String(), ";") {
+		name, v, ok := strings.Cut(tag, "=")
+		if ok && strings.TrimSpace(name) == "p" {
+			return strings.Join(strings.Fields(v), ""), nil
+		}
+	}
+	return "", nil
+}
+
+// migrateKey returns the DKIMKey adopting an OpenDKIM key, with the private key in the format
+// generated by the controller, and its public key.
+func migrateKey(k openDKIMKey, ttl uint, namespace string) (*dkimmanagerv2.DKIMKey, []byte, string, error) {
+	priv, err := dkim.NormalizePrivateKey(k.priv)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("%s: %w", k.source, err)
+	}
+	keyType, keyLength, err := dkim.DetectKeyType(priv)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("%s: %w", k.source, err)
+	}
+	if keyType == dkim.KeyTypeED25519 {
+		keyLength = dkim.KeyLength2048
+	}
+	dk := keygenOptions{
+		namespace: namespace,
+		domain:    k.domain,
+		selector:  k.selector,
+		keyType:   string(keyType),
+		keyLength: uint(keyLength),
+		purpose:   string(dkim.KeyPurposeDKIM),
+		ttl:       ttl,
+	}.dkimKey()
+	if err := validateKeygen(dk); err != nil {
+		return nil, nil, "", fmt.Errorf("%s: %w", k.source, err)
+	}
+	pub, err := dkim.DerivePublicKey(priv, keyType, keyLength)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("%s: %w", k.source, err)
+	}
+	if k.path != "" {
+		published, err := publishedKey(k.path)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if published != "" && published != pub {
+			return nil, nil, "", fmt.Errorf("%s: the key does not match the public key of its .txt record", k.source)
+		}
+	}
+	return dk, priv, pub, nil
+}
+
+// runMigrateOpenDKIM writes the Secret and DKIMKey manifests adopting the keys of an OpenDKIM
+// installation, and prints the DNS records the controller will publish for them, which carry
+// the same public keys as the records already published for OpenDKIM.
+func runMigrateOpenDKIM(args []string, stdout, stderr io.Writer) error {
+	var keyTable, keyDir, namespace, outputDir string
+	var ttl uint
+	fs := pflag.NewFlagSet("migrate-opendkim", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&keyTable, "key-table", "", "The OpenDKIM KeyTable listing the keys to migrate.")
+	fs.StringVar(&keyDir, "key-dir", "", "The directory holding the keys to migrate, as <domain>/<selector>.private files.")
+	fs.StringVar(&namespace, "namespace", "", "The namespace of the manifests. Empty omits it.")
+	fs.UintVar(&ttl, "ttl", 86400, "The TTL of the DKIM records.")
+	fs.StringVar(&outputDir, "output-dir", ".", "The directory the manifests are written to.")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Write DKIMKey and Secret manifests adopting the keys of an OpenDKIM installation.\n\nUsage:\n  dkim-manager migrate-opendkim (--key-table FILE | --key-dir DIR) [flags]\n\nFlags:\n%s", fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if (keyTable == "") == (keyDir == "") {
+		return fmt.Errorf("exactly one of --key-table and --key-dir is required")
+	}
+
+	var keys []openDKIMKey
+	var err error
+	if keyTable != "" {
+		keys, err = readKeyTable(keyTable)
+	} else {
+		keys, err = readKeyDir(keyDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Check all keys before writing anything, so that a failed migration can simply be run again.
+	files := map[string][]byte{}
+	var names []string
+	var records bytes.Buffer
+	for _, k := range keys {
+		dk, priv, pub, err := migrateKey(k, ttl, namespace)
+		if err != nil {
+			return err
+		}
+		name := dk.Name + ".yaml"
+		if _, ok := files[name]; ok {
+			return fmt.Errorf("%s: duplicate key for selector %s of %s", k.source, dk.Spec.Selector, dk.Spec.Domain)
+		}
+		entry := dk.Keys()[0]
+		data, err := keygenManifests(dk, map[string][]byte{entry.PrivateKeyFilename(): priv})
+		if err != nil {
+			return err
+		}
+		files[name] = data
+		names = append(names, name)
+		fmt.Fprintln(&records, dkim.GenZoneRecord(entry.TXTRecordName(), dk.Spec.TTL, dk.RecordValue(entry, pub)))
+	}
+	for _, name := range names {
+		path := filepath.Join(outputDir, name)
+		if err := writeNewFile(path, files[name]); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "wrote %s\n", path)
+	}
+	_, err = records.WriteTo(stdout)
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// writeFile writes a test fixture, creating its parent directories.
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+}
+
+// genPKCS8RSA generates an RSA key encoded as by recent versions of opendkim-genkey,
+// and returns it along with its DER encoding and public key.
+func genPKCS8RSA(t *testing.T) (string, []byte, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, int(dkim.KeyLength1024))
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), der, base64.StdEncoding.EncodeToString(pub)
+}
+
+// genkeyRecord returns a record as written by opendkim-genkey next to the private key.
+func genkeyRecord(selector, domain, pub string) string {
+	return fmt.Sprintf("%s._domainkey\tIN\tTXT\t( \"v=DKIM1; h=sha256; k=rsa; \"\n\t  \"p=%s\"\n\t  \"%s\" )  ; ----- DKIM key %s for %s\n",
+		selector, pub[:100], pub[100:], selector, domain)
+}
+
+// readManifests returns the Secret and DKIMKey of a manifest file.
+func readManifests(t *testing.T, path string) (*corev1.Secret, *dkimmanagerv2.DKIMKey) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	docs := strings.Split(string(data), "---\n")
+	require.Len(t, docs, 2)
+	s := &corev1.Secret{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[0]), s))
+	dk := &dkimmanagerv2.DKIMKey{}
+	require.NoError(t, yaml.UnmarshalStrict([]byte(docs[1]), dk))
+	return s, dk
+}
+
+func TestMigrateOpenDKIMKeyDir(t *testing.T) {
+	t.Parallel()
+	keyDir := t.TempDir()
+	rsaPriv, _, rsaPub := genPKCS8RSA(t)
+	writeFile(t, filepath.Join(keyDir, "example.com", "default.private"), rsaPriv)
+	writeFile(t, filepath.Join(keyDir, "example.com", "default.txt"), genkeyRecord("default", "example.com", rsaPub))
+	edPriv, edPub, err := dkim.GenED25519()
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(keyDir, "example.org", "mail.private"), string(edPriv))
+	writeFile(t, filepath.Join(keyDir, "README"), "not a key")
+
+	outputDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, runMigrateOpenDKIM([]string{"--key-dir", keyDir, "--namespace", "mail", "--ttl", "3600", "--output-dir", outputDir}, &stdout, &stderr))
+
+	s, dk := readManifests(t, filepath.Join(outputDir, "default-example-com.yaml"))
+	assert.Equal(t, "default-example-com", s.Name)
+	assert.Equal(t, "mail", s.Namespace)
+	assert.Equal(t, dkimmanagerv2.DKIMKeySpec{
+		SecretName: "default-example-com",
+		Selector:   "default",
+		Domain:     "example.com",
+		TTL:        3600,
+		KeyLength:  dkim.KeyLength1024,
+		KeyType:    dkim.KeyTypeRSA,
+		Purpose:    dkim.KeyPurposeDKIM,
+	}, dk.Spec)
+	pub, err := dkim.DerivePublicKey(s.Data["example.com.default.key"], dk.Spec.KeyType, dk.Spec.KeyLength)
+	require.NoError(t, err)
+	assert.Equal(t, rsaPub, pub)
+
+	s, dk = readManifests(t, filepath.Join(outputDir, "mail-example-org.yaml"))
+	assert.Equal(t, dkim.KeyTypeED25519, dk.Spec.KeyType)
+	assert.Equal(t, dkim.KeyLength2048, dk.Spec.KeyLength)
+	assert.Equal(t, edPriv, s.Data["example.org.mail.key"])
+
+	assert.Equal(t, strings.Join([]string{
+		dkim.GenZoneRecord("default._domainkey.example.com", 3600, dkim.GenTXTValue(rsaPub, dkim.KeyTypeRSA)),
+		dkim.GenZoneRecord("mail._domainkey.example.org", 3600, dkim.GenTXTValue(edPub, dkim.KeyTypeED25519)),
+	}, "\n")+"\n", stdout.String())
+}
+
+func TestMigrateOpenDKIMKeyTable(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	filePriv, _, filePub := genPKCS8RSA(t)
+	keyPath := filepath.Join(dir, "keys", "sel1.private")
+	writeFile(t, keyPath, filePriv)
+	_, inlineDER, inlinePub := genPKCS8RSA(t)
+	keyTable := filepath.Join(dir, "KeyTable")
+	writeFile(t, keyTable, strings.Join([]string{
+		"# KeyTable",
+		"",
+		fmt.Sprintf("sel1._domainkey.example.com  Example.com:sel1:%s", keyPath),
+		fmt.Sprintf("sel2._domainkey.example.net\texample.net:sel2:%s", base64.StdEncoding.EncodeToString(inlineDER)),
+	}, "\n"))
+
+	outputDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, runMigrateOpenDKIM([]string{"--key-table", "file:" + keyTable, "--output-dir", outputDir}, &stdout, &stderr))
+
+	s, dk := readManifests(t, filepath.Join(outputDir, "sel1-example-com.yaml"))
+	assert.Empty(t, s.Namespace)
+	assert.Equal(t, "example.com", dk.Spec.Domain)
+	pub, err := dkim.DeriveRSAPublicKey(s.Data["example.com.sel1.key"], dkim.KeyLength1024)
+	require.NoError(t, err)
+	assert.Equal(t, filePub, pub)
+
+	s, _ = readManifests(t, filepath.Join(outputDir, "sel2-example-net.yaml"))
+	pub, err = dkim.DeriveRSAPublicKey(s.Data["example.net.sel2.key"], dkim.KeyLength1024)
+	require.NoError(t, err)
+	assert.Equal(t, inlinePub, pub)
+
+	assert.Equal(t, strings.Join([]string{
+		dkim.GenZoneRecord("sel1._domainkey.example.com", 86400, dkim.GenTXTValue(filePub, dkim.KeyTypeRSA)),
+		dkim.GenZoneRecord("sel2._domainkey.example.net", 86400, dkim.GenTXTValue(inlinePub, dkim.KeyTypeRSA)),
+	}, "\n")+"\n", stdout.String())
+}
+
+func TestMigrateOpenDKIMInvalid(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	priv, _, pub := genPKCS8RSA(t)
+	_, _, otherPub := genPKCS8RSA(t)
+	writeFile(t, filepath.Join(dir, "mismatch", "example.com", "default.private"), priv)
+	writeFile(t, filepath.Join(dir, "mismatch", "example.com", "default.txt"), genkeyRecord("default", "example.com", otherPub))
+	writeFile(t, filepath.Join(dir, "valid", "example.com", "default.private"), priv)
+	writeFile(t, filepath.Join(dir, "valid", "example.com", "default.txt"), genkeyRecord("default", "example.com", pub))
+	writeFile(t, filepath.Join(dir, "empty", "README"), "")
+	_, edPub, err := dkim.GenED25519()
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(dir, "Placeholder"), fmt.Sprintf("default %%:default:%s\n", filepath.Join(dir, "valid", "example.com", "default.private")))
+	writeFile(t, filepath.Join(dir, "Malformed"), "default example.com\n")
+	writeFile(t, filepath.Join(dir, "NotAKey"), fmt.Sprintf("default example.com:default:%s\n", edPub))
+	writeFile(t, filepath.Join(dir, "Duplicate"), fmt.Sprintf("a example.com:default:%[1]s\nb example.com:default:%[1]s\n", filepath.Join(dir, "valid", "example.com", "default.private")))
+	writeFile(t, filepath.Join(dir, "InvalidSelector"), fmt.Sprintf("a example.com:in_valid:%s\n", filepath.Join(dir, "valid", "example.com", "default.private")))
+
+	cases := []struct {
+		title string
+		args  []string
+	}{
+		{title: "NoSource", args: nil},
+		{title: "BothSources", args: []string{"--key-table", filepath.Join(dir, "Duplicate"), "--key-dir", filepath.Join(dir, "valid")}},
+		{title: "MismatchedRecord", args: []string{"--key-dir", filepath.Join(dir, "mismatch")}},
+		{title: "EmptyKeyDir", args: []string{"--key-dir", filepath.Join(dir, "empty")}},
+		{title: "MissingKeyTable", args: []string{"--key-table", filepath.Join(dir, "missing")}},
+		{title: "DomainPlaceholder", args: []string{"--key-table", filepath.Join(dir, "Placeholder")}},
+		{title: "MalformedKeyTable", args: []string{"--key-table", filepath.Join(dir, "Malformed")}},
+		{title: "NotAKey", args: []string{"--key-table", filepath.Join(dir, "NotAKey")}},
+		{title: "Duplicate", args: []string{"--key-table", filepath.Join(dir, "Duplicate")}},
+		{title: "InvalidSelector", args: []string{"--key-table", filepath.Join(dir, "InvalidSelector")}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			outputDir := t.TempDir()
+			var stdout, stderr bytes.Buffer
+			assert.Error(t, runMigrateOpenDKIM(append(tc.args, "--output-dir", outputDir), &stdout, &stderr))
+			entries, err := os.ReadDir(outputDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	outputDir := t.TempDir()
+	require.NoError(t, runMigrateOpenDKIM([]string{"--key-dir", filepath.Join(dir, "valid"), "--output-dir", outputDir}, &stdout, &stderr))
+	assert.Error(t, runMigrateOpenDKIM([]string{"--key-dir", filepath.Join(dir, "valid"), "--output-dir", outputDir}, &stdout, &stderr), "existing manifests must not be overwritten")
+}
+
+func TestMigrateKeyBase64ED25519(t *testing.T) {
+	t.Parallel()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	k := openDKIMKey{domain: "example.com", selector: "ed", source: "test", priv: []byte(base64.StdEncoding.EncodeToString(der))}
+	dk, priv, _, err := migrateKey(k, 86400, "")
+	require.NoError(t, err)
+	assert.Equal(t, dkim.KeyTypeED25519, dk.Spec.KeyType)
+	block, _ := pem.Decode(priv)
+	require.NotNil(t, block)
+	assert.Equal(t, der, block.Bytes)
+}
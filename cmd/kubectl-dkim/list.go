@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+func (c *cli) list(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	allNamespaces := fs.BoolP("all-namespaces", "A", false, "List DKIMKeys across all namespaces.")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	var opts []client.ListOption
+	if !*allNamespaces {
+		opts = append(opts, client.InNamespace(c.namespace))
+	}
+	dkl := &dkimmanagerv2.DKIMKeyList{}
+	if err := c.client.List(ctx, dkl, opts...); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 3, ' ', 0)
+	header := "NAME\tDOMAIN\tSELECTORS\tTYPE\tPURPOSE\tKEY AGE\tREADY\tREASON"
+	if *allNamespaces {
+		header = "NAMESPACE\t" + header
+	}
+	fmt.Fprintln(w, header)
+	for i := range dkl.Items {
+		dk := &dkl.Items[i]
+		age, err := c.keyAge(ctx, dk)
+		if err != nil {
+			return err
+		}
+		ready, reason := status(dk)
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", dk.Name, dk.Spec.Domain, strings.Join(selectors(dk), ","),
+			dk.Spec.KeyType, dk.Spec.Purpose, age, ready, reason)
+		if *allNamespaces {
+			row = dk.Namespace + "\t" + row
+		}
+		fmt.Fprintln(w, row)
+	}
+	return w.Flush()
+}
+
+// selectors returns the selectors of the keys of the DKIMKey.
+func selectors(dk *dkimmanagerv2.DKIMKey) []string {
+	var res []string
+	for _, k := range dk.Keys() {
+		res = append(res, k.Selector)
+	}
+	return res
+}
+
+// keyAge returns the age of the Secret holding the private keys of the DKIMKey, or - if there is none.
+func (c *cli) keyAge(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (string, error) {
+	if dk.Spec.Revoked || dk.Spec.SecretName == "" {
+		return "-", nil
+	}
+	s := &metav1.PartialObjectMetadata{}
+	s.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: dk.Namespace, Name: dk.Spec.SecretName}, s); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "-", nil
+		}
+		return "", err
+	}
+	return duration.HumanDuration(c.now().Sub(s.CreationTimestamp.Time)), nil
+}
+
+// status returns the status of the Ready condition of the DKIMKey, and the reason of the first
+// failing condition, so that e.g. keys requiring rotation stand out among ready keys.
+func status(dk *dkimmanagerv2.DKIMKey) (string, string) {
+	ready := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+	if ready == nil {
+		return string(metav1.ConditionUnknown), "-"
+	}
+	reason := ready.Reason
+	if ready.Status == metav1.ConditionTrue {
+		for _, t := range []string{dkimmanagerv2.ConditionPolicyCompliant, dkimmanagerv2.ConditionDNSPublished} {
+			if cond := meta.FindStatusCondition(dk.Status.Conditions, t); cond != nil && cond.Status == metav1.ConditionFalse {
+				reason = cond.Reason
+				break
+			}
+		}
+	}
+	return string(ready.Status), reason
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-dkim is a kubectl plugin inspecting and operating the DKIMKeys managed by dkim-manager.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(dkimmanagerv2.AddToScheme(scheme))
+}
+
+// errFailed is returned when a command already reported its failure on the output.
+var errFailed = errors.New("")
+
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(c *cli, ctx context.Context, fs *pflag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{name: "list", description: "List DKIMKeys with their domain, selectors, key age and status.", run: (*cli).list},
+	{name: "show-record", args: "NAME", description: "Print the DKIM records of a DKIMKey, as TXT values and zone file lines.", run: (*cli).showRecord},
+	{name: "rotate", args: "NAME", description: "Create a DKIMKey with a new selector, copying the spec of an existing DKIMKey.", run: (*cli).rotate},
+	{name: "revoke", args: "NAME", description: "Revoke a DKIMKey, publishing an empty public key and deleting its private keys.", run: (*cli).revoke},
+	{name: "verify", args: "NAME", description: "Check that the Secret, the DNSEndpoint and live DNS agree on the DKIM records of a DKIMKey.", run: (*cli).verify},
+	{name: "export-public", args: "NAME", description: "Print the public keys of a DKIMKey in PEM format.", run: (*cli).exportPublic},
+}
+
+// cli holds the state shared by all commands.
+type cli struct {
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+	now    func() time.Time
+
+	kubeconfig  string
+	kubeContext string
+	namespace   string
+
+	// client is created from the kubeconfig by connect, unless already set.
+	client client.Client
+}
+
+// flagSet returns a flag set for the command, including the flags selecting the cluster and namespace.
+func (c *cli) flagSet(cmd command) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(cmd.name, pflag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.StringVar(&c.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&c.kubeContext, "context", "", "The kubeconfig context to use.")
+	fs.StringVarP(&c.namespace, "namespace", "n", c.namespace, "The namespace of the DKIMKeys. Defaults to the namespace of the kubeconfig context.")
+	fs.Usage = func() {
+		fmt.Fprintf(c.errOut, "%s\n\nUsage:\n  kubectl dkim %s %s [flags]\n\nFlags:\n%s", cmd.description, cmd.name, cmd.args, fs.FlagUsages())
+	}
+	return fs
+}
+
+// parse parses the arguments of a command expecting nargs positional arguments, then connects to the cluster.
+func (c *cli) parse(fs *pflag.FlagSet, args []string, nargs int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return nil, fmt.Errorf("%s expects %d argument(s), got %d", fs.Name(), nargs, fs.NArg())
+	}
+	return fs.Args(), c.connect()
+}
+
+func (c *cli) connect() error {
+	if c.client != nil {
+		return nil
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.kubeconfig
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: c.kubeContext})
+	if c.namespace == "" {
+		ns, _, err := cc.Namespace()
+		if err != nil {
+			return err
+		}
+		c.namespace = ns
+	}
+	cfg, err := cc.ClientConfig()
+	if err != nil {
+		return err
+	}
+	c.client, err = client.New(cfg, client.Options{Scheme: scheme})
+	return err
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.errOut, "kubectl-dkim inspects and operates the DKIMKeys managed by dkim-manager.\n\nUsage:\n  kubectl dkim COMMAND [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.errOut, "  %-14s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(c.errOut, "\nUse \"kubectl dkim COMMAND --help\" for the flags of a command.\n")
+}
+
+// run runs the command named by the first argument.
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		c.usage()
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(c, ctx, c.flagSet(cmd), args[1:])
+		}
+	}
+	c.usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func main() {
+	c := &cli{
+		in:     os.Stdin,
+		out:    os.Stdout,
+		errOut: os.Stderr,
+		now:    time.Now,
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := c.run(ctx, os.Args[1:])
+	cancel()
+	switch {
+	case err == nil, errors.Is(err, pflag.ErrHelp):
+	case errors.Is(err, errFailed):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+var testNow = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestCLI(t *testing.T, objs ...client.Object) (*cli, *bytes.Buffer) {
+	t.Helper()
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{corev1.SchemeGroupVersion, dkimmanagerv2.GroupVersion, externaldns.GroupVersionKind.GroupVersion()})
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	mapper.Add(dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeyKind), meta.RESTScopeNamespace)
+	mapper.Add(externaldns.GroupVersionKind, meta.RESTScopeNamespace)
+	out := &bytes.Buffer{}
+	return &cli{
+		in:        strings.NewReader(""),
+		out:       out,
+		errOut:    &bytes.Buffer{},
+		now:       func() time.Time { return testNow },
+		namespace: "default",
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(objs...).Build(),
+	}, out
+}
+
+// testKey returns a DKIMKey with an RSA and an Ed25519 key, and the Secret holding them.
+func testKey(t *testing.T, name string) (*dkimmanagerv2.DKIMKey, *corev1.Secret, []string) {
+	t.Helper()
+	dk := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName:      name,
+			Selector:        "sel1",
+			Domain:          "example.com",
+			TTL:             3600,
+			KeyLength:       dkim.KeyLength2048,
+			KeyType:         dkim.KeyTypeRSA,
+			Purpose:         dkim.KeyPurposeDKIM,
+			ED25519Selector: "sel1-ed25519",
+		},
+	}
+	rsaPriv, rsaPub, err := dkim.GenRSA(dkim.KeyLength2048)
+	require.NoError(t, err)
+	edPriv, edPub, err := dkim.GenED25519()
+	require.NoError(t, err)
+	keys := dk.Keys()
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(testNow.Add(-48 * time.Hour))},
+		Data: map[string][]byte{
+			keys[0].PrivateKeyFilename(): rsaPriv,
+			keys[1].PrivateKeyFilename(): edPriv,
+		},
+	}
+	return dk, s, []string{rsaPub, edPub}
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+	dk, s, _ := testKey(t, "key1")
+	dk.Status.Conditions = []metav1.Condition{
+		{Type: dkimmanagerv2.ConditionReady, Status: metav1.ConditionTrue, Reason: dkimmanagerv2.ReasonSucceeded},
+		{Type: dkimmanagerv2.ConditionPolicyCompliant, Status: metav1.ConditionFalse, Reason: dkimmanagerv2.ReasonRotationRequired},
+	}
+	other := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{Name: "key2", Namespace: "other"},
+		Spec:       dkimmanagerv2.DKIMKeySpec{SecretName: "key2", Selector: "arc1", Domain: "example.org", KeyType: dkim.KeyTypeED25519, Purpose: dkim.KeyPurposeARC},
+	}
+	c, out := newTestCLI(t, dk, s, other)
+
+	require.NoError(t, c.run(context.Background(), []string{"list"}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"NAME", "DOMAIN", "SELECTORS", "TYPE", "PURPOSE", "KEY", "AGE", "READY", "REASON"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"key1", "example.com", "sel1,sel1-ed25519", "rsa", "dkim", "2d", "True", "RotationRequired"}, strings.Fields(lines[1]))
+
+	out.Reset()
+	require.NoError(t, c.run(context.Background(), []string{"list", "-A"}))
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"other", "key2", "example.org", "arc1", "ed25519", "arc", "-", "Unknown", "-"}, strings.Fields(lines[2]))
+}
+
+func TestShowRecord(t *testing.T) {
+	t.Parallel()
+	dk, s, pubs := testKey(t, "key1")
+	dk.Spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: "dkim.example.net"}
+	c, out := newTestCLI(t, dk, s)
+
+	require.NoError(t, c.run(context.Background(), []string{"show-record", "--zone", "key1"}))
+	keys := dk.Keys()
+	assert.Equal(t, strings.Join([]string{
+		dkim.GenZoneRecord("sel1._domainkey.example.com.dkim.example.net", 3600, dkim.GenTXTValue(pubs[0], dkim.KeyTypeRSA)),
+		"sel1._domainkey.example.com.\t3600\tIN\tCNAME\tsel1._domainkey.example.com.dkim.example.net.",
+		dkim.GenZoneRecord(keys[1].TXTRecordName(), 3600, dkim.GenTXTValue(pubs[1], dkim.KeyTypeED25519)),
+		"sel1-ed25519._domainkey.example.com.\t3600\tIN\tCNAME\tsel1-ed25519._domainkey.example.com.dkim.example.net.",
+	}, "\n")+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, c.run(context.Background(), []string{"show-record", "key1"}))
+	assert.Contains(t, out.String(), "Value: v=DKIM1; h=sha256; k=rsa;p="+pubs[0]+"\n")
+
+	assert.Error(t, c.run(context.Background(), []string{"show-record", "missing"}))
+	assert.Error(t, c.run(context.Background(), []string{"show-record"}))
+}
+
+func TestExportPublic(t *testing.T) {
+	t.Parallel()
+	dk, s, _ := testKey(t, "key1")
+	c, out := newTestCLI(t, dk, s)
+
+	require.NoError(t, c.run(context.Background(), []string{"export-public", "key1"}))
+	rest := out.Bytes()
+	var types []string
+	for {
+		block, r := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		assert.Equal(t, "PUBLIC KEY", block.Type)
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.NoError(t, err)
+		switch pub.(type) {
+		case *rsa.PublicKey:
+			types = append(types, "rsa")
+		case ed25519.PublicKey:
+			types = append(types, "ed25519")
+		}
+		rest = r
+	}
+	assert.Equal(t, []string{"rsa", "ed25519"}, types)
+	assert.Contains(t, out.String(), "# sel1._domainkey.example.com (rsa)\n")
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+	dk, s, _ := testKey(t, "key1")
+	dk.Labels = map[string]string{"team": "mail"}
+	managed := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "set-example.org",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: dkimmanagerv2.GroupVersion.String(),
+				Kind:       dkimmanagerv2.DKIMKeySetKind,
+				Name:       "set",
+				UID:        "uid",
+				Controller: ptr.To(true),
+			}},
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{SecretName: "set-example.org", Selector: "sel1", Domain: "example.org", KeyType: dkim.KeyTypeRSA},
+	}
+	c, out := newTestCLI(t, dk, s, managed)
+	ctx := context.Background()
+
+	require.NoError(t, c.run(ctx, []string{"rotate", "key1", "--selector", "sel2"}))
+	assert.Contains(t, out.String(), "dkimkey/key1-20260401 created")
+	rotated := &dkimmanagerv2.DKIMKey{}
+	require.NoError(t, c.client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "key1-20260401"}, rotated))
+	expected := dk.Spec
+	expected.Selector = "sel2"
+	expected.SecretName = "key1-20260401"
+	expected.ED25519Selector = "sel2-ed25519"
+	assert.Equal(t, expected, rotated.Spec)
+	assert.Equal(t, dk.Labels, rotated.Labels)
+
+	require.NoError(t, c.run(ctx, []string{"rotate", "key1", "--selector", "sel3", "--ed25519-selector", "ed3", "--name", "key1-next"}))
+	require.NoError(t, c.client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "key1-next"}, rotated))
+	assert.Equal(t, "ed3", rotated.Spec.ED25519Selector)
+
+	assert.Error(t, c.run(ctx, []string{"rotate", "key1"}))
+	assert.Error(t, c.run(ctx, []string{"rotate", "key1", "--selector", "sel1"}))
+	assert.ErrorContains(t, c.run(ctx, []string{"rotate", "set-example.org", "--selector", "sel2"}), "DKIMKeySet set")
+}
+
+func TestRevoke(t *testing.T) {
+	t.Parallel()
+	dk, s, _ := testKey(t, "key1")
+	c, out := newTestCLI(t, dk, s)
+	ctx := context.Background()
+	key := client.ObjectKey{Namespace: "default", Name: "key1"}
+
+	c.in = strings.NewReader("n\n")
+	assert.Error(t, c.run(ctx, []string{"revoke", "key1"}))
+	require.NoError(t, c.client.Get(ctx, key, dk))
+	assert.False(t, dk.Spec.Revoked)
+
+	c.in = strings.NewReader("y\n")
+	require.NoError(t, c.run(ctx, []string{"revoke", "key1"}))
+	require.NoError(t, c.client.Get(ctx, key, dk))
+	assert.True(t, dk.Spec.Revoked)
+	assert.Equal(t, "dkimkey/key1 revoked\n", out.String())
+
+	out.Reset()
+	require.NoError(t, c.run(ctx, []string{"revoke", "--yes", "key1"}))
+	assert.Equal(t, "dkimkey/key1 is already revoked\n", out.String())
+}
+
+func dnsEndpoint(name string, targets map[string][]string) *unstructured.Unstructured {
+	de := externaldns.DNSEndpoint()
+	de.SetName(name)
+	de.SetNamespace("default")
+	var endpoints []any
+	for dnsName, values := range targets {
+		ts := make([]any, 0, len(values))
+		for _, v := range values {
+			ts = append(ts, v)
+		}
+		endpoints = append(endpoints, map[string]any{
+			"dnsName":    dnsName,
+			"recordType": "TXT",
+			"recordTTL":  int64(3600),
+			"targets":    ts,
+		})
+	}
+	de.Object["spec"] = map[string]any{"endpoints": endpoints}
+	return de
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dk, s, pubs := testKey(t, "key1")
+	de := dnsEndpoint("key1", map[string][]string{
+		"sel1._domainkey.example.com":         {dkim.GenTXTValue(pubs[0], dkim.KeyTypeRSA)},
+		"sel1-ed25519._domainkey.example.com": {dkim.GenTXTValue(pubs[1], dkim.KeyTypeED25519)},
+	})
+	c, out := newTestCLI(t, dk, s, de)
+	require.NoError(t, c.run(ctx, []string{"verify", "key1"}))
+	assert.NotContains(t, out.String(), "FAIL")
+	assert.Contains(t, out.String(), "SKIP  DNS")
+
+	stale, s2, _ := testKey(t, "key2")
+	de2 := dnsEndpoint("key2", map[string][]string{
+		"sel1._domainkey.example.com": {dkim.GenTXTValue(pubs[0], dkim.KeyTypeRSA)},
+	})
+	c, out = newTestCLI(t, stale, s2, de2)
+	assert.ErrorIs(t, c.run(ctx, []string{"verify", "key2"}), errFailed)
+	assert.Contains(t, out.String(), "FAIL  DNSEndpoint  sel1._domainkey.example.com: TXT targets differ from the Secret")
+	assert.Contains(t, out.String(), "FAIL  DNSEndpoint  sel1-ed25519._domainkey.example.com: no TXT endpoint")
+
+	out.Reset()
+	require.NoError(t, c.run(ctx, []string{"verify", "key2", "--skip-dnsendpoint"}))
+
+	missing, _, _ := testKey(t, "key3")
+	c, out = newTestCLI(t, missing)
+	assert.ErrorIs(t, c.run(ctx, []string{"verify", "key3"}), errFailed)
+	assert.Contains(t, out.String(), "FAIL  Secret")
+}
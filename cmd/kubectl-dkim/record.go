@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+func (c *cli) getKey(ctx context.Context, name string) (*dkimmanagerv2.DKIMKey, error) {
+	dk := &dkimmanagerv2.DKIMKey{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: c.namespace, Name: name}, dk); err != nil {
+		return nil, err
+	}
+	return dk, nil
+}
+
+// publicKeys derives the public keys of the DKIMKey, in the order of dk.Keys(), from the private keys
+// held in its Secret. Revoked keys have empty public keys.
+func (c *cli) publicKeys(ctx context.Context, dk *dkimmanagerv2.DKIMKey) ([]string, error) {
+	keys := dk.Keys()
+	pubs := make([]string, len(keys))
+	if dk.Spec.Revoked {
+		return pubs, nil
+	}
+	s := &corev1.Secret{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: dk.Namespace, Name: dk.Spec.SecretName}, s); err != nil {
+		return nil, fmt.Errorf("failed to get Secret: %w", err)
+	}
+	for i, k := range keys {
+		priv, ok := s.Data[k.PrivateKeyFilename()]
+		if !ok {
+			return nil, fmt.Errorf("private key for selector %s not found in Secret %s", k.Selector, s.Name)
+		}
+		pub, err := dkim.DerivePublicKey(priv, k.KeyType, k.KeyLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive public key for selector %s: %w", k.Selector, err)
+		}
+		pubs[i] = pub
+	}
+	return pubs, nil
+}
+
+// records returns the records published for the DKIMKey with the given public keys,
+// as the controller publishes them.
+func records(dk *dkimmanagerv2.DKIMKey, pubs []string) []publisher.Record {
+	var res []publisher.Record
+	for i, k := range dk.Keys() {
+		res = append(res, publisher.Record{
+			Name:    k.TXTRecordName(),
+			TTL:     dk.Spec.TTL,
+			Targets: []string{dk.RecordValue(k, pubs[i])},
+		})
+		if k.DelegationZone != "" {
+			res = append(res, publisher.Record{
+				Name:    k.RecordName(),
+				Type:    publisher.RecordTypeCNAME,
+				TTL:     dk.Spec.TTL,
+				Targets: []string{k.TXTRecordName()},
+			})
+		}
+	}
+	return res
+}
+
+// zoneRecord formats a record as a line of a BIND zone file.
+func zoneRecord(r publisher.Record) string {
+	if r.Type == publisher.RecordTypeCNAME {
+		return dkim.GenCNAMEZoneRecord(r.Name, r.TTL, r.Targets[0])
+	}
+	return dkim.GenZoneRecord(r.Name, r.TTL, r.Targets[0])
+}
+
+func (c *cli) showRecord(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	zoneOnly := fs.Bool("zone", false, "Only print zone file lines.")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	dk, err := c.getKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	pubs, err := c.publicKeys(ctx, dk)
+	if err != nil {
+		return err
+	}
+	for i, r := range records(dk, pubs) {
+		if *zoneOnly {
+			fmt.Fprintln(c.out, zoneRecord(r))
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(c.out)
+		}
+		fmt.Fprintf(c.out, "Name:  %s\nType:  %s\n", r.Name, r.RecordType())
+		if r.Type == publisher.RecordTypeCNAME {
+			fmt.Fprintf(c.out, "Value: %s\n", r.Targets[0])
+		} else {
+			fmt.Fprintf(c.out, "Value: %s\n", dkim.JoinTXTValue(r.Targets[0]))
+		}
+		fmt.Fprintf(c.out, "Zone:  %s\n", zoneRecord(r))
+	}
+	return nil
+}
+
+func (c *cli) exportPublic(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	dk, err := c.getKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if dk.Spec.Revoked {
+		return fmt.Errorf("dkimkey %s is revoked and has no public keys", dk.Name)
+	}
+	pubs, err := c.publicKeys(ctx, dk)
+	if err != nil {
+		return err
+	}
+	for i, k := range dk.Keys() {
+		der, err := base64.StdEncoding.DecodeString(pubs[i])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "# %s (%s)\n", k.RecordName(), k.KeyType)
+		if err := pem.Encode(c.out, &pem.Block{Type: "PUBLIC KEY", Bytes: der}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+func (c *cli) rotate(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	selector := fs.String("selector", "", "The selector of the new key. Required.")
+	ed25519Selector := fs.String("ed25519-selector", "", "The selector of the new Ed25519 key, for DKIMKeys publishing one. Defaults to <selector>-ed25519.")
+	newName := fs.String("name", "", "The name of the new DKIMKey, also used for its Secret. Defaults to <name>-<YYYYMMDD>.")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if *selector == "" {
+		return fmt.Errorf("--selector is required")
+	}
+	old, err := c.getKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if owner := metav1.GetControllerOf(old); owner != nil && owner.Kind == dkimmanagerv2.DKIMKeySetKind {
+		return fmt.Errorf("dkimkey %s is managed by DKIMKeySet %s and cannot be rotated individually", old.Name, owner.Name)
+	}
+	if *selector == old.Spec.Selector {
+		return fmt.Errorf("the new selector must differ from the current selector %s", old.Spec.Selector)
+	}
+
+	dk := &dkimmanagerv2.DKIMKey{}
+	dk.Name = *newName
+	if dk.Name == "" {
+		dk.Name = old.Name + "-" + c.now().UTC().Format("20060102")
+	}
+	dk.Namespace = old.Namespace
+	dk.Labels = old.Labels
+	old.Spec.DeepCopyInto(&dk.Spec)
+	dk.Spec.Selector = *selector
+	dk.Spec.SecretName = dk.Name
+	dk.Spec.Revoked = false
+	if old.Spec.ED25519Selector != "" {
+		dk.Spec.ED25519Selector = *ed25519Selector
+		if dk.Spec.ED25519Selector == "" {
+			dk.Spec.ED25519Selector = *selector + "-ed25519"
+		}
+	}
+	if err := c.client.Create(ctx, dk); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "dkimkey/%s created with selector %s\n", dk.Name, dk.Spec.Selector)
+	fmt.Fprintf(c.out, "Once signers use the new selector and the old signatures expired, retire dkimkey/%s with:\n", old.Name)
+	fmt.Fprintf(c.out, "  kubectl dkim revoke -n %s %s\n", old.Namespace, old.Name)
+	return nil
+}
+
+func (c *cli) revoke(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	yes := fs.BoolP("yes", "y", false, "Do not ask for confirmation.")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	dk, err := c.getKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if dk.Spec.Revoked {
+		fmt.Fprintf(c.out, "dkimkey/%s is already revoked\n", dk.Name)
+		return nil
+	}
+	if !*yes {
+		fmt.Fprintf(c.errOut, "Revoking dkimkey/%s publishes an empty public key for %s and deletes its private keys.\n", dk.Name, strings.Join(dk.RecordNames(), ", "))
+		fmt.Fprintf(c.errOut, "Messages signed with it will fail DKIM verification. This cannot be undone. Continue? [y/N] ")
+		answer, _ := bufio.NewReader(c.in).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return fmt.Errorf("aborted")
+		}
+	}
+	patch := client.MergeFrom(dk.DeepCopy())
+	dk.Spec.Revoked = true
+	if err := c.client.Patch(ctx, dk, patch); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "dkimkey/%s revoked\n", dk.Name)
+	return nil
+}
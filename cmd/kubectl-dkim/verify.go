@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/dnsprobe"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// verifier reports the outcome of each check of the verify command.
+type verifier struct {
+	c      *cli
+	failed bool
+}
+
+func (v *verifier) report(result, check, format string, args ...any) {
+	if result == "FAIL" {
+		v.failed = true
+	}
+	fmt.Fprintf(v.c.out, "%-5s %-12s %s\n", result, check, fmt.Sprintf(format, args...))
+}
+
+func (c *cli) verify(ctx context.Context, fs *pflag.FlagSet, args []string) error {
+	nameservers := fs.StringSlice("nameservers", nil, "The recursive nameservers, as host or host:port, queried to check live DNS. Empty skips the check.")
+	timeout := fs.Duration("timeout", 5*time.Second, "The timeout for each DNS query.")
+	skipDNSEndpoint := fs.Bool("skip-dnsendpoint", false, "Skip the DNSEndpoint check, e.g. when dkim-manager publishes records without external-dns.")
+	group := fs.String("dnsendpoint-group", externaldns.DNSEndpointGroup, "The API group of external-dns DNSEndpoints.")
+	version := fs.String("dnsendpoint-version", "", "The API version of external-dns DNSEndpoints. Empty selects the preferred version served by the API server.")
+	kind := fs.String("dnsendpoint-kind", externaldns.DNSEndpointKind, "The kind of external-dns DNSEndpoints.")
+	args, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	dk, err := c.getKey(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	v := &verifier{c: c}
+	pubs, err := c.publicKeys(ctx, dk)
+	if err != nil {
+		v.report("FAIL", "Secret", "%v", err)
+		return errFailed
+	}
+	if dk.Spec.Revoked {
+		v.report("SKIP", "Secret", "dkimkey is revoked, expecting empty public keys")
+	} else {
+		v.report("OK", "Secret", "%s holds the private keys of selector(s) %s", dk.Spec.SecretName, strings.Join(selectors(dk), ", "))
+	}
+	expected := records(dk, pubs)
+
+	if *skipDNSEndpoint {
+		v.report("SKIP", "DNSEndpoint", "skipped by --skip-dnsendpoint")
+	} else {
+		gvk, err := externaldns.Discover(c.client.RESTMapper(), *group, *version, *kind)
+		if err != nil {
+			v.report("FAIL", "DNSEndpoint", "%v", err)
+		} else {
+			c.verifyDNSEndpoint(ctx, v, dk, gvk, expected)
+		}
+	}
+
+	if len(*nameservers) == 0 {
+		v.report("SKIP", "DNS", "no --nameservers given")
+	} else {
+		verifyDNS(ctx, v, dnsprobe.NewProber(*nameservers, *timeout), expected)
+	}
+
+	if v.failed {
+		return errFailed
+	}
+	return nil
+}
+
+// verifyDNSEndpoint checks that the DNSEndpoint of the DKIMKey holds the expected records.
+func (c *cli) verifyDNSEndpoint(ctx context.Context, v *verifier, dk *dkimmanagerv2.DKIMKey, gvk schema.GroupVersionKind, expected []publisher.Record) {
+	de := externaldns.NewDNSEndpoint(gvk)
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: dk.Namespace, Name: dk.Name}, de); err != nil {
+		if apierrors.IsNotFound(err) {
+			v.report("FAIL", "DNSEndpoint", "%s not found", dk.Name)
+			return
+		}
+		v.report("FAIL", "DNSEndpoint", "%v", err)
+		return
+	}
+	endpoints, _, err := unstructured.NestedSlice(de.Object, "spec", "endpoints")
+	if err != nil {
+		v.report("FAIL", "DNSEndpoint", "%s has invalid endpoints: %v", dk.Name, err)
+		return
+	}
+	for _, r := range expected {
+		targets, found := endpointTargets(endpoints, r)
+		switch {
+		case !found:
+			v.report("FAIL", "DNSEndpoint", "%s: no %s endpoint", r.Name, r.RecordType())
+		case !sameTargets(r, targets):
+			v.report("FAIL", "DNSEndpoint", "%s: %s targets differ from the Secret", r.Name, r.RecordType())
+		default:
+			v.report("OK", "DNSEndpoint", "%s: %s targets match the Secret", r.Name, r.RecordType())
+		}
+	}
+}
+
+// endpointTargets returns the targets of the endpoint publishing the record r.
+func endpointTargets(endpoints []any, r publisher.Record) ([]string, bool) {
+	for _, e := range endpoints {
+		endpoint, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(endpoint, "dnsName")
+		recordType, _, _ := unstructured.NestedString(endpoint, "recordType")
+		if !strings.EqualFold(strings.TrimSuffix(name, "."), r.Name) || recordType != r.RecordType() {
+			continue
+		}
+		targets, _, _ := unstructured.NestedStringSlice(endpoint, "targets")
+		return targets, true
+	}
+	return nil, false
+}
+
+func sameTargets(r publisher.Record, targets []string) bool {
+	normalize := func(t string) string {
+		if r.Type == publisher.RecordTypeCNAME {
+			return strings.ToLower(strings.TrimSuffix(t, "."))
+		}
+		return dkim.JoinTXTValue(t)
+	}
+	want := make([]string, 0, len(r.Targets))
+	for _, t := range r.Targets {
+		want = append(want, normalize(t))
+	}
+	got := make([]string, 0, len(targets))
+	for _, t := range targets {
+		got = append(got, normalize(t))
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	return slices.Equal(want, got)
+}
+
+// verifyDNS checks that the nameservers serve the expected TXT values. Delegated records are
+// resolved through their CNAME record, as verifiers do.
+func verifyDNS(ctx context.Context, v *verifier, prober *dnsprobe.Prober, expected []publisher.Record) {
+	values := map[string]string{}
+	for _, r := range expected {
+		if r.Type != publisher.RecordTypeCNAME {
+			values[r.Name] = r.Targets[0]
+		}
+	}
+	for _, r := range expected {
+		value := values[r.Name]
+		if r.Type == publisher.RecordTypeCNAME {
+			value = values[r.Targets[0]]
+		}
+		if err := prober.Verify(ctx, r.Name, value); err != nil {
+			v.report("FAIL", "DNS", "%v", err)
+			continue
+		}
+		v.report("OK", "DNS", "%s serves the expected TXT value", r.Name)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/bimi"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// BIMIRecordReconciler reconciles a BIMIRecord object.
+type BIMIRecordReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// Publisher publishes the BIMI records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+
+	records *domainRecordReconciler[*dkimmanagerv2.BIMIRecord]
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=bimirecords,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=bimirecords/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=bimirecords/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dmarcpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile publishes the BIMI record described by a BIMIRecord, once its logo is valid and the DMARC policy
+// of its domain is enforced. The record is withdrawn if the logo becomes invalid or the DMARC policy stops being enforced.
+func (r *BIMIRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.records.Reconcile(ctx, req)
+}
+
+// checkDMARCEnforcement withdraws the BIMI record and marks the BIMIRecord as invalid unless the DMARC policy
+// of its domain is enforced, as receivers ignore BIMI records otherwise.
+// It returns false if reconciliation should not proceed.
+func (r *BIMIRecordReconciler) checkDMARCEnforcement(ctx context.Context, br *dkimmanagerv2.BIMIRecord) (bool, error) {
+	err := policy.CheckDMARCEnforcement(ctx, r.Client, br.Spec.Domain)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, policy.ErrDMARCNotEnforced) {
+		return false, err
+	}
+	log.FromContext(ctx).Info("DMARC policy is not enforced, withdrawing BIMI record", "domain", br.Spec.Domain)
+	return false, r.withdraw(ctx, br, err.Error())
+}
+
+// checkLogo withdraws the BIMI record and marks the BIMIRecord as invalid if its logo is missing
+// or does not follow the SVG Tiny PS profile.
+// The ConfigMap is read from the API server, as only the metadata of ConfigMaps is cached.
+// It returns false if reconciliation should not proceed.
+func (r *BIMIRecordReconciler) checkLogo(ctx context.Context, br *dkimmanagerv2.BIMIRecord) (bool, error) {
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: br.Namespace, Name: br.Spec.Logo.Name}
+	if err := r.ReadClient.Get(ctx, key, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		return false, r.withdraw(ctx, br, fmt.Sprintf("logo ConfigMap %s not found", key))
+	}
+	data, ok := cm.BinaryData[br.Spec.Logo.Key]
+	if !ok {
+		var s string
+		s, ok = cm.Data[br.Spec.Logo.Key]
+		data = []byte(s)
+	}
+	if !ok {
+		return false, r.withdraw(ctx, br, fmt.Sprintf("logo ConfigMap %s has no key %s", key, br.Spec.Logo.Key))
+	}
+	if err := bimi.ValidateSVGTinyPS(data); err != nil {
+		return false, r.withdraw(ctx, br, fmt.Sprintf("invalid logo: %v", err))
+	}
+	return true, nil
+}
+
+// withdraw unpublishes the BIMI record and marks the BIMIRecord as invalid with the given message.
+func (r *BIMIRecordReconciler) withdraw(ctx context.Context, br *dkimmanagerv2.BIMIRecord, message string) error {
+	if err := r.Publisher.Unpublish(ctx, br, []string{br.RecordName()}); err != nil {
+		return err
+	}
+	return r.records.markInvalid(ctx, br, message)
+}
+
+// bimiRecordsForDMARCPolicy enqueues the BIMIRecords of the domain of a changed DMARCPolicy and of its subdomains,
+// so that BIMI records follow the enforcement of the DMARC policy.
+func (r *BIMIRecordReconciler) bimiRecordsForDMARCPolicy(ctx context.Context, o client.Object) []reconcile.Request {
+	dp, ok := o.(*dkimmanagerv2.DMARCPolicy)
+	if !ok {
+		return nil
+	}
+	domain := policy.NormalizeRecordName(dp.Spec.Domain)
+	return r.records.recordsMatching(ctx, func(br *dkimmanagerv2.BIMIRecord) bool {
+		d := policy.NormalizeRecordName(br.Spec.Domain)
+		return d == domain || strings.HasSuffix(d, "."+domain)
+	})
+}
+
+// bimiRecordsForLogo enqueues the BIMIRecords referencing a changed ConfigMap as their logo,
+// so that BIMI records follow the validity of their logo. ConfigMaps are watched by metadata only,
+// which avoids caching the data of every ConfigMap in the watched namespaces.
+func (r *BIMIRecordReconciler) bimiRecordsForLogo(ctx context.Context, o client.Object) []reconcile.Request {
+	return r.records.recordsMatching(ctx, func(br *dkimmanagerv2.BIMIRecord) bool {
+		return br.Spec.Logo.Name == o.GetName()
+	}, client.InNamespace(o.GetNamespace()))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BIMIRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Publisher = defaultPublisher(r.Publisher, r.Client, r.ReadClient, r.Scheme, r.DNSEndpointGVK)
+	r.records = &domainRecordReconciler[*dkimmanagerv2.BIMIRecord]{
+		Client:     r.Client,
+		namespaces: r.Namespaces,
+		publisher:  r.Publisher,
+		kind:       "BIMIRecord",
+		recordType: "BIMI",
+		newObject:  func() *dkimmanagerv2.BIMIRecord { return &dkimmanagerv2.BIMIRecord{} },
+		newList:    func() client.ObjectList { return &dkimmanagerv2.BIMIRecordList{} },
+		validate: func(br *dkimmanagerv2.BIMIRecord) error {
+			return br.Record().Validate()
+		},
+		check: func(ctx context.Context, br *dkimmanagerv2.BIMIRecord) (ctrl.Result, bool, error) {
+			if ok, err := r.checkDMARCEnforcement(ctx, br); !ok {
+				return ctrl.Result{}, false, err
+			}
+			ok, err := r.checkLogo(ctx, br)
+			return ctrl.Result{}, ok, err
+		},
+	}
+	return r.records.newControllerManagedBy(mgr).
+		Watches(&dkimmanagerv2.DMARCPolicy{}, handler.EnqueueRequestsFromMapFunc(r.bimiRecordsForDMARCPolicy)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.bimiRecordsForLogo), builder.OnlyMetadata).
+		Complete(r)
+}
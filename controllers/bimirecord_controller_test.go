@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dmarc"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+const testBIMILogo = `<svg xmlns="http://www.w3.org/2000/svg" version="1.2" baseProfile="tiny-ps" viewBox="0 0 100 100">
+  <title>atelierhsn</title>
+  <circle cx="50" cy="50" r="40" fill="#336699"/>
+</svg>`
+
+func bimiRecordReason(ctx context.Context, br *dkimmanagerv2.BIMIRecord) (string, error) {
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(br), br); err != nil {
+		return "", err
+	}
+	cond := meta.FindStatusCondition(br.Status.Conditions, dkimmanagerv2.ConditionReady)
+	if cond == nil {
+		return "", fmt.Errorf("BIMIRecord has no Ready condition")
+	}
+	return cond.Reason, nil
+}
+
+var _ = Describe("BIMIRecord controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		err = (&DMARCPolicyReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+		err = (&BIMIRecordReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	shouldCreateLogo := func(namespace, name, logo string) {
+		By("creating logo ConfigMap")
+		err := k8sClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string]string{"logo.svg": logo},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	shouldUpdateLogo := func(namespace, name, logo string) {
+		cm := &corev1.ConfigMap{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, cm)
+		Expect(err).NotTo(HaveOccurred())
+		cm.Data = map[string]string{"logo.svg": logo}
+		err = k8sClient.Update(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("should only publish the BIMI record while the DMARC policy is enforced", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		domain := name + ".bimi.atelierhsn.com"
+		shouldCreateNamespace(ctx, namespace)
+		shouldCreateLogo(namespace, name, testBIMILogo)
+
+		By("creating BIMIRecord")
+		br := &dkimmanagerv2.BIMIRecord{}
+		br.SetName(name)
+		br.SetNamespace(namespace)
+		br.Spec = dkimmanagerv2.BIMIRecordSpec{
+			Domain:       domain,
+			TTL:          3600,
+			LogoURL:      "https://atelierhsn.com/logo.svg",
+			AuthorityURL: "https://atelierhsn.com/vmc.pem",
+			Logo:         dkimmanagerv2.ConfigMapKeyReference{Name: name, Key: "logo.svg"},
+		}
+		err := k8sClient.Create(ctx, br)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() (string, error) {
+			return bimiRecordReason(ctx, br)
+		}).Should(Equal(dkimmanagerv2.ReasonInvalid))
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-bimi", namespace)
+		}).ShouldNot(Succeed())
+
+		By("creating an enforced DMARCPolicy")
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = dkimmanagerv2.DMARCPolicySpec{
+			Domain: domain,
+			Policy: dmarc.PolicyQuarantine,
+		}
+		err = k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name + "-bimi"}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			endpoint := endpoints[0].(map[string]interface{})
+			g.Expect(endpoint).To(HaveKeyWithValue("dnsName", "default._bimi."+domain))
+			g.Expect(endpoint).To(HaveKeyWithValue("recordType", "TXT"))
+			g.Expect(endpoint["targets"]).To(ConsistOf(`"v=BIMI1; l=https://atelierhsn.com/logo.svg; a=https://atelierhsn.com/vmc.pem;"`))
+		}).Should(Succeed())
+		Expect(bimiRecordReason(ctx, br)).To(Equal(dkimmanagerv2.ReasonSucceeded))
+
+		By("relaxing the DMARCPolicy")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(dp), dp)
+		Expect(err).NotTo(HaveOccurred())
+		dp.Spec.Policy = dmarc.PolicyNone
+		err = k8sClient.Update(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-bimi", namespace)
+		}).ShouldNot(Succeed())
+		Expect(bimiRecordReason(ctx, br)).To(Equal(dkimmanagerv2.ReasonInvalid))
+	})
+
+	It("should not publish BIMI records with an invalid logo", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		domain := name + ".bimi.atelierhsn.com"
+		shouldCreateNamespace(ctx, namespace)
+		shouldCreateLogo(namespace, name, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1"><title>x</title></svg>`)
+
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = dkimmanagerv2.DMARCPolicySpec{
+			Domain: domain,
+			Policy: dmarc.PolicyReject,
+		}
+		err := k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		br := &dkimmanagerv2.BIMIRecord{}
+		br.SetName(name)
+		br.SetNamespace(namespace)
+		br.Spec = dkimmanagerv2.BIMIRecordSpec{
+			Domain:  domain,
+			LogoURL: "https://atelierhsn.com/logo.svg",
+			Logo:    dkimmanagerv2.ConfigMapKeyReference{Name: name, Key: "logo.svg"},
+		}
+		err = k8sClient.Create(ctx, br)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			reason, err := bimiRecordReason(ctx, br)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+			cond := meta.FindStatusCondition(br.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond.Message).To(HavePrefix("invalid logo"))
+		}).Should(Succeed())
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-bimi", namespace)
+		}).ShouldNot(Succeed())
+
+		By("fixing the logo")
+		shouldUpdateLogo(namespace, name, testBIMILogo)
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-bimi", namespace)
+		}).Should(Succeed())
+		Expect(bimiRecordReason(ctx, br)).To(Equal(dkimmanagerv2.ReasonSucceeded))
+
+		By("breaking the logo again")
+		shouldUpdateLogo(namespace, name, `<svg xmlns="http://www.w3.org/2000/svg"/>`)
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-bimi", namespace)
+		}).ShouldNot(Succeed())
+		Expect(bimiRecordReason(ctx, br)).To(Equal(dkimmanagerv2.ReasonInvalid))
+	})
+})
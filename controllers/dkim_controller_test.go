@@ -3,13 +3,18 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"golang.org/x/net/dns/dnsmessage"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/utils/ptr"
@@ -21,7 +26,10 @@ import (
 	dkimmanagerv1 "github.com/hsn723/dkim-manager/api/v1"
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
 	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/dnsprobe"
 	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
 )
 
 func getDNSEndpoint(ctx context.Context, name, namespace string) error {
@@ -100,7 +108,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -131,6 +139,241 @@ var _ = Describe("DKIMKey controller", func() {
 		Expect(dk.IsReady()).To(BeTrue())
 	})
 
+	It("should publish RSA and Ed25519 keys under sibling selectors", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName:      name,
+			Selector:        name,
+			Domain:          "atelierhsn.com",
+			TTL:             3600,
+			KeyLength:       dkim.KeyLength2048,
+			KeyType:         dkim.KeyTypeRSA,
+			ED25519Selector: name + "-ed25519",
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			s := &corev1.Secret{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, s)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(s.Data).To(HaveKey(dkim.PrivateKeyFilename(name, "atelierhsn.com")))
+			g.Expect(s.Data).To(HaveKey(dkim.PrivateKeyFilename(name+"-ed25519", "atelierhsn.com")))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(2))
+			var names []string
+			for _, e := range endpoints {
+				names = append(names, e.(map[string]interface{})["dnsName"].(string))
+			}
+			g.Expect(names).To(ConsistOf(
+				dkim.RecordName(name, "atelierhsn.com"),
+				dkim.RecordName(name+"-ed25519", "atelierhsn.com"),
+			))
+		}).Should(Succeed())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			return err == nil && dk.IsReady()
+		}).Should(BeTrue())
+	})
+
+	It("should copy DNSEndpoint options onto the DNSEndpoint", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			DNSEndpoint: &dkimmanagerv2.DNSEndpointOptions{
+				Labels:        map[string]string{"dns": "internal"},
+				Annotations:   map[string]string{"external-dns.alpha.kubernetes.io/class": "internal"},
+				SetIdentifier: "primary",
+				ProviderSpecific: []dkimmanagerv2.ProviderSpecificProperty{
+					{Name: "aws/weight", Value: "100"},
+				},
+			},
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(de.GetLabels()).To(HaveKeyWithValue("dns", "internal"))
+			g.Expect(de.GetAnnotations()).To(HaveKeyWithValue("external-dns.alpha.kubernetes.io/class", "internal"))
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			endpoint := endpoints[0].(map[string]interface{})
+			g.Expect(endpoint).To(HaveKeyWithValue("setIdentifier", "primary"))
+			g.Expect(endpoint["providerSpecific"]).To(ConsistOf(map[string]interface{}{"name": "aws/weight", "value": "100"}))
+		}).Should(Succeed())
+	})
+
+	It("should publish DKIM record tags", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			Tags: &dkimmanagerv2.DKIMRecordTags{
+				Testing:      true,
+				ServiceTypes: []string{"email"},
+			},
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		targets := func(g Gomega) []interface{} {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			return endpoints[0].(map[string]interface{})["targets"].([]interface{})
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(targets(g)).To(ConsistOf(HavePrefix("\"v=DKIM1; h=sha256; k=rsa; s=email; t=y;\"")))
+		}).Should(Succeed())
+
+		By("leaving testing mode")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk); err != nil {
+				return err
+			}
+			dk.Spec.Tags.Testing = false
+			return k8sClient.Update(ctx, dk)
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(targets(g)).To(ConsistOf(HavePrefix("\"v=DKIM1; h=sha256; k=rsa; s=email;\"")))
+		}).Should(Succeed())
+	})
+
+	It("should publish an empty public key and delete the private key when revoked", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			return getSecret(ctx, name, namespace)
+		}).Should(Succeed())
+
+		By("revoking the key")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk); err != nil {
+				return err
+			}
+			dk.Spec.Revoked = true
+			return k8sClient.Update(ctx, dk)
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			g.Expect(endpoints[0].(map[string]interface{})["targets"]).To(ConsistOf("\"v=DKIM1; h=sha256; k=rsa;\" \"p=\""))
+		}).Should(Succeed())
+		Eventually(func() error {
+			return getSecret(ctx, name, namespace)
+		}).ShouldNot(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonRevoked))
+		}).Should(Succeed())
+	})
+
+	It("should publish delegated DKIM records behind a CNAME record", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+			Delegation: &dkimmanagerv2.DKIMDelegation{Zone: "dkim.example.net"},
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		txtName := dkim.DelegatedRecordName(name, "atelierhsn.com", "dkim.example.net")
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(2))
+			g.Expect(endpoints[0]).To(HaveKeyWithValue("dnsName", txtName))
+			g.Expect(endpoints[0]).To(HaveKeyWithValue("recordType", "TXT"))
+			g.Expect(endpoints[1]).To(HaveKeyWithValue("dnsName", dkim.RecordName(name, "atelierhsn.com")))
+			g.Expect(endpoints[1]).To(HaveKeyWithValue("recordType", "CNAME"))
+			g.Expect(endpoints[1]).To(HaveKeyWithValue("targets", ConsistOf(txtName)))
+		}).Should(Succeed())
+	})
+
 	It("should allow existing DNSEndpoint with no public keys", func() {
 		name := uuid.NewString()
 		namespace := uuid.NewString()
@@ -158,7 +401,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -209,7 +452,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -296,7 +539,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -339,7 +582,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyType:    dkim.KeyTypeED25519,
@@ -399,7 +642,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: dk1Name,
-			Selector:   "selector1",
+			Selector:   dk1Name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyType:    dkim.KeyTypeED25519,
@@ -410,7 +653,7 @@ var _ = Describe("DKIMKey controller", func() {
 		dk2.SetNamespace(namespace)
 		dk2.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: dk2Name,
-			Selector:   "selector2",
+			Selector:   dk2Name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyType:    dkim.KeyTypeRSA,
@@ -461,6 +704,327 @@ var _ = Describe("DKIMKey controller", func() {
 			return getDNSEndpoint(ctx, dk2Name, namespace)
 		}).Should(Succeed())
 	})
+
+	It("should keep the Secret and DNSEndpoint of a deleted DKIMKey with the Retain deletion policy", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName:     name,
+			Selector:       name,
+			Domain:         "atelierhsn.com",
+			TTL:            3600,
+			KeyType:        dkim.KeyTypeED25519,
+			DeletionPolicy: dkimmanagerv2.DeletionPolicyRetain,
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		targets := func(g Gomega) []interface{} {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			return endpoints[0].(map[string]interface{})["targets"].([]interface{})
+		}
+		var published []interface{}
+		Eventually(func(g Gomega) {
+			g.Expect(getSecret(ctx, name, namespace)).To(Succeed())
+			published = targets(g)
+		}).Should(Succeed())
+
+		By("deleting DKIMKey")
+		err = k8sClient.Delete(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk))
+		}).Should(BeTrue())
+
+		s := &corev1.Secret{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, s)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.OwnerReferences).To(BeEmpty())
+		Expect(s.Annotations).To(HaveKeyWithValue(dkimmanagerv2.AdoptSecretAnnotation, "true"))
+		de := externaldns.DNSEndpoint()
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(de.GetOwnerReferences()).To(BeEmpty())
+		Expect(de.GetLabels()).To(HaveKeyWithValue(publisher.ReleasedLabel, dkimmanagerv2.DKIMKeyKind))
+
+		By("recreating DKIMKey")
+		dk = &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyType:    dkim.KeyTypeED25519,
+		}
+		err = k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+			g.Expect(dk.IsReady()).To(BeTrue())
+			g.Expect(targets(g)).To(Equal(published), "the retained key must be adopted")
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, s)).To(Succeed())
+			g.Expect(v1.IsControlledBy(s, dk)).To(BeTrue(), "the adopted Secret must be owned again")
+			g.Expect(s.Annotations).NotTo(HaveKey(dkimmanagerv2.AdoptSecretAnnotation))
+		}).Should(Succeed())
+
+		By("deleting DKIMKey with the Delete deletion policy")
+		err = k8sClient.Delete(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(getSecret(ctx, name, namespace))
+		}).Should(BeTrue())
+	})
+
+	It("should not adopt Secrets which do not opt in or are controlled by another resource", func() {
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		key, _, err := dkim.GenED25519()
+		Expect(err).NotTo(HaveOccurred())
+		cm := &corev1.ConfigMap{}
+		cm.SetName(uuid.NewString())
+		cm.SetNamespace(namespace)
+		err = k8sClient.Create(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, annotated := range []bool{false, true} {
+			name := uuid.NewString()
+			By(fmt.Sprintf("creating Secret with adopt annotation %v", annotated))
+			s := &corev1.Secret{}
+			s.SetName(name)
+			s.SetNamespace(namespace)
+			s.Data = map[string][]byte{dkim.PrivateKeyFilename(name, "atelierhsn.com"): key}
+			if annotated {
+				s.Annotations = map[string]string{dkimmanagerv2.AdoptSecretAnnotation: "true"}
+				s.OwnerReferences = []v1.OwnerReference{*v1.NewControllerRef(cm, corev1.SchemeGroupVersion.WithKind("ConfigMap"))}
+			}
+			err := k8sClient.Create(ctx, s)
+			Expect(err).NotTo(HaveOccurred())
+
+			dk := &dkimmanagerv2.DKIMKey{}
+			dk.SetName(name)
+			dk.SetNamespace(namespace)
+			dk.Spec = dkimmanagerv2.DKIMKeySpec{
+				SecretName: name,
+				Selector:   name,
+				Domain:     "atelierhsn.com",
+				TTL:        3600,
+				KeyType:    dkim.KeyTypeED25519,
+			}
+			err = k8sClient.Create(ctx, dk)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+				cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+				g.Expect(cond).NotTo(BeNil())
+				g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+			}).Should(Succeed())
+			Consistently(func() error {
+				return getDNSEndpoint(ctx, name, namespace)
+			}).ShouldNot(Succeed())
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(s), s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v1.IsControlledBy(s, dk)).To(BeFalse())
+		}
+	})
+
+	It("should revoke the DKIM records of a deleted DKIMKey with the Revoke deletion policy", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName:     name,
+			Selector:       name,
+			Domain:         "atelierhsn.com",
+			TTL:            3600,
+			KeyLength:      dkim.KeyLength2048,
+			KeyType:        dkim.KeyTypeRSA,
+			DeletionPolicy: dkimmanagerv2.DeletionPolicyRevoke,
+		}
+
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			return getSecret(ctx, name, namespace)
+		}).Should(Succeed())
+
+		By("deleting DKIMKey")
+		err = k8sClient.Delete(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk))
+		}).Should(BeTrue())
+
+		Expect(getSecret(ctx, name, namespace)).NotTo(Succeed())
+		de := externaldns.DNSEndpoint()
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(de.GetOwnerReferences()).To(BeEmpty())
+		Expect(de.GetLabels()).To(HaveKeyWithValue(publisher.ReleasedLabel, dkimmanagerv2.DKIMKeyKind))
+		endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints).To(HaveLen(1))
+		Expect(endpoints[0].(map[string]interface{})["targets"]).To(ConsistOf("\"v=DKIM1; h=sha256; k=rsa;\" \"p=\""))
+	})
+
+	It("should not reconcile DKIMKeys for domains not allowed by policy", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating DKIMDomainPolicy")
+		p := &dkimmanagerv2.DKIMDomainPolicy{}
+		p.SetName(uuid.NewString())
+		p.Spec = dkimmanagerv2.DKIMDomainPolicySpec{
+			Namespaces: []string{namespace},
+			Domains:    []string{"example.com"},
+		}
+		err := k8sClient.Create(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, p)).To(Succeed())
+		})
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyType:    dkim.KeyTypeED25519,
+		}
+		err = k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			if err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+			if cond == nil || cond.Reason != dkimmanagerv2.ReasonInvalid {
+				return fmt.Errorf("DKIMKey is not invalid")
+			}
+			return nil
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getSecret(ctx, name, namespace)
+		}).ShouldNot(Succeed())
+
+		By("allowing the domain")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(p), p)
+		Expect(err).NotTo(HaveOccurred())
+		p.Spec.Domains = append(p.Spec.Domains, "atelierhsn.com")
+		err = k8sClient.Update(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getSecret(ctx, name, namespace)
+		}).Should(Succeed())
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name, namespace)
+		}).Should(Succeed())
+
+		By("disallowing the domain again")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(p), p)
+		Expect(err).NotTo(HaveOccurred())
+		p.Spec.Domains = []string{"example.com"}
+		err = k8sClient.Update(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			return apierrors.IsNotFound(getDNSEndpoint(ctx, name, namespace))
+		}).Should(BeTrue(), "the records must be withdrawn")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+		}).Should(Succeed())
+	})
+
+	It("should mark DKIMKeys claiming an already claimed record as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		otherNamespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		shouldCreateNamespace(ctx, otherNamespace)
+
+		spec := dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyType:    dkim.KeyTypeED25519,
+		}
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = spec
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name, namespace)
+		}).Should(Succeed())
+
+		By("creating conflicting DKIMKey")
+		dk2 := &dkimmanagerv2.DKIMKey{}
+		dk2.SetName(name)
+		dk2.SetNamespace(otherNamespace)
+		dk2.Spec = spec
+		err = k8sClient.Create(ctx, dk2)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk2), dk2)
+			if err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(dk2.Status.Conditions, dkimmanagerv2.ConditionReady)
+			if cond == nil || cond.Reason != dkimmanagerv2.ReasonInvalid {
+				return fmt.Errorf("DKIMKey is not invalid")
+			}
+			return nil
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name, otherNamespace)
+		}).ShouldNot(Succeed())
+
+		By("deleting the original DKIMKey")
+		err = k8sClient.Delete(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name, otherNamespace)
+		}).Should(Succeed())
+	})
 })
 
 var _ = Describe("DKIMKey controller namespaced", func() {
@@ -518,7 +1082,7 @@ var _ = Describe("DKIMKey controller namespaced", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -563,7 +1127,7 @@ var _ = Describe("DKIMKey controller namespaced", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -587,6 +1151,336 @@ var _ = Describe("DKIMKey controller namespaced", func() {
 	})
 })
 
+var _ = Describe("DKIMKey key policy", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	observedNamespace := uuid.NewString()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler := &DKIMKeyReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Log:        ctrl.Log.WithName("controllers").WithName("DKIMKey"),
+			Namespaces: []string{observedNamespace},
+			ReadClient: mgr.GetAPIReader(),
+			KeyPolicy: policy.KeyPolicy{
+				MinRSAKeyLength: dkim.KeyLength2048,
+				MaxKeyAge:       time.Nanosecond,
+			},
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Create(ctx, &corev1.Namespace{
+			ObjectMeta: v1.ObjectMeta{Name: observedNamespace},
+		})
+		err = client.IgnoreAlreadyExists(err)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should not generate keys violating the key policy", func() {
+		name := uuid.NewString()
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(observedNamespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength1024,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			g.Expect(err).NotTo(HaveOccurred())
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionPolicyCompliant)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonKeyPolicyViolation))
+			cond = meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getSecret(ctx, name, observedNamespace)
+		}).ShouldNot(Succeed())
+	})
+
+	It("should report keys older than the maximum key age", func() {
+		name := uuid.NewString()
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(observedNamespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(dk.IsReady()).To(BeTrue())
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionPolicyCompliant)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Status).To(Equal(v1.ConditionFalse))
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonRotationRequired))
+		}).Should(Succeed())
+	})
+
+	It("should republish the records when the spec of a ready key changes", func() {
+		name := uuid.NewString()
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(observedNamespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		recordTTL := func(g Gomega) int64 {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: observedNamespace, Name: name}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			ttl, _, err := unstructured.NestedInt64(endpoints[0].(map[string]interface{}), "recordTTL")
+			g.Expect(err).NotTo(HaveOccurred())
+			return ttl
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+			g.Expect(dk.IsReady()).To(BeTrue())
+			g.Expect(meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionPolicyCompliant)).NotTo(BeNil())
+			g.Expect(recordTTL(g)).To(Equal(int64(3600)))
+		}).Should(Succeed())
+
+		By("changing the TTL")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk); err != nil {
+				return err
+			}
+			dk.Spec.TTL = 600
+			return k8sClient.Update(ctx, dk)
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(recordTTL(g)).To(Equal(int64(600)))
+		}).Should(Succeed())
+	})
+})
+
+// serveDNSEndpoints starts a UDP nameserver standing in for external-dns, answering TXT queries
+// from the DNSEndpoints in the given namespace, and returns its address.
+func serveDNSEndpoints(ctx context.Context, namespace string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(conn.Close)
+
+	go func() {
+		defer GinkgoRecover()
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp, err := answerFromDNSEndpoints(ctx, namespace, buf[:n])
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func answerFromDNSEndpoints(ctx context.Context, namespace string, query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+	var answers [][]string
+	del := externaldns.DNSEndpointList()
+	if err := k8sClient.List(ctx, del, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for _, de := range del.Items {
+		endpoints, _, _ := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+		for _, e := range endpoints {
+			ep := e.(map[string]interface{})
+			if ep["dnsName"] != strings.TrimSuffix(q.Name.String(), ".") {
+				continue
+			}
+			targets, _, _ := unstructured.NestedStringSlice(ep, "targets")
+			for _, t := range targets {
+				answers = append(answers, dkim.SplitTXTValue(t))
+			}
+		}
+	}
+	h.Response = true
+	h.Authoritative = true
+	if len(answers) == 0 {
+		h.RCode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, h)
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, txt := range answers {
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		if err := b.TXTResource(rh, dnsmessage.TXTResource{TXT: txt}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+var _ = Describe("DKIMKey DNS verification", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	observedNamespace := uuid.NewString()
+	unpublishedNamespace := uuid.NewString()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler := &DKIMKeyReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Log:        ctrl.Log.WithName("controllers").WithName("DKIMKey"),
+			Namespaces: []string{observedNamespace, unpublishedNamespace},
+			ReadClient: mgr.GetAPIReader(),
+			DNSProber:  dnsprobe.NewProber([]string{serveDNSEndpoints(ctx, observedNamespace)}, time.Second),
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, ns := range []string{observedNamespace, unpublishedNamespace} {
+			err = k8sClient.Create(ctx, &corev1.Namespace{
+				ObjectMeta: v1.ObjectMeta{Name: ns},
+			})
+			Expect(client.IgnoreAlreadyExists(err)).NotTo(HaveOccurred())
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	shouldReportDNSPublished := func(namespace string, status v1.ConditionStatus, reason string) {
+		name := uuid.NewString()
+
+		By("creating DKIMKey")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(dk.IsReady()).To(BeTrue())
+			cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionDNSPublished)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Status).To(Equal(status))
+			g.Expect(cond.Reason).To(Equal(reason))
+		}).WithTimeout(30 * time.Second).Should(Succeed())
+	}
+
+	It("should report records served by the nameservers as published", func() {
+		shouldReportDNSPublished(observedNamespace, v1.ConditionTrue, dkimmanagerv2.ReasonPublished)
+	})
+
+	It("should report records missing from the nameservers as not published", func() {
+		shouldReportDNSPublished(unpublishedNamespace, v1.ConditionFalse, dkimmanagerv2.ReasonNotPublished)
+	})
+})
+
 var _ = Describe("DKIMKey v1/v2 conversion", func() {
 	ctx := context.Background()
 	var stopFunc func()
@@ -637,7 +1531,7 @@ var _ = Describe("DKIMKey v1/v2 conversion", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv1.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
@@ -694,7 +1588,7 @@ var _ = Describe("DKIMKey v1/v2 conversion", func() {
 		dk.SetNamespace(namespace)
 		dk.Spec = dkimmanagerv2.DKIMKeySpec{
 			SecretName: name,
-			Selector:   "selector1",
+			Selector:   name,
 			Domain:     "atelierhsn.com",
 			TTL:        3600,
 			KeyLength:  dkim.KeyLength2048,
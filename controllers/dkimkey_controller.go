@@ -18,8 +18,10 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -31,12 +33,17 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
 	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/dnsprobe"
 	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
 )
 
 const (
@@ -44,6 +51,9 @@ const (
 	apiGroup      = "dkim-manager.atelierhsn.com"
 
 	fieldOwner client.FieldOwner = "dkim-manager"
+
+	dnsProbeMinBackoff = 10 * time.Second
+	dnsProbeMaxBackoff = 10 * time.Minute
 )
 
 // DKIMKeyReconciler reconciles a DKIMKey object.
@@ -52,6 +62,14 @@ type DKIMKeyReconciler struct {
 	Log        logr.Logger
 	Scheme     *runtime.Scheme
 	Namespaces []string
+	KeyPolicy  policy.KeyPolicy
+	// DNSProber verifies that DKIM records are served by nameservers. Nil disables verification.
+	DNSProber *dnsprobe.Prober
+	// Publisher publishes the DKIM records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
 	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
 	ReadClient client.Reader
 }
@@ -59,8 +77,11 @@ type DKIMKeyReconciler struct {
 //+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeys/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeys/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
 //+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -85,6 +106,13 @@ func (r *DKIMKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	if dk.ObjectMeta.DeletionTimestamp.IsZero() {
+		if dk.Spec.Selector == "" || dk.Spec.SecretName == "" {
+			logger.Info("selector or secret name is missing, ignoring")
+			return ctrl.Result{}, r.markInvalid(ctx, dk, "selector and secretName must be set explicitly or through the mutating webhook templates")
+		}
+		if ok, err := r.checkDomainPolicy(ctx, dk); !ok {
+			return ctrl.Result{}, err
+		}
 		if !controllerutil.ContainsFinalizer(dk, finalizerName) {
 			controllerutil.AddFinalizer(dk, finalizerName)
 			return ctrl.Result{}, r.Update(ctx, dk)
@@ -94,16 +122,52 @@ func (r *DKIMKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, r.finalize(ctx, dk)
 	}
 
+	if ok, err := r.checkRecordConflicts(ctx, dk); !ok {
+		return ctrl.Result{}, err
+	}
+
+	if dk.Spec.Revoked {
+		return ctrl.Result{}, r.revoke(ctx, dk)
+	}
+
+	requeueAfter, changed, err := r.evaluateKeyPolicy(ctx, dk)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if dk.IsReady() && dk.Status.ObservedGeneration == dk.Generation {
-		return ctrl.Result{}, nil
+		dnsRequeueAfter, dnsChanged, err := r.reverifyDNSRecords(ctx, dk)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		requeueAfter = shortestRequeue(requeueAfter, dnsRequeueAfter)
+		if changed || dnsChanged {
+			return ctrl.Result{RequeueAfter: requeueAfter}, r.Status().Update(ctx, dk)
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
 	}
 
-	return r.reconcile(ctx, dk)
+	res, err := r.reconcile(ctx, dk)
+	if err == nil {
+		res.RequeueAfter = shortestRequeue(res.RequeueAfter, requeueAfter)
+	}
+	return res, err
+}
+
+// shortestRequeue returns the shortest non-zero delay.
+func shortestRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
 }
 
-// setCondition updates the status condition on the DKIMKey.
+// setCondition updates the status condition on the DKIMKey. Only the Ready condition marks the
+// generation as observed, as the other conditions are set before the spec is reconciled.
 func (r *DKIMKeyReconciler) setCondition(dk *dkimmanagerv2.DKIMKey, condType string, status v1.ConditionStatus, reason, message string) {
-	dk.Status.ObservedGeneration = dk.Generation
+	if condType == dkimmanagerv2.ConditionReady {
+		dk.Status.ObservedGeneration = dk.Generation
+	}
 	meta.SetStatusCondition(&dk.Status.Conditions, v1.Condition{
 		Type:               condType,
 		Status:             status,
@@ -124,6 +188,154 @@ func (r *DKIMKeyReconciler) hasCondition(dk *dkimmanagerv2.DKIMKey, condType str
 	return false
 }
 
+// checkDomainPolicy marks the DKIMKey as invalid if no DKIMDomainPolicy allows its domain, withdrawing
+// its records if they were published. It returns false if reconciliation should not proceed.
+func (r *DKIMKeyReconciler) checkDomainPolicy(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (bool, error) {
+	err := policy.CheckDomain(ctx, r.Client, dk.Namespace, dk.Spec.Domain)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, policy.ErrDomainNotAllowed) {
+		return false, err
+	}
+	log.FromContext(ctx).Info("domain is not allowed by policy, ignoring", "domain", dk.Spec.Domain)
+	if controllerutil.ContainsFinalizer(dk, finalizerName) {
+		if err := r.Publisher.Unpublish(ctx, dk, dk.RecordNames()); err != nil {
+			return false, err
+		}
+	}
+	return false, r.markInvalid(ctx, dk, err.Error())
+}
+
+// checkRecordConflicts marks the DKIMKey as invalid if another DKIMKey claimed the same record name first.
+// It returns false if reconciliation should not proceed.
+func (r *DKIMKeyReconciler) checkRecordConflicts(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (bool, error) {
+	conflicts, err := policy.FindRecordConflicts(ctx, r.Client, client.ObjectKeyFromObject(dk), dk.RecordNames())
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conflicts {
+		if !policy.ClaimedBefore(&c, dk) {
+			continue
+		}
+		recordName := policy.SharedRecordName(dk, &c)
+		log.FromContext(ctx).Info("record is already claimed by another DKIMKey, ignoring", "record", recordName, "owner", client.ObjectKeyFromObject(&c))
+		return false, r.markInvalid(ctx, dk, fmt.Sprintf("record %s is already claimed by DKIMKey %s", recordName, client.ObjectKeyFromObject(&c)))
+	}
+	return true, nil
+}
+
+// evaluateKeyPolicy sets the PolicyCompliant condition according to the key policy, and removes it
+// if no requirement applies to keys of the purpose of the DKIMKey. It returns
+// the delay after which compliance should be evaluated again, and whether the condition changed.
+func (r *DKIMKeyReconciler) evaluateKeyPolicy(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (time.Duration, bool, error) {
+	keyPolicy := r.KeyPolicy.ForPurpose(dk.Spec.Purpose)
+	if keyPolicy.IsEmpty() {
+		return 0, meta.RemoveStatusCondition(&dk.Status.Conditions, dkimmanagerv2.ConditionPolicyCompliant), nil
+	}
+	status, reason, message := v1.ConditionTrue, dkimmanagerv2.ReasonCompliant, "DKIM key complies with the key policy"
+	var requeueAfter time.Duration
+	if err := keyPolicy.CheckDKIMKey(dk); err != nil {
+		status, reason, message = v1.ConditionFalse, dkimmanagerv2.ReasonKeyPolicyViolation, err.Error()
+	} else {
+		created, err := r.keyCreationTime(ctx, dk)
+		if err != nil {
+			return 0, false, err
+		}
+		if !created.IsZero() {
+			now := time.Now()
+			if err := keyPolicy.CheckAge(created, now); err != nil {
+				status, reason, message = v1.ConditionFalse, dkimmanagerv2.ReasonRotationRequired, err.Error()
+			} else if deadline := keyPolicy.RotationDeadline(created); !deadline.IsZero() {
+				requeueAfter = deadline.Sub(now)
+			}
+		}
+	}
+	return requeueAfter, r.updateCondition(dk, dkimmanagerv2.ConditionPolicyCompliant, status, reason, message), nil
+}
+
+// updateCondition sets the status condition on the DKIMKey unless it is already set, and reports whether it changed.
+func (r *DKIMKeyReconciler) updateCondition(dk *dkimmanagerv2.DKIMKey, condType string, status v1.ConditionStatus, reason, message string) bool {
+	cond := meta.FindStatusCondition(dk.Status.Conditions, condType)
+	if cond != nil && cond.Status == status && cond.Reason == reason && cond.Message == message {
+		return false
+	}
+	r.setCondition(dk, condType, status, reason, message)
+	return true
+}
+
+// reverifyDNSRecords verifies the DKIM records of a ready DKIMKey until they are found to be published.
+func (r *DKIMKeyReconciler) reverifyDNSRecords(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (time.Duration, bool, error) {
+	if r.DNSProber == nil || meta.IsStatusConditionTrue(dk.Status.Conditions, dkimmanagerv2.ConditionDNSPublished) {
+		return 0, false, nil
+	}
+	records, err := r.checkForExistingKeys(ctx, dk)
+	if err != nil {
+		return 0, false, err
+	}
+	requeueAfter, changed := r.verifyDNSRecords(ctx, dk, records)
+	return requeueAfter, changed, nil
+}
+
+// verifyDNSRecords sets the DNSPublished condition according to the records served by the nameservers.
+// It returns the delay after which publication should be checked again, and whether the condition changed.
+func (r *DKIMKeyReconciler) verifyDNSRecords(ctx context.Context, dk *dkimmanagerv2.DKIMKey, records []publisher.Record) (time.Duration, bool) {
+	if r.DNSProber == nil {
+		return 0, false
+	}
+	for _, rec := range records {
+		// Only TXT records are probed, as the CNAME records of delegated keys may be managed by the owners of the domains.
+		if rec.RecordType() != publisher.RecordTypeTXT {
+			continue
+		}
+		for _, target := range rec.Targets {
+			if err := r.DNSProber.Verify(ctx, rec.Name, target); err != nil {
+				log.FromContext(ctx).Info("DKIM record is not published yet", "record", rec.Name, "reason", err.Error())
+				changed := r.updateCondition(dk, dkimmanagerv2.ConditionDNSPublished, v1.ConditionFalse, dkimmanagerv2.ReasonNotPublished, err.Error())
+				return dnsProbeBackoff(dk), changed
+			}
+		}
+	}
+	return 0, r.updateCondition(dk, dkimmanagerv2.ConditionDNSPublished, v1.ConditionTrue, dkimmanagerv2.ReasonPublished, "DKIM records are served by all nameservers")
+}
+
+// dnsProbeBackoff returns the delay before probing the nameservers again. The delay grows with the time
+// elapsed since the records were first found missing, so that no retry counter needs to be kept.
+func dnsProbeBackoff(dk *dkimmanagerv2.DKIMKey) time.Duration {
+	delay := dnsProbeMinBackoff
+	if cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionDNSPublished); cond != nil {
+		delay = max(delay, time.Since(cond.LastTransitionTime.Time))
+	}
+	return min(delay, dnsProbeMaxBackoff)
+}
+
+// keyCreationTime returns the creation time of the Secret holding the private key,
+// or the zero time if it does not exist yet.
+func (r *DKIMKeyReconciler) keyCreationTime(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (time.Time, error) {
+	s := &corev1.Secret{}
+	sKey := client.ObjectKey{
+		Namespace: dk.Namespace,
+		Name:      dk.Spec.SecretName,
+	}
+	if err := r.ReadClient.Get(ctx, sKey, s); err != nil {
+		if apierrors.IsNotFound(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get Secret: %v", err)
+	}
+	return s.CreationTimestamp.Time, nil
+}
+
+// markInvalid sets the Ready condition to Invalid with the given message, unless it is already set.
+func (r *DKIMKeyReconciler) markInvalid(ctx context.Context, dk *dkimmanagerv2.DKIMKey, message string) error {
+	cond := meta.FindStatusCondition(dk.Status.Conditions, dkimmanagerv2.ConditionReady)
+	if cond != nil && cond.Reason == dkimmanagerv2.ReasonInvalid && cond.Message == message && dk.Status.ObservedGeneration == dk.Generation {
+		return nil
+	}
+	r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonInvalid, message)
+	return r.Status().Update(ctx, dk)
+}
+
 func (r *DKIMKeyReconciler) isOwnedByDKIMKey(dk *dkimmanagerv2.DKIMKey, ownerRefs []v1.OwnerReference) bool {
 	for _, owner := range ownerRefs {
 		if owner.Kind == dkimmanagerv2.DKIMKeyKind && owner.Name == dk.Name {
@@ -144,19 +356,64 @@ func (r *DKIMKeyReconciler) finalize(ctx context.Context, dk *dkimmanagerv2.DKIM
 		return nil
 	}
 	logger := log.FromContext(ctx)
-	del := externaldns.DNSEndpointList()
+	switch dk.Spec.DeletionPolicy {
+	case dkimmanagerv2.DeletionPolicyRetain:
+		if err := r.Publisher.Release(ctx, dk); err != nil {
+			return err
+		}
+		if err := r.releasePrivateKeys(ctx, dk); err != nil {
+			return err
+		}
+	case dkimmanagerv2.DeletionPolicyRevoke:
+		var records []publisher.Record
+		for _, k := range dk.Keys() {
+			records = append(records, revokedKeyRecords(dk, k)...)
+		}
+		// On conflict, the records belong to another resource and there is nothing to revoke.
+		if err := r.reconcileDKIMRecord(ctx, dk, records); err != nil && !errors.Is(err, publisher.ErrRecordSetConflict) {
+			return err
+		}
+		if err := r.deletePrivateKeys(ctx, dk); err != nil {
+			return err
+		}
+		if err := r.Publisher.Release(ctx, dk); err != nil {
+			return err
+		}
+	default:
+		if err := r.Publisher.Unpublish(ctx, dk, dk.RecordNames()); err != nil {
+			return err
+		}
+		if err := r.deletePrivateKeys(ctx, dk); err != nil {
+			return err
+		}
+	}
+	logger.Info("done finalizing", "deletionPolicy", dk.Spec.DeletionPolicy)
+	controllerutil.RemoveFinalizer(dk, finalizerName)
+	return r.Update(ctx, dk)
+}
+
+// deletePrivateKeys deletes the Secrets owned by the DKIMKey.
+func (r *DKIMKeyReconciler) deletePrivateKeys(ctx context.Context, dk *dkimmanagerv2.DKIMKey) error {
 	lo := &client.ListOptions{Namespace: dk.Namespace}
-	if err := r.ReadClient.List(ctx, del, lo); client.IgnoreNotFound(err) != nil {
+	ss := &corev1.SecretList{}
+	if err := r.ReadClient.List(ctx, ss, lo); err != nil {
 		return err
 	}
-	for _, de := range del.Items {
-		if !r.isOwnedByDKIMKey(dk, de.GetOwnerReferences()) {
+	for _, s := range ss.Items {
+		if !r.isOwnedByDKIMKey(dk, s.GetOwnerReferences()) {
 			continue
 		}
-		if err := r.Delete(ctx, &de); err != nil {
+		if err := r.Delete(ctx, &s); client.IgnoreNotFound(err) != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+// releasePrivateKeys removes the owner references to the DKIMKey from the Secrets it owns, so that
+// they are kept, and annotates them so that they are adopted again if the DKIMKey is recreated.
+func (r *DKIMKeyReconciler) releasePrivateKeys(ctx context.Context, dk *dkimmanagerv2.DKIMKey) error {
+	lo := &client.ListOptions{Namespace: dk.Namespace}
 	ss := &corev1.SecretList{}
 	if err := r.ReadClient.List(ctx, ss, lo); err != nil {
 		return err
@@ -165,57 +422,97 @@ func (r *DKIMKeyReconciler) finalize(ctx context.Context, dk *dkimmanagerv2.DKIM
 		if !r.isOwnedByDKIMKey(dk, s.GetOwnerReferences()) {
 			continue
 		}
-		if err := r.Delete(ctx, &s); err != nil {
+		patch := client.MergeFrom(s.DeepCopy())
+		s.OwnerReferences = slices.DeleteFunc(s.OwnerReferences, func(owner v1.OwnerReference) bool {
+			return r.isOwnedByDKIMKey(dk, []v1.OwnerReference{owner})
+		})
+		if s.Annotations == nil {
+			s.Annotations = map[string]string{}
+		}
+		s.Annotations[dkimmanagerv2.AdoptSecretAnnotation] = "true"
+		if err := r.Patch(ctx, &s, patch); client.IgnoreNotFound(err) != nil {
 			return err
 		}
 	}
-	logger.Info("done finalizing")
-	controllerutil.RemoveFinalizer(dk, finalizerName)
-	return r.Update(ctx, dk)
+	return nil
+}
+
+// revoke publishes the DKIM records with an empty public key, then deletes the private keys.
+// The records stay published until the DKIMKey is deleted.
+func (r *DKIMKeyReconciler) revoke(ctx context.Context, dk *dkimmanagerv2.DKIMKey) error {
+	if r.hasCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonRevoked) && dk.Status.ObservedGeneration == dk.Generation {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+	logger.Info("revoking DKIM key")
+	var records []publisher.Record
+	for _, k := range dk.Keys() {
+		records = append(records, revokedKeyRecords(dk, k)...)
+	}
+	if err := r.reconcileDKIMRecord(ctx, dk, records); err != nil {
+		logger.Error(err, "failed to publish revoked DKIM records")
+		r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to publish revoked DKIM records: %v", err))
+		return r.Status().Update(ctx, dk)
+	}
+	if err := r.deletePrivateKeys(ctx, dk); err != nil {
+		return err
+	}
+	meta.RemoveStatusCondition(&dk.Status.Conditions, dkimmanagerv2.ConditionPolicyCompliant)
+	meta.RemoveStatusCondition(&dk.Status.Conditions, dkimmanagerv2.ConditionDNSPublished)
+	r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonRevoked, "DKIM key revoked")
+	return r.Status().Update(ctx, dk)
 }
 
 func (r *DKIMKeyReconciler) reconcile(ctx context.Context, dk *dkimmanagerv2.DKIMKey) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	var key []byte
-	var pub string
-	var reason string
-	var err error
-	var targets []string
-	targets, err = r.checkForExistingKey(ctx, dk)
+	records, err := r.checkForExistingKeys(ctx, dk)
 	if err != nil {
 		logger.Error(err, "precondition failed")
 		r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonInvalid, err.Error())
 		return ctrl.Result{}, r.Status().Update(ctx, dk)
 	}
-	if targets == nil {
-		logger.Info("generating new key pair", "selector", dk.Spec.Selector)
-		key, pub, reason, err = r.generateKeyPair(dk)
-		if err != nil {
-			logger.Error(err, "failed to generate key pair")
-			r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, reason, err.Error())
+	if records == nil {
+		if err := r.KeyPolicy.ForPurpose(dk.Spec.Purpose).CheckDKIMKey(dk); err != nil {
+			logger.Info("key policy violation, not generating key pair", "reason", err.Error())
+			r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonInvalid, err.Error())
 			return ctrl.Result{}, r.Status().Update(ctx, dk)
 		}
-		if err := r.reconcileDKIMPrivateKey(ctx, dk, key); err != nil {
+		keys := make(map[string][]byte)
+		for _, k := range dk.Keys() {
+			logger.Info("generating new key pair", "selector", k.Selector, "keyType", k.KeyType)
+			key, pub, reason, err := r.generateKeyPair(k)
+			if err != nil {
+				logger.Error(err, "failed to generate key pair")
+				r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, reason, err.Error())
+				return ctrl.Result{}, r.Status().Update(ctx, dk)
+			}
+			keys[k.PrivateKeyFilename()] = key
+			records = append(records, keyRecords(dk, k, pub)...)
+		}
+		if err := r.reconcileDKIMPrivateKey(ctx, dk, keys); err != nil {
 			logger.Error(err, "failed to reconcile Secret")
 			r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to reconcile Secret: %v", err))
 			return ctrl.Result{}, r.Status().Update(ctx, dk)
 		}
-		targets = []string{dkim.GenTXTValue(pub, dk.Spec.KeyType)}
 	}
-	if err := r.reconcileDKIMRecord(ctx, dk, targets); err != nil {
-		logger.Error(err, "failed to reconcile DNSEndpoint")
-		r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to reconcile DNSEndpoint: %v", err))
+	if err := r.reconcileDKIMRecord(ctx, dk, records); err != nil {
+		if errors.Is(err, publisher.ErrRecordSetConflict) {
+			return ctrl.Result{}, r.markInvalid(ctx, dk, err.Error())
+		}
+		logger.Error(err, "failed to publish DKIM records")
+		r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to publish DKIM records: %v", err))
 		return ctrl.Result{}, r.Status().Update(ctx, dk)
 	}
 	logger.Info("done reconciling DKIMKey")
 	r.setCondition(dk, dkimmanagerv2.ConditionReady, v1.ConditionTrue, dkimmanagerv2.ReasonSucceeded, "DKIM key created successfully")
-	return ctrl.Result{}, r.Status().Update(ctx, dk)
+	requeueAfter, _ := r.verifyDNSRecords(ctx, dk, records)
+	return ctrl.Result{RequeueAfter: requeueAfter}, r.Status().Update(ctx, dk)
 }
 
-func (r DKIMKeyReconciler) generateKeyPair(dk *dkimmanagerv2.DKIMKey) (key []byte, pub, reason string, err error) {
-	switch dk.Spec.KeyType {
+func (r DKIMKeyReconciler) generateKeyPair(k dkimmanagerv2.DKIMKeyEntry) (key []byte, pub, reason string, err error) {
+	switch k.KeyType {
 	case dkim.KeyTypeRSA:
-		key, pub, err = dkim.GenRSA(dk.Spec.KeyLength)
+		key, pub, err = dkim.GenRSA(k.KeyLength)
 	case dkim.KeyTypeED25519:
 		key, pub, err = dkim.GenED25519()
 	default:
@@ -231,10 +528,9 @@ func (r DKIMKeyReconciler) generateKeyPair(dk *dkimmanagerv2.DKIMKey) (key []byt
 	return
 }
 
-func (r *DKIMKeyReconciler) checkForExistingKey(ctx context.Context, dk *dkimmanagerv2.DKIMKey) ([]string, error) {
-	var targets []string
-	// If the private key does not exist, subsequent checks can be short-circuited.
-	// Otherwise, the public key can be derived from the private key.
+func (r *DKIMKeyReconciler) checkForExistingKeys(ctx context.Context, dk *dkimmanagerv2.DKIMKey) ([]publisher.Record, error) {
+	// If the private keys do not exist, subsequent checks can be short-circuited.
+	// Otherwise, the public keys can be derived from the private keys.
 	s := &corev1.Secret{}
 	sKey := client.ObjectKey{
 		Namespace: dk.Namespace,
@@ -247,62 +543,114 @@ func (r *DKIMKeyReconciler) checkForExistingKey(ctx context.Context, dk *dkimman
 	if apierrors.IsNotFound(err) {
 		return nil, nil
 	}
-	priv, ok := s.Data[r.generatePrivateKeyFilename(dk)]
-	if !ok {
-		return nil, fmt.Errorf("private key not found in Secret")
+	if err := r.adoptSecret(ctx, dk, s); err != nil {
+		return nil, err
 	}
-	var pub string
-	switch dk.Spec.KeyType {
-	case dkim.KeyTypeRSA:
-		pub, err = dkim.DeriveRSAPublicKey(priv, dk.Spec.KeyLength)
-	case dkim.KeyTypeED25519:
-		pub, err = dkim.DeriveED25519PublicKey(priv)
-	default:
-		return nil, fmt.Errorf("invalid key type specified")
+	keys := dk.Keys()
+	records := make([]publisher.Record, 0, len(keys))
+	for _, k := range keys {
+		priv, ok := s.Data[k.PrivateKeyFilename()]
+		if !ok {
+			return nil, fmt.Errorf("private key for selector %s not found in Secret", k.Selector)
+		}
+		pub, err := dkim.DerivePublicKey(priv, k.KeyType, k.KeyLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive public key: %v", err)
+		}
+		records = append(records, keyRecords(dk, k, pub)...)
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to derive public key: %v", err)
+	return records, nil
+}
+
+// adoptSecret makes the DKIMKey the controller of an existing Secret holding its private keys,
+// so that the Secret follows the deletion policy of the DKIMKey. As in the webhook, only Secrets
+// owned by the DKIMKey, or opting in with AdoptSecretAnnotation, and without another controller are adopted.
+// The annotation is removed once adopted.
+func (r *DKIMKeyReconciler) adoptSecret(ctx context.Context, dk *dkimmanagerv2.DKIMKey, s *corev1.Secret) error {
+	if v1.IsControlledBy(s, dk) {
+		return nil
+	}
+	if s.Annotations[dkimmanagerv2.AdoptSecretAnnotation] != "true" && !r.isOwnedByDKIMKey(dk, s.OwnerReferences) {
+		return fmt.Errorf("secret %s already exists and is not owned by this DKIMKey", s.Name)
+	}
+	if owner := v1.GetControllerOf(s); owner != nil {
+		return fmt.Errorf("secret %s is already controlled by %s %s", s.Name, owner.Kind, owner.Name)
+	}
+	patch := client.MergeFrom(s.DeepCopy())
+	delete(s.Annotations, dkimmanagerv2.AdoptSecretAnnotation)
+	if err := ctrl.SetControllerReference(dk, s, r.Scheme); err != nil {
+		return fmt.Errorf("failed to adopt Secret: %v", err)
 	}
-	targets = []string{dkim.GenTXTValue(pub, dk.Spec.KeyType)}
-	return targets, nil
+	if err := r.Patch(ctx, s, patch); err != nil {
+		return fmt.Errorf("failed to adopt Secret: %v", err)
+	}
+	log.FromContext(ctx).Info("adopted existing Secret", "secret", s.Name)
+	return nil
 }
 
-func (r *DKIMKeyReconciler) reconcileDKIMRecord(ctx context.Context, dk *dkimmanagerv2.DKIMKey, targets []string) error {
-	logger := log.FromContext(ctx)
-	de := externaldns.DNSEndpoint()
-	de.SetName(dk.Name)
-	de.SetNamespace(dk.Namespace)
-	de.UnstructuredContent()["spec"] = map[string]interface{}{
-		"endpoints": []map[string]interface{}{
-			{
-				"dnsName":    fmt.Sprintf("%s._domainkey.%s", dk.Spec.Selector, dk.Spec.Domain),
-				"recordTTL":  dk.Spec.TTL,
-				"recordType": "TXT",
-				"targets":    targets,
-			},
+// keyRecords returns the records publishing the public key pub of k. Delegated keys are published
+// as a TXT record under the delegation zone, and a CNAME record pointing to it under the domain.
+func keyRecords(dk *dkimmanagerv2.DKIMKey, k dkimmanagerv2.DKIMKeyEntry, pub string) []publisher.Record {
+	return keyRecordsWithValue(dk, k, dk.RecordValue(k, pub))
+}
+
+// revokedKeyRecords returns the records of k with an empty public key, marking the key as revoked.
+func revokedKeyRecords(dk *dkimmanagerv2.DKIMKey, k dkimmanagerv2.DKIMKeyEntry) []publisher.Record {
+	return keyRecordsWithValue(dk, k, dkim.GenRevokedTXTValue(k.KeyType, dk.Spec.RecordTags()))
+}
+
+func keyRecordsWithValue(dk *dkimmanagerv2.DKIMKey, k dkimmanagerv2.DKIMKeyEntry, value string) []publisher.Record {
+	records := []publisher.Record{
+		{
+			Name:    k.TXTRecordName(),
+			TTL:     dk.Spec.TTL,
+			Targets: []string{value},
 		},
 	}
-	if err := ctrl.SetControllerReference(dk, de, r.Scheme); err != nil {
-		return err
+	if k.DelegationZone != "" {
+		records = append(records, publisher.Record{
+			Name:    k.RecordName(),
+			Type:    publisher.RecordTypeCNAME,
+			TTL:     dk.Spec.TTL,
+			Targets: []string{k.TXTRecordName()},
+		})
 	}
-	ac := client.ApplyConfigurationFromUnstructured(de)
-	if err := r.Apply(ctx, ac, fieldOwner, client.ForceOwnership); err != nil {
+	return records
+}
+
+func (r *DKIMKeyReconciler) reconcileDKIMRecord(ctx context.Context, dk *dkimmanagerv2.DKIMKey, records []publisher.Record) error {
+	opts := applyDNSEndpointOptions(dk.Spec.DNSEndpoint, records)
+	if err := r.Publisher.Publish(ctx, dk, records, opts); err != nil {
 		return err
 	}
-	logger.Info("done reconciling DNSEndpoint")
+	log.FromContext(ctx).Info("done reconciling DKIM records")
 	return nil
 }
 
-func (r *DKIMKeyReconciler) reconcileDKIMPrivateKey(ctx context.Context, dk *dkimmanagerv2.DKIMKey, key []byte) error {
+// applyDNSEndpointOptions sets the set identifier and provider-specific properties of de on the records,
+// and returns the options to publish them with.
+func applyDNSEndpointOptions(de *dkimmanagerv2.DNSEndpointOptions, records []publisher.Record) publisher.PublishOptions {
+	if de == nil {
+		return publisher.PublishOptions{}
+	}
+	props := make([]publisher.ProviderSpecificProperty, 0, len(de.ProviderSpecific))
+	for _, prop := range de.ProviderSpecific {
+		props = append(props, publisher.ProviderSpecificProperty{Name: prop.Name, Value: prop.Value})
+	}
+	for i := range records {
+		records[i].SetIdentifier = de.SetIdentifier
+		records[i].ProviderSpecific = props
+	}
+	return publisher.PublishOptions{Labels: de.Labels, Annotations: de.Annotations}
+}
+
+func (r *DKIMKeyReconciler) reconcileDKIMPrivateKey(ctx context.Context, dk *dkimmanagerv2.DKIMKey, keys map[string][]byte) error {
 	logger := log.FromContext(ctx)
-	filename := r.generatePrivateKeyFilename(dk)
 	s := &corev1.Secret{}
 	s.SetName(dk.Spec.SecretName)
 	s.SetNamespace(dk.Namespace)
 	s.Immutable = ptr.To(true)
-	s.Data = map[string][]byte{
-		filename: key,
-	}
+	s.Data = keys
 	if err := ctrl.SetControllerReference(dk, s, r.Scheme); err != nil {
 		return err
 	}
@@ -313,13 +661,51 @@ func (r *DKIMKeyReconciler) reconcileDKIMPrivateKey(ctx context.Context, dk *dki
 	return nil
 }
 
-func (r DKIMKeyReconciler) generatePrivateKeyFilename(dk *dkimmanagerv2.DKIMKey) string {
-	return fmt.Sprintf("%s.%s.key", dk.Spec.Domain, dk.Spec.Selector)
+// dkimKeysForPolicy enqueues all DKIMKeys when a DKIMDomainPolicy changes.
+func (r *DKIMKeyReconciler) dkimKeysForPolicy(ctx context.Context, _ client.Object) []reconcile.Request {
+	dkl := &dkimmanagerv2.DKIMKeyList{}
+	if err := r.List(ctx, dkl); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list DKIMKeys")
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(dkl.Items))
+	for _, dk := range dkl.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&dk)})
+	}
+	return reqs
+}
+
+// dkimKeysForRecord enqueues the other DKIMKeys sharing the record name of a changed DKIMKey,
+// so that a DKIMKey blocked by a conflict is reconciled once the conflict is resolved.
+func (r *DKIMKeyReconciler) dkimKeysForRecord(ctx context.Context, o client.Object) []reconcile.Request {
+	dk, ok := o.(*dkimmanagerv2.DKIMKey)
+	if !ok {
+		return nil
+	}
+	conflicts, err := policy.FindRecordConflicts(ctx, r.Client, client.ObjectKeyFromObject(dk), dk.RecordNames())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to find conflicting DKIMKeys")
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(conflicts))
+	for _, c := range conflicts {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&c)})
+	}
+	return reqs
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DKIMKeyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Publisher == nil {
+		gvk := r.DNSEndpointGVK
+		if gvk.Empty() {
+			gvk = externaldns.GroupVersionKind
+		}
+		r.Publisher = publisher.NewExternalDNSPublisher(r.Client, r.ReadClient, r.Scheme, fieldOwner, gvk)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&dkimmanagerv2.DKIMKey{}).
+		Watches(&dkimmanagerv2.DKIMKey{}, handler.EnqueueRequestsFromMapFunc(r.dkimKeysForRecord)).
+		Watches(&dkimmanagerv2.DKIMDomainPolicy{}, handler.EnqueueRequestsFromMapFunc(r.dkimKeysForPolicy)).
 		Complete(r)
 }
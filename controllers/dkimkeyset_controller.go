@@ -0,0 +1,344 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+)
+
+const (
+	// dkimKeySetLabel is set to the name of the DKIMKeySet on the DKIMKeys it provisions,
+	// shortened with a hash if it does not fit in a label value.
+	dkimKeySetLabel = "dkim-manager.atelierhsn.com/dkimkeyset"
+
+	// dkimKeySetOwnerIndex indexes DKIMKeys by the UID of the DKIMKeySet controlling them.
+	dkimKeySetOwnerIndex = ".metadata.controller.dkimkeyset"
+
+	// dkimKeySetRefreshInterval is the interval at which domains are read again from ConfigMaps,
+	// which are not watched.
+	dkimKeySetRefreshInterval = 5 * time.Minute
+)
+
+// DKIMKeySetReconciler reconciles a DKIMKeySet object.
+type DKIMKeySetReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeysets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeysets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeysets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list
+
+// Reconcile provisions a DKIMKey for each domain of a DKIMKeySet, and deletes the DKIMKeys of removed domains.
+func (r *DKIMKeySetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	ks := &dkimmanagerv2.DKIMKeySet{}
+	if err := r.Get(ctx, req.NamespacedName, ks); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !ks.DeletionTimestamp.IsZero() {
+		// The DKIMKeys are deleted by the garbage collector.
+		return ctrl.Result{}, nil
+	}
+
+	if len(r.Namespaces) > 0 && !slices.Contains(r.Namespaces, ks.Namespace) {
+		logger.Info("dkimkeyset is in an invalid namespace, ignoring")
+		return ctrl.Result{}, r.markInvalid(ctx, ks, "DKIMKeySet is in an invalid namespace")
+	}
+
+	domains, invalid, err := r.listDomains(ctx, ks)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var requeueAfter time.Duration
+	if ks.Spec.DomainsFrom != nil {
+		requeueAfter = dkimKeySetRefreshInterval
+	}
+	if invalid != "" {
+		return ctrl.Result{RequeueAfter: requeueAfter}, r.markInvalid(ctx, ks, invalid)
+	}
+
+	existing, err := r.provisionedKeys(ctx, ks)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var ready int32
+	var conflicts []string
+	for _, domain := range domains {
+		dk, ok := existing[domain]
+		if !ok {
+			created, err := r.createKey(ctx, ks, domain)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !created {
+				conflicts = append(conflicts, ks.KeyName(domain))
+			}
+			continue
+		}
+		delete(existing, domain)
+		if err := r.updateKey(ctx, ks, dk); err != nil {
+			return ctrl.Result{}, err
+		}
+		if dk.IsReady() {
+			ready++
+		}
+	}
+	for _, dk := range existing {
+		logger.Info("deleting DKIMKey of removed domain", "dkimkey", dk.Name, "domain", dk.Spec.Domain)
+		if err := r.Delete(ctx, dk); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	old := ks.Status.DeepCopy()
+	ks.Status.Domains = int32(len(domains))
+	ks.Status.ReadyKeys = ready
+	switch {
+	case len(conflicts) > 0:
+		r.setCondition(ks, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("DKIMKey names already in use: %s", strings.Join(conflicts, ", ")))
+	case int(ready) < len(domains):
+		r.setCondition(ks, v1.ConditionFalse, dkimmanagerv2.ReasonProgressing, fmt.Sprintf("%d of %d DKIMKeys are ready", ready, len(domains)))
+	default:
+		r.setCondition(ks, v1.ConditionTrue, dkimmanagerv2.ReasonSucceeded, fmt.Sprintf("%d DKIMKeys are ready", ready))
+	}
+	if equality.Semantic.DeepEqual(old, &ks.Status) {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, r.Status().Update(ctx, ks)
+}
+
+// setCondition updates the Ready condition on the DKIMKeySet.
+func (r *DKIMKeySetReconciler) setCondition(ks *dkimmanagerv2.DKIMKeySet, status v1.ConditionStatus, reason, message string) {
+	ks.Status.ObservedGeneration = ks.Generation
+	meta.SetStatusCondition(&ks.Status.Conditions, v1.Condition{
+		Type:               dkimmanagerv2.ConditionReady,
+		Status:             status,
+		ObservedGeneration: ks.Generation,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: v1.Now(),
+	})
+}
+
+// markInvalid sets the Ready condition to Invalid with the given message, unless it is already set.
+func (r *DKIMKeySetReconciler) markInvalid(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet, message string) error {
+	cond := meta.FindStatusCondition(ks.Status.Conditions, dkimmanagerv2.ConditionReady)
+	if cond != nil && cond.Reason == dkimmanagerv2.ReasonInvalid && cond.Message == message && ks.Status.ObservedGeneration == ks.Generation {
+		return nil
+	}
+	r.setCondition(ks, v1.ConditionFalse, dkimmanagerv2.ReasonInvalid, message)
+	return r.Status().Update(ctx, ks)
+}
+
+// listDomains returns the normalized domains of the DKIMKeySet, including those listed in ConfigMaps.
+// If the DKIMKeySet is invalid, including when domainsFrom matches no ConfigMap, the reason is returned instead.
+func (r *DKIMKeySetReconciler) listDomains(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet) ([]string, string, error) {
+	domains := slices.Clone(ks.Spec.Domains)
+	if from := ks.Spec.DomainsFrom; from != nil {
+		selector, err := v1.LabelSelectorAsSelector(&from.Selector)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid domainsFrom selector: %v", err), nil
+		}
+		cml := &corev1.ConfigMapList{}
+		if err := r.ReadClient.List(ctx, cml, client.InNamespace(ks.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, "", fmt.Errorf("failed to list ConfigMaps: %w", err)
+		}
+		// Guards against deleting every DKIMKey of ConfigMap domains when the ConfigMaps are relabeled
+		// or deleted by mistake. Unsetting domainsFrom removes them deliberately.
+		if len(cml.Items) == 0 {
+			return nil, "domainsFrom selector matches no ConfigMap", nil
+		}
+		for _, cm := range cml.Items {
+			domains = append(domains, parseDomainList(cm.Data[from.Key])...)
+		}
+	}
+	for i, domain := range domains {
+		domains[i] = policy.NormalizeRecordName(domain)
+		if err := dkim.ValidateDomain(domains[i]); err != nil {
+			return nil, fmt.Sprintf("invalid domain %q: %v", domain, err), nil
+		}
+	}
+	slices.Sort(domains)
+	return slices.Compact(domains), "", nil
+}
+
+// parseDomainList returns the domains listed one per line, ignoring empty lines and comments.
+func parseDomainList(data string) []string {
+	var domains []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains
+}
+
+// provisionedKeys returns the DKIMKeys provisioned by the DKIMKeySet, by normalized domain.
+func (r *DKIMKeySetReconciler) provisionedKeys(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet) (map[string]*dkimmanagerv2.DKIMKey, error) {
+	dkl := &dkimmanagerv2.DKIMKeyList{}
+	if err := r.List(ctx, dkl, client.InNamespace(ks.Namespace), client.MatchingFields{dkimKeySetOwnerIndex: string(ks.UID)}); err != nil {
+		return nil, fmt.Errorf("failed to list DKIMKeys: %w", err)
+	}
+	keys := make(map[string]*dkimmanagerv2.DKIMKey, len(dkl.Items))
+	for i := range dkl.Items {
+		dk := &dkl.Items[i]
+		if !v1.IsControlledBy(dk, ks) {
+			continue
+		}
+		keys[policy.NormalizeRecordName(dk.Spec.Domain)] = dk
+	}
+	return keys, nil
+}
+
+// keyLabels returns the labels of the DKIMKeys provisioned by the DKIMKeySet.
+func keyLabels(ks *dkimmanagerv2.DKIMKeySet) map[string]string {
+	labels := maps.Clone(ks.Spec.Template.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[dkimKeySetLabel] = keySetLabelValue(ks.Name)
+	return labels
+}
+
+// keySetLabelValue returns the name of the DKIMKeySet, shortened with a hash of the name
+// if it is longer than a label value can be.
+func keySetLabelValue(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := "-" + hex.EncodeToString(sum[:8])
+	return strings.TrimRight(name[:validation.LabelValueMaxLength-len(suffix)], "-.") + suffix
+}
+
+// createKey creates the DKIMKey of the domain. It returns false if its name is already used by another DKIMKey.
+func (r *DKIMKeySetReconciler) createKey(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet, domain string) (bool, error) {
+	dk := &dkimmanagerv2.DKIMKey{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        ks.KeyName(domain),
+			Namespace:   ks.Namespace,
+			Labels:      keyLabels(ks),
+			Annotations: maps.Clone(ks.Spec.Template.Annotations),
+		},
+		Spec: ks.KeySpec(domain),
+	}
+	if err := controllerutil.SetControllerReference(ks, dk, r.Scheme); err != nil {
+		return false, err
+	}
+	err := r.Create(ctx, dk)
+	if apierrors.IsAlreadyExists(err) {
+		log.FromContext(ctx).Info("DKIMKey name is already in use, ignoring", "dkimkey", dk.Name, "domain", domain)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create DKIMKey for %s: %w", domain, err)
+	}
+	log.FromContext(ctx).Info("created DKIMKey", "dkimkey", dk.Name, "domain", domain)
+	return true, nil
+}
+
+// updateKey applies the mutable fields of the template to a provisioned DKIMKey. Changes to the other
+// fields, which cannot be changed once a DKIMKey is created, only apply to DKIMKeys created afterwards.
+func (r *DKIMKeySetReconciler) updateKey(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet, dk *dkimmanagerv2.DKIMKey) error {
+	if !dk.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	spec := ks.KeySpec(dk.Spec.Domain)
+	patch := client.MergeFrom(dk.DeepCopy())
+	if spec.TTL != 0 {
+		dk.Spec.TTL = spec.TTL
+	}
+	dk.Spec.Tags = spec.Tags
+	dk.Spec.DNSEndpoint = spec.DNSEndpoint
+	if spec.DeletionPolicy != "" {
+		dk.Spec.DeletionPolicy = spec.DeletionPolicy
+	}
+	if dk.Labels == nil {
+		dk.Labels = map[string]string{}
+	}
+	maps.Copy(dk.Labels, keyLabels(ks))
+	if len(ks.Spec.Template.Annotations) > 0 {
+		if dk.Annotations == nil {
+			dk.Annotations = map[string]string{}
+		}
+		maps.Copy(dk.Annotations, ks.Spec.Template.Annotations)
+	}
+	data, err := patch.Data(dk)
+	if err != nil {
+		return err
+	}
+	if string(data) == "{}" {
+		return nil
+	}
+	return r.Patch(ctx, dk, patch)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DKIMKeySetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &dkimmanagerv2.DKIMKey{}, dkimKeySetOwnerIndex, func(o client.Object) []string {
+		owner := v1.GetControllerOf(o)
+		if owner == nil || owner.Kind != dkimmanagerv2.DKIMKeySetKind {
+			return nil
+		}
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil || gv.Group != apiGroup {
+			return nil
+		}
+		return []string{string(owner.UID)}
+	})
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dkimmanagerv2.DKIMKeySet{}).
+		Owns(&dkimmanagerv2.DKIMKey{}).
+		Complete(r)
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+func provisionedDomains(ctx context.Context, ks *dkimmanagerv2.DKIMKeySet) ([]string, error) {
+	dkl := &dkimmanagerv2.DKIMKeyList{}
+	if err := k8sClient.List(ctx, dkl, client.InNamespace(ks.Namespace), client.MatchingLabels{dkimKeySetLabel: keySetLabelValue(ks.Name)}); err != nil {
+		return nil, err
+	}
+	var domains []string
+	for _, dk := range dkl.Items {
+		if dk.DeletionTimestamp.IsZero() {
+			domains = append(domains, dk.Spec.Domain)
+		}
+	}
+	return domains, nil
+}
+
+var _ = Describe("DKIMKeySet controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		err = (&DKIMKeySetReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should provision a DKIMKey per domain", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating domain list ConfigMap")
+		err := k8sClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"customers": "true"}},
+			Data:       map[string]string{"domains": "# customers\nc.atelierhsn.com\n\nB.atelierhsn.com.\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating DKIMKeySet")
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(name)
+		ks.SetNamespace(namespace)
+		ks.Spec = dkimmanagerv2.DKIMKeySetSpec{
+			Domains: []string{"a.atelierhsn.com", "b.atelierhsn.com"},
+			DomainsFrom: &dkimmanagerv2.DomainsFromConfigMaps{
+				Selector: v1.LabelSelector{MatchLabels: map[string]string{"customers": "true"}},
+				Key:      "domains",
+			},
+			Template: dkimmanagerv2.DKIMKeyTemplate{
+				Labels: map[string]string{"team": "mail"},
+				Spec: dkimmanagerv2.DKIMKeyTemplateSpec{
+					Selector: "selector1",
+					TTL:      3600,
+					KeyType:  dkim.KeyTypeED25519,
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("a.atelierhsn.com", "b.atelierhsn.com", "c.atelierhsn.com"))
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ks.KeyName("a.atelierhsn.com")}, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dk.Spec.Selector).To(Equal("selector1"))
+		Expect(dk.Spec.SecretName).To(Equal(ks.KeyName("a.atelierhsn.com")))
+		Expect(dk.Spec.KeyType).To(Equal(dkim.KeyTypeED25519))
+		Expect(dk.Labels).To(HaveKeyWithValue("team", "mail"))
+		Expect(v1.IsControlledBy(dk, ks)).To(BeTrue())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ks), ks)).To(Succeed())
+			g.Expect(ks.Status.Domains).To(Equal(int32(3)))
+			g.Expect(ks.Status.ReadyKeys).To(BeZero())
+		}).Should(Succeed())
+
+		By("removing a domain and changing the TTL")
+		ks.Spec.Domains = []string{"a.atelierhsn.com"}
+		ks.Spec.Template.Spec.TTL = 600
+		err = k8sClient.Update(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("a.atelierhsn.com", "b.atelierhsn.com", "c.atelierhsn.com"))
+		Eventually(func() (uint, error) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)
+			return dk.Spec.TTL, err
+		}).Should(Equal(uint(600)))
+
+		By("removing the ConfigMap domains")
+		ks.Spec.DomainsFrom = nil
+		err = k8sClient.Update(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("a.atelierhsn.com"))
+	})
+
+	It("should keep the DKIMKeys of ConfigMap domains when no ConfigMap matches", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: ctrl.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"customers": "true"}},
+			Data:       map[string]string{"domains": "c.atelierhsn.com\n"},
+		}
+		err := k8sClient.Create(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(name)
+		ks.SetNamespace(namespace)
+		ks.Spec = dkimmanagerv2.DKIMKeySetSpec{
+			Domains: []string{"a.atelierhsn.com"},
+			DomainsFrom: &dkimmanagerv2.DomainsFromConfigMaps{
+				Selector: v1.LabelSelector{MatchLabels: map[string]string{"customers": "true"}},
+				Key:      "domains",
+			},
+			Template: dkimmanagerv2.DKIMKeyTemplate{
+				Spec: dkimmanagerv2.DKIMKeyTemplateSpec{Selector: "selector1"},
+			},
+		}
+		err = k8sClient.Create(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("a.atelierhsn.com", "c.atelierhsn.com"))
+
+		By("deleting the ConfigMap")
+		err = k8sClient.Delete(ctx, cm)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(ks), ks)
+		Expect(err).NotTo(HaveOccurred())
+		ks.Spec.Template.Spec.TTL = 600
+		err = k8sClient.Update(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ks), ks)).To(Succeed())
+			g.Expect(ks.Status.ObservedGeneration).To(Equal(ks.Generation))
+			g.Expect(ks.Status.Conditions).To(ContainElement(HaveField("Reason", dkimmanagerv2.ReasonInvalid)))
+		}).Should(Succeed())
+		Consistently(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("a.atelierhsn.com", "c.atelierhsn.com"))
+	})
+
+	It("should not take over existing DKIMKeys", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(name)
+		ks.SetNamespace(namespace)
+		ks.Spec.Domains = []string{"taken.atelierhsn.com"}
+		ks.Spec.Template.Spec.Selector = "selector1"
+
+		By("creating a DKIMKey with the same name")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(ks.KeyName("taken.atelierhsn.com"))
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   "selector1",
+			Domain:     "taken.atelierhsn.com",
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Create(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ks), ks)).To(Succeed())
+			g.Expect(ks.IsReady()).To(BeFalse())
+			g.Expect(ks.Status.Conditions).To(ContainElement(HaveField("Reason", dkimmanagerv2.ReasonFailed)))
+		}).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dk), dk)).To(Succeed())
+		Expect(dk.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should provision DKIMKeys for DKIMKeySets with names longer than a label value", func() {
+		name := strings.Repeat("a", 200) + "." + uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(name)
+		ks.SetNamespace(namespace)
+		ks.Spec.Domains = []string{"long.atelierhsn.com"}
+		ks.Spec.Template.Spec.Selector = "selector1"
+		err := k8sClient.Create(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(ConsistOf("long.atelierhsn.com"))
+		dk := &dkimmanagerv2.DKIMKey{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ks.KeyName("long.atelierhsn.com")}, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v1.IsControlledBy(dk, ks)).To(BeTrue())
+	})
+
+	It("should reject invalid domains", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(name)
+		ks.SetNamespace(namespace)
+		ks.Spec.Domains = []string{"valid.atelierhsn.com", "in valid"}
+		err := k8sClient.Create(ctx, ks)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ks), ks)).To(Succeed())
+			g.Expect(ks.Status.Conditions).To(ContainElement(HaveField("Reason", dkimmanagerv2.ReasonInvalid)))
+		}).Should(Succeed())
+		Consistently(func() ([]string, error) {
+			return provisionedDomains(ctx, ks)
+		}).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("parseDomainList", func() {
+	It("should ignore empty lines and comments", func() {
+		Expect(parseDomainList("a.example.com\n\n  # comment\n b.example.com \r\n")).To(Equal([]string{"a.example.com", "b.example.com"}))
+		Expect(parseDomainList("")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("keySetLabelValue", func() {
+	It("should shorten names longer than a label value", func() {
+		Expect(keySetLabelValue("customers")).To(Equal("customers"))
+		long := strings.Repeat("a", 100)
+		Expect(validation.IsValidLabelValue(keySetLabelValue(long))).To(BeEmpty())
+		Expect(keySetLabelValue(long)).NotTo(Equal(keySetLabelValue(long + "b")))
+	})
+})
+
+var _ = Describe("DKIMKeySet.KeyName", func() {
+	It("should fit in a resource name", func() {
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		ks.SetName(strings.Repeat("a", 240) + ".b")
+		name := ks.KeyName("example.com")
+		Expect(validation.IsDNS1123Subdomain(name)).To(BeEmpty())
+		Expect(name).NotTo(Equal(ks.KeyName("example.org")))
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// DMARCPolicyReconciler reconciles a DMARCPolicy object.
+type DMARCPolicyReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// Publisher publishes the DMARC records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+
+	records *domainRecordReconciler[*dkimmanagerv2.DMARCPolicy]
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dmarcpolicies,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dmarcpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dmarcpolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile publishes the DMARC record described by a DMARCPolicy.
+func (r *DMARCPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.records.Reconcile(ctx, req)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DMARCPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Publisher = defaultPublisher(r.Publisher, r.Client, r.ReadClient, r.Scheme, r.DNSEndpointGVK)
+	r.records = &domainRecordReconciler[*dkimmanagerv2.DMARCPolicy]{
+		Client:     r.Client,
+		namespaces: r.Namespaces,
+		publisher:  r.Publisher,
+		kind:       "DMARCPolicy",
+		recordType: "DMARC",
+		newObject:  func() *dkimmanagerv2.DMARCPolicy { return &dkimmanagerv2.DMARCPolicy{} },
+		newList:    func() client.ObjectList { return &dkimmanagerv2.DMARCPolicyList{} },
+		validate: func(dp *dkimmanagerv2.DMARCPolicy) error {
+			return dp.Record().Validate()
+		},
+	}
+	return r.records.newControllerManagedBy(mgr).Complete(r)
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/dmarc"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+var _ = Describe("DMARCPolicy controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler := &DMARCPolicyReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should publish the DMARC record as a DNSEndpoint", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating DMARCPolicy")
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = dkimmanagerv2.DMARCPolicySpec{
+			Domain:              "atelierhsn.com",
+			TTL:                 3600,
+			Policy:              dmarc.PolicyReject,
+			Percentage:          ptr.To[int32](50),
+			AggregateReportURIs: []string{"mailto:dmarc@atelierhsn.com"},
+			DKIMAlignment:       dmarc.AlignmentStrict,
+		}
+		err := k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name + "-dmarc"}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			endpoint := endpoints[0].(map[string]interface{})
+			g.Expect(endpoint).To(HaveKeyWithValue("dnsName", "_dmarc.atelierhsn.com"))
+			g.Expect(endpoint).To(HaveKeyWithValue("recordType", "TXT"))
+			g.Expect(endpoint["targets"]).To(ConsistOf(`"v=DMARC1; p=reject; pct=50; rua=mailto:dmarc@atelierhsn.com; adkim=s;"`))
+		}).Should(Succeed())
+
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(dp), dp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dp.IsReady()).To(BeTrue())
+
+		By("changing the domain")
+		dp.Spec.Domain = "example.com"
+		err = k8sClient.Update(ctx, dp)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should withdraw the DMARC record when the domain is no longer allowed by policy", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = dkimmanagerv2.DMARCPolicySpec{
+			Domain: "withdraw.atelierhsn.com",
+			TTL:    3600,
+			Policy: dmarc.PolicyNone,
+		}
+		err := k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-dmarc", namespace)
+		}).Should(Succeed())
+
+		By("creating DKIMDomainPolicy not allowing the domain")
+		p := &dkimmanagerv2.DKIMDomainPolicy{}
+		p.SetName(uuid.NewString())
+		p.Spec = dkimmanagerv2.DKIMDomainPolicySpec{
+			Namespaces: []string{namespace},
+			Domains:    []string{"example.com"},
+		}
+		err = k8sClient.Create(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(k8sClient.Delete(ctx, p)).To(Succeed())
+		})
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-dmarc", namespace)
+		}).ShouldNot(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dp), dp)).To(Succeed())
+			cond := meta.FindStatusCondition(dp.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+		}).Should(Succeed())
+	})
+
+	It("should mark DMARCPolicies claiming an already claimed domain as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		otherNamespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		shouldCreateNamespace(ctx, otherNamespace)
+
+		spec := dkimmanagerv2.DMARCPolicySpec{
+			Domain: "dmarc.atelierhsn.com",
+			TTL:    3600,
+			Policy: dmarc.PolicyNone,
+		}
+
+		By("creating DMARCPolicy")
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = spec
+		err := k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-dmarc", namespace)
+		}).Should(Succeed())
+
+		By("creating conflicting DMARCPolicy")
+		dp2 := &dkimmanagerv2.DMARCPolicy{}
+		dp2.SetName(name)
+		dp2.SetNamespace(otherNamespace)
+		dp2.Spec = spec
+		err = k8sClient.Create(ctx, dp2)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dp2), dp2)
+			if err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(dp2.Status.Conditions, dkimmanagerv2.ConditionReady)
+			if cond == nil || cond.Reason != dkimmanagerv2.ReasonInvalid {
+				return fmt.Errorf("DMARCPolicy is not invalid")
+			}
+			return nil
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-dmarc", otherNamespace)
+		}).ShouldNot(Succeed())
+
+		By("deleting the original DMARCPolicy")
+		err = k8sClient.Delete(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-dmarc", otherNamespace)
+		}).Should(Succeed())
+	})
+
+	It("should not take over the DNSEndpoint of a DKIMKey sharing its name", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating DKIMKey named after the DNSEndpoint of the DMARCPolicy")
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name + "-dmarc")
+		dk.SetNamespace(namespace)
+		dk.Spec = dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   name,
+			Domain:     "collision.atelierhsn.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeRSA,
+		}
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		de := externaldns.DNSEndpoint()
+		de.SetName(dk.Name)
+		de.SetNamespace(namespace)
+		de.SetOwnerReferences([]v1.OwnerReference{*v1.NewControllerRef(dk, dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeyKind))})
+		err = unstructured.SetNestedSlice(de.UnstructuredContent(), []interface{}{}, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Create(ctx, de)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("creating DMARCPolicy")
+		dp := &dkimmanagerv2.DMARCPolicy{}
+		dp.SetName(name)
+		dp.SetNamespace(namespace)
+		dp.Spec = dkimmanagerv2.DMARCPolicySpec{
+			Domain: "collision.atelierhsn.com",
+			TTL:    3600,
+			Policy: dmarc.PolicyNone,
+		}
+		err = k8sClient.Create(ctx, dp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(dp), dp)
+			if err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(dp.Status.Conditions, dkimmanagerv2.ConditionReady)
+			if cond == nil || cond.Reason != dkimmanagerv2.ReasonInvalid {
+				return fmt.Errorf("DMARCPolicy is not invalid")
+			}
+			return nil
+		}).Should(Succeed())
+
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(de), de)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v1.IsControlledBy(de, dk)).To(BeTrue())
+		endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(endpoints).To(BeEmpty())
+	})
+})
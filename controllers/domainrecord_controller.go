@@ -0,0 +1,317 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// domainRecord is a resource publishing a single TXT record for its domain,
+// such as a DMARCPolicy or a SPFRecord.
+type domainRecord interface {
+	client.Object
+	GetDomain() string
+	GetTTL() uint
+	GetDNSEndpoint() *dkimmanagerv2.DNSEndpointOptions
+	RecordName() string
+	RecordValue() string
+	GetConditions() []v1.Condition
+	SetConditions(conditions []v1.Condition)
+	SetObservedGeneration(generation int64)
+}
+
+// domainRecordReconciler reconciles the domainRecords of a kind. It holds the logic shared by all kinds,
+// while the checks specific to a kind are plugged in as hooks.
+type domainRecordReconciler[T domainRecord] struct {
+	client.Client
+	namespaces []string
+	publisher  publisher.Publisher
+	// kind is the kind of the resources, e.g. DMARCPolicy.
+	kind string
+	// recordType is the type of the published records, e.g. DMARC.
+	recordType string
+	newObject  func() T
+	newList    func() client.ObjectList
+
+	// validate validates the record described by a resource.
+	validate func(o T) error
+	// check runs the checks specific to the kind once the resource has its finalizer, on every reconciliation.
+	// It returns false if reconciliation should not proceed. Optional.
+	check func(ctx context.Context, o T) (ctrl.Result, bool, error)
+	// republish reports whether the record of a resource ready for its generation is published again. Optional.
+	republish func(o T) bool
+	// render returns the value of the record to publish and the result to return once it is published.
+	// It returns false if reconciliation should not proceed. Defaults to the RecordValue of the resource.
+	render func(ctx context.Context, o T) (string, ctrl.Result, bool, error)
+	// published updates the status of a resource once its record is published. Optional.
+	published func(o T)
+}
+
+// Reconcile publishes the record described by a domainRecord.
+func (r *domainRecordReconciler[T]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	o := r.newObject()
+	if err := r.Get(ctx, req.NamespacedName, o); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !o.GetDeletionTimestamp().IsZero() {
+		logger.Info("finalizing")
+		return ctrl.Result{}, r.finalize(ctx, o)
+	}
+
+	if len(r.namespaces) > 0 && !slices.Contains(r.namespaces, o.GetNamespace()) {
+		logger.Info(strings.ToLower(r.kind) + " is in an invalid namespace, ignoring")
+		return ctrl.Result{}, r.markInvalid(ctx, o, r.kind+" is in an invalid namespace")
+	}
+	if err := dkim.ValidateDomain(o.GetDomain()); err != nil {
+		return ctrl.Result{}, r.markInvalid(ctx, o, err.Error())
+	}
+	if err := r.validate(o); err != nil {
+		return ctrl.Result{}, r.markInvalid(ctx, o, err.Error())
+	}
+	if ok, err := r.checkDomainPolicy(ctx, o); !ok {
+		return ctrl.Result{}, err
+	}
+	if ok, err := r.checkRecordConflicts(ctx, o); !ok {
+		return ctrl.Result{}, err
+	}
+	if !controllerutil.ContainsFinalizer(o, finalizerName) {
+		controllerutil.AddFinalizer(o, finalizerName)
+		return ctrl.Result{}, r.Update(ctx, o)
+	}
+	if r.check != nil {
+		if res, ok, err := r.check(ctx, o); !ok {
+			return res, err
+		}
+	}
+
+	if r.isUpToDate(o) && (r.republish == nil || !r.republish(o)) {
+		return ctrl.Result{}, nil
+	}
+
+	value := o.RecordValue()
+	var res ctrl.Result
+	if r.render != nil {
+		var ok bool
+		var err error
+		value, res, ok, err = r.render(ctx, o)
+		if !ok {
+			return res, err
+		}
+	}
+
+	records := []publisher.Record{
+		{
+			Name:    o.RecordName(),
+			TTL:     o.GetTTL(),
+			Targets: []string{value},
+		},
+	}
+	opts := applyDNSEndpointOptions(o.GetDNSEndpoint(), records)
+	if err := r.publisher.Publish(ctx, o, records, opts); err != nil {
+		if errors.Is(err, publisher.ErrRecordSetConflict) {
+			return ctrl.Result{}, r.markInvalid(ctx, o, err.Error())
+		}
+		logger.Error(err, "failed to publish "+r.recordType+" record")
+		r.setCondition(o, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to publish %s record: %v", r.recordType, err))
+		return ctrl.Result{}, r.Status().Update(ctx, o)
+	}
+	logger.Info("done reconciling " + r.kind)
+	if r.published != nil {
+		r.published(o)
+	}
+	r.setCondition(o, v1.ConditionTrue, dkimmanagerv2.ReasonSucceeded, r.recordType+" record published successfully")
+	return res, r.Status().Update(ctx, o)
+}
+
+// isUpToDate returns true if the resource is ready for its current generation.
+func (r *domainRecordReconciler[T]) isUpToDate(o T) bool {
+	cond := meta.FindStatusCondition(o.GetConditions(), dkimmanagerv2.ConditionReady)
+	return cond != nil && cond.Status == v1.ConditionTrue && cond.ObservedGeneration == o.GetGeneration()
+}
+
+// setCondition updates the Ready condition on the resource.
+func (r *domainRecordReconciler[T]) setCondition(o T, status v1.ConditionStatus, reason, message string) {
+	o.SetObservedGeneration(o.GetGeneration())
+	conditions := o.GetConditions()
+	meta.SetStatusCondition(&conditions, v1.Condition{
+		Type:               dkimmanagerv2.ConditionReady,
+		Status:             status,
+		ObservedGeneration: o.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: v1.Now(),
+	})
+	o.SetConditions(conditions)
+}
+
+// markInvalid sets the Ready condition to Invalid with the given message, unless it is already set.
+func (r *domainRecordReconciler[T]) markInvalid(ctx context.Context, o T, message string) error {
+	cond := meta.FindStatusCondition(o.GetConditions(), dkimmanagerv2.ConditionReady)
+	if cond != nil && cond.Reason == dkimmanagerv2.ReasonInvalid && cond.Message == message && cond.ObservedGeneration == o.GetGeneration() {
+		return nil
+	}
+	r.setCondition(o, v1.ConditionFalse, dkimmanagerv2.ReasonInvalid, message)
+	return r.Status().Update(ctx, o)
+}
+
+// checkDomainPolicy marks the resource as invalid if no DKIMDomainPolicy allows its domain, withdrawing
+// the record if it was published. It returns false if reconciliation should not proceed.
+func (r *domainRecordReconciler[T]) checkDomainPolicy(ctx context.Context, o T) (bool, error) {
+	err := policy.CheckDomain(ctx, r.Client, o.GetNamespace(), o.GetDomain())
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, policy.ErrDomainNotAllowed) {
+		return false, err
+	}
+	log.FromContext(ctx).Info("domain is not allowed by policy, ignoring", "domain", o.GetDomain())
+	if controllerutil.ContainsFinalizer(o, finalizerName) {
+		if err := r.publisher.Unpublish(ctx, o, []string{o.RecordName()}); err != nil {
+			return false, err
+		}
+	}
+	return false, r.markInvalid(ctx, o, err.Error())
+}
+
+// checkRecordConflicts marks the resource as invalid if another resource of its kind claimed the same domain first.
+// It returns false if reconciliation should not proceed.
+func (r *domainRecordReconciler[T]) checkRecordConflicts(ctx context.Context, o T) (bool, error) {
+	conflicts, err := r.findConflicts(ctx, o)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conflicts {
+		if !policy.ClaimedBefore(c, o) {
+			continue
+		}
+		log.FromContext(ctx).Info("record is already claimed by another "+r.kind+", ignoring", "record", o.RecordName(), "owner", client.ObjectKeyFromObject(c))
+		return false, r.markInvalid(ctx, o, fmt.Sprintf("record %s is already claimed by %s %s", o.RecordName(), r.kind, client.ObjectKeyFromObject(c)))
+	}
+	return true, nil
+}
+
+// findConflicts returns the other resources of the kind for the domain of the resource.
+func (r *domainRecordReconciler[T]) findConflicts(ctx context.Context, o T) ([]T, error) {
+	return policy.FindDomainConflicts(ctx, r.Client, r.newList(), client.ObjectKeyFromObject(o), o.GetDomain(), func(c T) string {
+		return c.GetDomain()
+	})
+}
+
+func (r *domainRecordReconciler[T]) finalize(ctx context.Context, o T) error {
+	if !controllerutil.ContainsFinalizer(o, finalizerName) {
+		return nil
+	}
+	if err := r.publisher.Unpublish(ctx, o, []string{o.RecordName()}); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("done finalizing")
+	controllerutil.RemoveFinalizer(o, finalizerName)
+	return r.Update(ctx, o)
+}
+
+// recordsForPolicy enqueues all resources of the kind when a DKIMDomainPolicy changes.
+func (r *domainRecordReconciler[T]) recordsForPolicy(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.recordsMatching(ctx, func(T) bool { return true })
+}
+
+// recordsMatching enqueues the resources of the kind listed with opts for which match returns true.
+func (r *domainRecordReconciler[T]) recordsMatching(ctx context.Context, match func(o T) bool, opts ...client.ListOption) []reconcile.Request {
+	list := r.newList()
+	if err := r.List(ctx, list, opts...); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list "+r.kind+" resources")
+		return nil
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to extract "+r.kind+" resources")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for _, item := range items {
+		o, ok := item.(T)
+		if !ok || !match(o) {
+			continue
+		}
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+	}
+	return reqs
+}
+
+// recordsForDomain enqueues the other resources of the domain of a changed resource of the kind,
+// so that a resource blocked by a conflict is reconciled once the conflict is resolved.
+func (r *domainRecordReconciler[T]) recordsForDomain(ctx context.Context, obj client.Object) []reconcile.Request {
+	o, ok := obj.(T)
+	if !ok {
+		return nil
+	}
+	conflicts, err := r.findConflicts(ctx, o)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to find conflicting "+r.kind+" resources")
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(conflicts))
+	for _, c := range conflicts {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(c)})
+	}
+	return reqs
+}
+
+// newControllerManagedBy returns a controller builder watching the resources of the kind and DKIMDomainPolicies.
+// Kinds depending on other resources add their own watches before completing it.
+func (r *domainRecordReconciler[T]) newControllerManagedBy(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(r.newObject()).
+		Watches(r.newObject(), handler.EnqueueRequestsFromMapFunc(r.recordsForDomain)).
+		Watches(&dkimmanagerv2.DKIMDomainPolicy{}, handler.EnqueueRequestsFromMapFunc(r.recordsForPolicy))
+}
+
+// defaultPublisher returns p, or a publisher creating external-dns DNSEndpoints of the given GroupVersionKind
+// if p is nil. The GroupVersionKind defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+func defaultPublisher(p publisher.Publisher, c client.Client, reader client.Reader, scheme *runtime.Scheme, gvk schema.GroupVersionKind) publisher.Publisher {
+	if p != nil {
+		return p
+	}
+	if gvk.Empty() {
+		gvk = externaldns.GroupVersionKind
+	}
+	return publisher.NewExternalDNSPublisher(c, reader, scheme, fieldOwner, gvk)
+}
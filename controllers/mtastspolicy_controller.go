@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/mtasts"
+	"github.com/hsn723/dkim-manager/pkg/policy"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// MTASTSPolicyReconciler reconciles a MTASTSPolicy object.
+// It also implements mtasts.PolicyGetter to serve the policies it publishes.
+type MTASTSPolicyReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// Publisher publishes the MTA-STS records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+
+	records *domainRecordReconciler[*dkimmanagerv2.MTASTSPolicy]
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=mtastspolicies,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=mtastspolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=mtastspolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile publishes the MTA-STS record announcing the policy described by a MTASTSPolicy.
+func (r *MTASTSPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.records.Reconcile(ctx, req)
+}
+
+// GetPolicy returns the policy of the ready MTASTSPolicy of the domain, or nil if there is none.
+// Policies changed since they were last reconciled are not served, as the policy ID published in
+// the TXT record would not match them yet.
+func (r *MTASTSPolicyReconciler) GetPolicy(ctx context.Context, domain string) (*mtasts.Policy, error) {
+	mpl := &dkimmanagerv2.MTASTSPolicyList{}
+	if err := r.List(ctx, mpl); err != nil {
+		return nil, fmt.Errorf("failed to list MTASTSPolicies: %w", err)
+	}
+	for _, mp := range mpl.Items {
+		if !mp.DeletionTimestamp.IsZero() || !mp.IsReady() || mp.Status.ObservedGeneration != mp.Generation {
+			continue
+		}
+		if policy.NormalizeRecordName(mp.Spec.Domain) == policy.NormalizeRecordName(domain) {
+			p := mp.Policy()
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MTASTSPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Publisher = defaultPublisher(r.Publisher, r.Client, r.ReadClient, r.Scheme, r.DNSEndpointGVK)
+	r.records = &domainRecordReconciler[*dkimmanagerv2.MTASTSPolicy]{
+		Client:     r.Client,
+		namespaces: r.Namespaces,
+		publisher:  r.Publisher,
+		kind:       "MTASTSPolicy",
+		recordType: "MTA-STS",
+		newObject:  func() *dkimmanagerv2.MTASTSPolicy { return &dkimmanagerv2.MTASTSPolicy{} },
+		newList:    func() client.ObjectList { return &dkimmanagerv2.MTASTSPolicyList{} },
+		validate: func(mp *dkimmanagerv2.MTASTSPolicy) error {
+			return mp.Policy().Validate()
+		},
+		published: func(mp *dkimmanagerv2.MTASTSPolicy) {
+			mp.Status.PolicyID = mp.Policy().ID()
+		},
+	}
+	return r.records.newControllerManagedBy(mgr).Complete(r)
+}
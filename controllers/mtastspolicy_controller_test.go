@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/mtasts"
+)
+
+var _ = Describe("MTASTSPolicy controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	var reconciler *MTASTSPolicyReconciler
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler = &MTASTSPolicyReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should publish the MTA-STS record and serve the policy", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating MTASTSPolicy")
+		mp := &dkimmanagerv2.MTASTSPolicy{}
+		mp.SetName(name)
+		mp.SetNamespace(namespace)
+		mp.Spec = dkimmanagerv2.MTASTSPolicySpec{
+			Domain: "mta-sts.atelierhsn.com",
+			TTL:    3600,
+			Mode:   mtasts.ModeEnforce,
+			MX:     []string{"mail.atelierhsn.com"},
+			MaxAge: 86400,
+		}
+		err := k8sClient.Create(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name + "-mta-sts"}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			endpoint := endpoints[0].(map[string]interface{})
+			g.Expect(endpoint).To(HaveKeyWithValue("dnsName", "_mta-sts.mta-sts.atelierhsn.com"))
+			g.Expect(endpoint).To(HaveKeyWithValue("recordType", "TXT"))
+			g.Expect(endpoint["targets"]).To(ConsistOf(mp.Policy().GenTXTValue()))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(mp), mp)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mp.IsReady()).To(BeTrue())
+			g.Expect(mp.Status.PolicyID).To(Equal(mp.Policy().ID()))
+		}).Should(Succeed())
+
+		By("fetching the policy")
+		h := mtasts.NewHandler(reconciler)
+		req := httptest.NewRequest(http.MethodGet, mtasts.WellKnownPath, nil)
+		req.Host = mtasts.PolicyHost("mta-sts.atelierhsn.com")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal(mp.Policy().Text()))
+
+		By("changing the mode")
+		oldID := mp.Status.PolicyID
+		mp.Spec.Mode = mtasts.ModeTesting
+		err = k8sClient.Update(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(mp), mp)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mp.Status.ObservedGeneration).To(Equal(mp.Generation))
+			g.Expect(mp.Status.PolicyID).NotTo(Equal(oldID))
+		}).Should(Succeed())
+
+		By("deleting the MTASTSPolicy")
+		err = k8sClient.Delete(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			return getDNSEndpoint(ctx, name+"-mta-sts", namespace)
+		}).ShouldNot(Succeed())
+
+		Eventually(func(g Gomega) {
+			p, err := reconciler.GetPolicy(ctx, "mta-sts.atelierhsn.com")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(p).To(BeNil())
+		}).Should(Succeed())
+	})
+
+	It("should not serve policies changed since they were reconciled", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		mp := &dkimmanagerv2.MTASTSPolicy{}
+		mp.SetName(name)
+		mp.SetNamespace(namespace)
+		mp.Spec = dkimmanagerv2.MTASTSPolicySpec{
+			Domain: "stale.mta-sts.atelierhsn.com",
+			TTL:    3600,
+			Mode:   mtasts.ModeEnforce,
+			MX:     []string{"mail.atelierhsn.com"},
+			MaxAge: 86400,
+		}
+		err := k8sClient.Create(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(mp), mp)).To(Succeed())
+			g.Expect(mp.IsReady()).To(BeTrue())
+		}).Should(Succeed())
+
+		By("changing the mode while the controller is stopped")
+		stopFunc()
+		mp.Spec.Mode = mtasts.ModeTesting
+		err = k8sClient.Update(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+
+		p, err := (&MTASTSPolicyReconciler{Client: k8sClient}).GetPolicy(ctx, "stale.mta-sts.atelierhsn.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+	})
+
+	It("should mark MTASTSPolicies without MX host patterns as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		mp := &dkimmanagerv2.MTASTSPolicy{}
+		mp.SetName(name)
+		mp.SetNamespace(namespace)
+		mp.Spec = dkimmanagerv2.MTASTSPolicySpec{
+			Domain: "invalid.mta-sts.atelierhsn.com",
+			Mode:   mtasts.ModeEnforce,
+		}
+		err := k8sClient.Create(ctx, mp)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(mp), mp)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(mp.Status.Conditions).To(ContainElement(HaveField("Reason", dkimmanagerv2.ReasonInvalid)))
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-mta-sts", namespace)
+		}).ShouldNot(Succeed())
+	})
+})
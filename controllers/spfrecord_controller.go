@@ -0,0 +1,167 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+	"github.com/hsn723/dkim-manager/pkg/spf"
+)
+
+const (
+	defaultSPFRefreshInterval = time.Hour
+	spfRetryInterval          = time.Minute
+)
+
+// SPFRecordReconciler reconciles a SPFRecord object.
+type SPFRecordReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// Publisher publishes the SPF records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
+	// Resolver looks up the included records of flattened SPF records. Defaults to net.DefaultResolver.
+	Resolver spf.Resolver
+	// RefreshInterval is the interval at which flattened SPF records are resolved again. Defaults to an hour.
+	RefreshInterval time.Duration
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+
+	records *domainRecordReconciler[*dkimmanagerv2.SPFRecord]
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=spfrecords,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=spfrecords/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=spfrecords/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile publishes the SPF record described by a SPFRecord, flattening it if requested.
+func (r *SPFRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.records.Reconcile(ctx, req)
+}
+
+// render flattens the SPF record of the SPFRecord if requested and records the number of DNS lookups it requires,
+// including those of the included records. It returns false if reconciliation should not proceed.
+func (r *SPFRecordReconciler) render(ctx context.Context, sr *dkimmanagerv2.SPFRecord) (string, ctrl.Result, bool, error) {
+	record := sr.Record()
+	var requeueAfter time.Duration
+	if sr.Spec.Flatten {
+		flattened, err := spf.Flatten(ctx, r.Resolver, record)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to flatten SPF record")
+			message := fmt.Sprintf("Failed to flatten SPF record: %v", err)
+			retryAfter := r.retryInterval(sr)
+			// The record published for the current generation is still served, so a failed refresh only
+			// reports the failure, keeping the SPFRecord ready.
+			if !r.records.isUpToDate(sr) {
+				r.records.setCondition(sr, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, message)
+			}
+			r.setRefreshedCondition(sr, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, message)
+			return "", ctrl.Result{RequeueAfter: retryAfter}, false, r.Status().Update(ctx, sr)
+		}
+		record = flattened
+		requeueAfter = r.RefreshInterval
+		r.setRefreshedCondition(sr, v1.ConditionTrue, dkimmanagerv2.ReasonSucceeded, "SPF record flattened successfully")
+	} else {
+		meta.RemoveStatusCondition(&sr.Status.Conditions, dkimmanagerv2.ConditionRefreshed)
+	}
+	// Records exceeding the limit by themselves are rejected without looking up the included records.
+	lookups := record.Lookups()
+	if lookups <= spf.MaxLookups {
+		var err error
+		lookups, err = spf.CountLookups(ctx, r.Resolver, record)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to count SPF lookups")
+			r.records.setCondition(sr, v1.ConditionFalse, dkimmanagerv2.ReasonFailed, fmt.Sprintf("Failed to count the DNS lookups of the SPF record: %v", err))
+			return "", ctrl.Result{RequeueAfter: spfRetryInterval}, false, r.Status().Update(ctx, sr)
+		}
+	}
+	if lookups > spf.MaxLookups {
+		return "", ctrl.Result{}, false, r.records.markInvalid(ctx, sr, fmt.Sprintf("SPF record requires %d DNS lookups, exceeding the limit of %d", lookups, spf.MaxLookups))
+	}
+	sr.Status.Lookups = lookups
+	return record.GenTXTValue(), ctrl.Result{RequeueAfter: requeueAfter}, true, nil
+}
+
+// retryInterval returns the interval after which a SPF record that failed to be flattened is flattened again.
+// It starts at spfRetryInterval and grows with the time the refresh has been failing, up to the refresh interval.
+func (r *SPFRecordReconciler) retryInterval(sr *dkimmanagerv2.SPFRecord) time.Duration {
+	interval := spfRetryInterval
+	cond := meta.FindStatusCondition(sr.Status.Conditions, dkimmanagerv2.ConditionRefreshed)
+	if cond != nil && cond.Status == v1.ConditionFalse {
+		interval = max(interval, time.Since(cond.LastTransitionTime.Time))
+	}
+	return min(interval, r.RefreshInterval)
+}
+
+// setRefreshedCondition updates the Refreshed condition on the SPFRecord.
+func (r *SPFRecordReconciler) setRefreshedCondition(sr *dkimmanagerv2.SPFRecord, status v1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&sr.Status.Conditions, v1.Condition{
+		Type:               dkimmanagerv2.ConditionRefreshed,
+		Status:             status,
+		ObservedGeneration: sr.Generation,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: v1.Now(),
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SPFRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Publisher = defaultPublisher(r.Publisher, r.Client, r.ReadClient, r.Scheme, r.DNSEndpointGVK)
+	if r.Resolver == nil {
+		r.Resolver = net.DefaultResolver
+	}
+	if r.RefreshInterval == 0 {
+		r.RefreshInterval = defaultSPFRefreshInterval
+	}
+	r.records = &domainRecordReconciler[*dkimmanagerv2.SPFRecord]{
+		Client:     r.Client,
+		namespaces: r.Namespaces,
+		publisher:  r.Publisher,
+		kind:       "SPFRecord",
+		recordType: "SPF",
+		newObject:  func() *dkimmanagerv2.SPFRecord { return &dkimmanagerv2.SPFRecord{} },
+		newList:    func() client.ObjectList { return &dkimmanagerv2.SPFRecordList{} },
+		validate: func(sr *dkimmanagerv2.SPFRecord) error {
+			return sr.Record().Validate()
+		},
+		// Flattened records are resolved again on every reconciliation, as the included records change over time.
+		republish: func(sr *dkimmanagerv2.SPFRecord) bool {
+			return sr.Spec.Flatten
+		},
+		render: r.render,
+	}
+	return r.records.newControllerManagedBy(mgr).Complete(r)
+}
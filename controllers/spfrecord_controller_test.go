@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+	"github.com/hsn723/dkim-manager/pkg/spf"
+)
+
+// txtResolver answers SPF lookups from in-memory TXT records.
+type txtResolver map[string][]string
+
+func (r txtResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	values, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	return values, nil
+}
+
+func (r txtResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func (r txtResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	return nil, fmt.Errorf("no such host %s", name)
+}
+
+// flakyResolver answers SPF lookups from in-memory TXT records, failing TXT lookups while failing is set.
+type flakyResolver struct {
+	txtResolver
+	failing atomic.Bool
+}
+
+func (r *flakyResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if r.failing.Load() {
+		return nil, fmt.Errorf("temporary failure looking up %s", name)
+	}
+	return r.txtResolver.LookupTXT(ctx, name)
+}
+
+func spfTargets(ctx context.Context, g Gomega, name, namespace string) []interface{} {
+	de := externaldns.DNSEndpoint()
+	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, de)
+	g.Expect(err).NotTo(HaveOccurred())
+	endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoints).To(HaveLen(1))
+	return endpoints[0].(map[string]interface{})["targets"].([]interface{})
+}
+
+var _ = Describe("SPFRecord controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	var resolver *flakyResolver
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		resolver = &flakyResolver{
+			txtResolver: txtResolver{
+				"_spf.example.net":  {"v=spf1 include:_nets.example.net ~all"},
+				"_nets.example.net": {"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 -all"},
+				"_deep.example.net": {"v=spf1" + strings.Repeat(" include:_nets.example.net", spf.MaxLookups) + " -all"},
+			},
+		}
+		reconciler := &SPFRecordReconciler{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			ReadClient:      mgr.GetAPIReader(),
+			Resolver:        resolver,
+			RefreshInterval: time.Second,
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should publish the SPF record as a DNSEndpoint", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating SPFRecord")
+		sr := &dkimmanagerv2.SPFRecord{}
+		sr.SetName(name)
+		sr.SetNamespace(namespace)
+		sr.Spec = dkimmanagerv2.SPFRecordSpec{
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			Mechanisms: []string{"mx"},
+			Includes:   []string{"_spf.example.net"},
+			All:        spf.AllFail,
+		}
+		err := k8sClient.Create(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			g.Expect(spfTargets(ctx, g, name+"-spf", namespace)).To(ConsistOf(`"v=spf1 mx include:_spf.example.net -all"`))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sr.IsReady()).To(BeTrue())
+			g.Expect(sr.Status.Lookups).To(Equal(3), "the lookups of included records must be counted")
+		}).Should(Succeed())
+
+		By("flattening the includes")
+		sr.Spec.Flatten = true
+		err = k8sClient.Update(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			g.Expect(spfTargets(ctx, g, name+"-spf", namespace)).To(ConsistOf(`"v=spf1 mx ip4:192.0.2.0/24 ip6:2001:db8::/32 -all"`))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sr.Status.Lookups).To(Equal(1))
+		}).Should(Succeed())
+	})
+
+	It("should keep flattened SPF records ready when a refresh fails", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		sr := &dkimmanagerv2.SPFRecord{}
+		sr.SetName(name)
+		sr.SetNamespace(namespace)
+		sr.Spec = dkimmanagerv2.SPFRecordSpec{
+			Domain:   "atelierhsn.com",
+			TTL:      3600,
+			Includes: []string{"_spf.example.net"},
+			All:      spf.AllFail,
+			Flatten:  true,
+		}
+		err := k8sClient.Create(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sr.IsReady()).To(BeTrue())
+			g.Expect(meta.IsStatusConditionTrue(sr.Status.Conditions, dkimmanagerv2.ConditionRefreshed)).To(BeTrue())
+		}).Should(Succeed())
+
+		By("failing DNS lookups")
+		resolver.failing.Store(true)
+		defer resolver.failing.Store(false)
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			cond := meta.FindStatusCondition(sr.Status.Conditions, dkimmanagerv2.ConditionRefreshed)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Status).To(Equal(v1.ConditionFalse))
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonFailed))
+		}).Should(Succeed())
+		Expect(sr.IsReady()).To(BeTrue())
+		Eventually(func(g Gomega) {
+			g.Expect(spfTargets(ctx, g, name+"-spf", namespace)).To(ConsistOf(`"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 -all"`))
+		}).Should(Succeed())
+
+		By("recovering DNS lookups")
+		resolver.failing.Store(false)
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(meta.IsStatusConditionTrue(sr.Status.Conditions, dkimmanagerv2.ConditionRefreshed)).To(BeTrue())
+			g.Expect(sr.IsReady()).To(BeTrue())
+		}).Should(Succeed())
+	})
+
+	It("should report includes that cannot be flattened", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		sr := &dkimmanagerv2.SPFRecord{}
+		sr.SetName(name)
+		sr.SetNamespace(namespace)
+		sr.Spec = dkimmanagerv2.SPFRecordSpec{
+			Domain:   "atelierhsn.com",
+			TTL:      3600,
+			Includes: []string{"_missing.example.net"},
+			All:      spf.AllSoftFail,
+			Flatten:  true,
+		}
+		err := k8sClient.Create(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			cond := meta.FindStatusCondition(sr.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonFailed))
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-spf", namespace)
+		}).ShouldNot(Succeed())
+	})
+
+	It("should mark SPFRecords exceeding the DNS lookup limit as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		sr := &dkimmanagerv2.SPFRecord{}
+		sr.SetName(name)
+		sr.SetNamespace(namespace)
+		sr.Spec = dkimmanagerv2.SPFRecordSpec{
+			Domain: "atelierhsn.com",
+			TTL:    3600,
+			All:    spf.AllSoftFail,
+		}
+		for i := range spf.MaxLookups + 1 {
+			sr.Spec.Includes = append(sr.Spec.Includes, fmt.Sprintf("_spf%d.example.net", i))
+		}
+		err := k8sClient.Create(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			cond := meta.FindStatusCondition(sr.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+		}).Should(Succeed())
+	})
+
+	It("should mark SPFRecords whose included records exceed the DNS lookup limit as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		sr := &dkimmanagerv2.SPFRecord{}
+		sr.SetName(name)
+		sr.SetNamespace(namespace)
+		sr.Spec = dkimmanagerv2.SPFRecordSpec{
+			Domain:   "atelierhsn.com",
+			TTL:      3600,
+			Includes: []string{"_deep.example.net"},
+			All:      spf.AllSoftFail,
+		}
+		err := k8sClient.Create(ctx, sr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(sr), sr)
+			g.Expect(err).NotTo(HaveOccurred())
+			cond := meta.FindStatusCondition(sr.Status.Conditions, dkimmanagerv2.ConditionReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).To(Equal(dkimmanagerv2.ReasonInvalid))
+		}).Should(Succeed())
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-spf", namespace)
+		}).ShouldNot(Succeed())
+	})
+})
@@ -0,0 +1,74 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/publisher"
+)
+
+// TLSRPTRecordReconciler reconciles a TLSRPTRecord object.
+type TLSRPTRecordReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Namespaces []string
+	// Publisher publishes the TLS-RPT records. Defaults to creating external-dns DNSEndpoints.
+	Publisher publisher.Publisher
+	// DNSEndpointGVK is the GroupVersionKind of the DNSEndpoints created by the default publisher.
+	// Defaults to externaldns.k8s.io/v1alpha1 DNSEndpoint.
+	DNSEndpointGVK schema.GroupVersionKind
+	// workaround for https://github.com/kubernetes-sigs/controller-runtime/issues/550
+	ReadClient client.Reader
+
+	records *domainRecordReconciler[*dkimmanagerv2.TLSRPTRecord]
+}
+
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=tlsrptrecords,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=tlsrptrecords/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=tlsrptrecords/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dkim-manager.atelierhsn.com,resources=dkimdomainpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile publishes the TLS-RPT record described by a TLSRPTRecord.
+func (r *TLSRPTRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.records.Reconcile(ctx, req)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TLSRPTRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Publisher = defaultPublisher(r.Publisher, r.Client, r.ReadClient, r.Scheme, r.DNSEndpointGVK)
+	r.records = &domainRecordReconciler[*dkimmanagerv2.TLSRPTRecord]{
+		Client:     r.Client,
+		namespaces: r.Namespaces,
+		publisher:  r.Publisher,
+		kind:       "TLSRPTRecord",
+		recordType: "TLS-RPT",
+		newObject:  func() *dkimmanagerv2.TLSRPTRecord { return &dkimmanagerv2.TLSRPTRecord{} },
+		newList:    func() client.ObjectList { return &dkimmanagerv2.TLSRPTRecordList{} },
+		validate: func(tr *dkimmanagerv2.TLSRPTRecord) error {
+			return tr.Record().Validate()
+		},
+	}
+	return r.records.newControllerManagedBy(mgr).Complete(r)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+var _ = Describe("TLSRPTRecord controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconciler := &TLSRPTRecordReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ReadClient: mgr.GetAPIReader(),
+		}
+		err = reconciler.SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should publish the TLS-RPT record as a DNSEndpoint", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating TLSRPTRecord")
+		tr := &dkimmanagerv2.TLSRPTRecord{}
+		tr.SetName(name)
+		tr.SetNamespace(namespace)
+		tr.Spec = dkimmanagerv2.TLSRPTRecordSpec{
+			Domain:     "atelierhsn.com",
+			TTL:        3600,
+			ReportURIs: []string{"mailto:tlsrpt@atelierhsn.com"},
+		}
+		err := k8sClient.Create(ctx, tr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			de := externaldns.DNSEndpoint()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name + "-tlsrpt"}, de)
+			g.Expect(err).NotTo(HaveOccurred())
+			endpoints, _, err := unstructured.NestedSlice(de.UnstructuredContent(), "spec", "endpoints")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(endpoints).To(HaveLen(1))
+			endpoint := endpoints[0].(map[string]interface{})
+			g.Expect(endpoint).To(HaveKeyWithValue("dnsName", "_smtp._tls.atelierhsn.com"))
+			g.Expect(endpoint).To(HaveKeyWithValue("recordType", "TXT"))
+			g.Expect(endpoint["targets"]).To(ConsistOf(`"v=TLSRPTv1; rua=mailto:tlsrpt@atelierhsn.com;"`))
+		}).Should(Succeed())
+
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tr), tr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tr.IsReady()).To(BeTrue())
+	})
+
+	It("should mark TLSRPTRecords with invalid report URIs as invalid", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		tr := &dkimmanagerv2.TLSRPTRecord{}
+		tr.SetName(name)
+		tr.SetNamespace(namespace)
+		tr.Spec = dkimmanagerv2.TLSRPTRecordSpec{
+			Domain:     "tlsrpt.atelierhsn.com",
+			ReportURIs: []string{"http://reports.atelierhsn.com/"},
+		}
+		err := k8sClient.Create(ctx, tr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tr), tr)
+			if err != nil {
+				return err
+			}
+			cond := meta.FindStatusCondition(tr.Status.Conditions, dkimmanagerv2.ConditionReady)
+			if cond == nil || cond.Reason != dkimmanagerv2.ReasonInvalid {
+				return fmt.Errorf("TLSRPTRecord is not invalid")
+			}
+			return nil
+		}).Should(Succeed())
+
+		Consistently(func() error {
+			return getDNSEndpoint(ctx, name+"-tlsrpt", namespace)
+		}).ShouldNot(Succeed())
+	})
+})
@@ -15,7 +15,7 @@ import (
 func dummyV1DKIMKeySpec(name string) dkimmanagerv1.DKIMKeySpec {
 	return dkimmanagerv1.DKIMKeySpec{
 		SecretName: name,
-		Selector:   "selector1",
+		Selector:   name,
 		Domain:     "atelierhsn.com",
 		TTL:        3600,
 		KeyLength:  dkim.KeyLength2048,
@@ -26,7 +26,7 @@ func dummyV1DKIMKeySpec(name string) dkimmanagerv1.DKIMKeySpec {
 func dummyDKIMKeySpec(name string) dkimmanagerv2.DKIMKeySpec {
 	return dkimmanagerv2.DKIMKeySpec{
 		SecretName: name,
-		Selector:   "selector1",
+		Selector:   name,
 		Domain:     "atelierhsn.com",
 		TTL:        3600,
 		KeyLength:  dkim.KeyLength2048,
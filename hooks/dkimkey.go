@@ -2,11 +2,21 @@ package hooks
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -14,6 +24,8 @@ import (
 
 	dkimmanagerv1 "github.com/hsn723/dkim-manager/api/v1"
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/policy"
 )
 
 const (
@@ -32,10 +44,245 @@ func isDKIMKeyOwner(owner v1.OwnerReference) bool {
 	return gv.Group == apiGroup
 }
 
-//+kubebuilder:webhook:path=/validate-dkim-manager-atelierhsn-com-v1-dkimkey,mutating=false,failurePolicy=fail,sideEffects=None,groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=update,versions=v1,name=vdkimkey.kb.io,admissionReviewVersions={v1}
+// DKIMKeyValidatorOptions configures the semantic checks performed on DKIMKeys.
+type DKIMKeyValidatorOptions struct {
+	// MinTTL is the lowest allowed record TTL. Zero disables the lower bound.
+	MinTTL uint
+	// MaxTTL is the highest allowed record TTL. Zero disables the upper bound.
+	MaxTTL uint
+	// KeyPolicy is the organization-wide policy new keys must comply with.
+	KeyPolicy policy.KeyPolicy
+}
 
-type dkimKeyValidator struct {
+// dkimKeyChecker performs the checks shared by all DKIMKey versions on the hub representation.
+type dkimKeyChecker struct {
 	client.Client
+	// reader is used for resources the manager does not cache, such as Secrets.
+	reader client.Reader
+	opts   DKIMKeyValidatorOptions
+}
+
+// ValidateDKIMKey checks the parts of a new DKIMKey that do not depend on the state of the cluster,
+// such as its domain, selectors, record tags and secret name.
+func ValidateDKIMKey(dk *dkimmanagerv2.DKIMKey) error {
+	if err := dkim.ValidateDomain(dk.Spec.Domain); err != nil {
+		return err
+	}
+	for _, k := range dk.Keys() {
+		if err := dkim.ValidateSelector(k.Selector); err != nil {
+			return err
+		}
+		if err := dkim.ValidateRecordName(k.Selector, k.Domain); err != nil {
+			return err
+		}
+		if k.DelegationZone == "" {
+			continue
+		}
+		if err := dkim.ValidateDelegatedRecordName(k.Selector, k.Domain, k.DelegationZone); err != nil {
+			return err
+		}
+	}
+	for _, check := range []func(*dkimmanagerv2.DKIMKey) admission.Response{checkED25519Selector, checkDNSEndpoint, checkRecordTags} {
+		if res := check(dk); !res.Allowed {
+			return errors.New(res.Result.Message)
+		}
+	}
+	if errs := validation.IsDNS1123Subdomain(dk.Spec.SecretName); len(errs) > 0 {
+		return fmt.Errorf("invalid secret name %q: %s", dk.Spec.SecretName, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (c *dkimKeyChecker) validateCreate(ctx context.Context, namespace string, dk *dkimmanagerv2.DKIMKey) admission.Response {
+	if err := ValidateDKIMKey(dk); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if res := c.checkTTL(dk.Spec.TTL); !res.Allowed {
+		return res
+	}
+	if err := c.opts.KeyPolicy.ForPurpose(dk.Spec.Purpose).CheckDKIMKey(dk); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if res := c.checkSecret(ctx, namespace, dk); !res.Allowed {
+		return res
+	}
+	if res := c.checkDomainPolicy(ctx, namespace, dk.Spec.Domain); !res.Allowed {
+		return res
+	}
+	return c.checkRecordConflicts(ctx, client.ObjectKey{Namespace: namespace, Name: dk.Name}, dk)
+}
+
+// validateUpdate validates a DKIMKey being updated from old. The TTL bounds are only checked when the
+// TTL changes, so that keys created before the bounds were set can still be updated.
+func (c *dkimKeyChecker) validateUpdate(ctx context.Context, namespace string, dk, old *dkimmanagerv2.DKIMKey) admission.Response {
+	if !dk.DeletionTimestamp.IsZero() {
+		return admission.Allowed("")
+	}
+	if dk.Spec.TTL != old.Spec.TTL {
+		if res := c.checkTTL(dk.Spec.TTL); !res.Allowed {
+			return res
+		}
+	}
+	if res := checkDNSEndpoint(dk); !res.Allowed {
+		return res
+	}
+	if res := checkRecordTags(dk); !res.Allowed {
+		return res
+	}
+	return c.checkDomainPolicy(ctx, namespace, dk.Spec.Domain)
+}
+
+// checkED25519Selector ensures that a sibling Ed25519 key is only requested alongside an RSA key,
+// under a different selector.
+func checkED25519Selector(dk *dkimmanagerv2.DKIMKey) admission.Response {
+	if dk.Spec.ED25519Selector == "" {
+		return admission.Allowed("")
+	}
+	if dk.Spec.KeyType != dkim.KeyTypeRSA {
+		return admission.Denied("ed25519Selector can only be set for rsa keys")
+	}
+	if strings.EqualFold(dk.Spec.ED25519Selector, dk.Spec.Selector) {
+		return admission.Denied("ed25519Selector must differ from selector")
+	}
+	return admission.Allowed("")
+}
+
+// delegationZone returns the zone the DKIM records are delegated to, or an empty string.
+func delegationZone(dk *dkimmanagerv2.DKIMKey) string {
+	if dk.Spec.Delegation == nil {
+		return ""
+	}
+	return dk.Spec.Delegation.Zone
+}
+
+// checkDNSEndpoint validates the labels, annotations and provider-specific properties
+// to set on the DNSEndpoint.
+func checkDNSEndpoint(dk *dkimmanagerv2.DKIMKey) admission.Response {
+	de := dk.Spec.DNSEndpoint
+	if de == nil {
+		return admission.Allowed("")
+	}
+	path := field.NewPath("spec", "dnsEndpoint")
+	errs := metav1validation.ValidateLabels(de.Labels, path.Child("labels"))
+	errs = append(errs, apivalidation.ValidateAnnotations(de.Annotations, path.Child("annotations"))...)
+	names := map[string]bool{}
+	for i, prop := range de.ProviderSpecific {
+		p := path.Child("providerSpecific").Index(i).Child("name")
+		if prop.Name == "" {
+			errs = append(errs, field.Required(p, ""))
+		} else if names[prop.Name] {
+			errs = append(errs, field.Duplicate(p, prop.Name))
+		}
+		names[prop.Name] = true
+	}
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+var (
+	allowedHashAlgorithms = []string{"sha1", "sha256"}
+	allowedServiceTypes   = []string{"email", "*"}
+)
+
+// checkRecordTags validates the optional tags of the DKIM records. The CRD schema already
+// restricts their values, but v1 resources carry them in an annotation.
+func checkRecordTags(dk *dkimmanagerv2.DKIMKey) admission.Response {
+	tags := dk.Spec.Tags
+	if tags == nil {
+		return admission.Allowed("")
+	}
+	path := field.NewPath("spec", "tags")
+	errs := checkTagValues(path.Child("hashAlgorithms"), tags.HashAlgorithms, allowedHashAlgorithms)
+	errs = append(errs, checkTagValues(path.Child("serviceTypes"), tags.ServiceTypes, allowedServiceTypes)...)
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+func checkTagValues(path *field.Path, values, allowed []string) field.ErrorList {
+	var errs field.ErrorList
+	seen := map[string]bool{}
+	for i, v := range values {
+		switch {
+		case !slices.Contains(allowed, v):
+			errs = append(errs, field.NotSupported(path.Index(i), v, allowed))
+		case seen[v]:
+			errs = append(errs, field.Duplicate(path.Index(i), v))
+		}
+		seen[v] = true
+	}
+	return errs
+}
+
+func (c *dkimKeyChecker) checkTTL(ttl uint) admission.Response {
+	if c.opts.MinTTL > 0 && ttl < c.opts.MinTTL {
+		return admission.Denied(fmt.Sprintf("ttl %d is lower than the minimum of %d", ttl, c.opts.MinTTL))
+	}
+	if c.opts.MaxTTL > 0 && ttl > c.opts.MaxTTL {
+		return admission.Denied(fmt.Sprintf("ttl %d is higher than the maximum of %d", ttl, c.opts.MaxTTL))
+	}
+	return admission.Allowed("")
+}
+
+// checkSecret denies using a Secret that already exists, unless it belongs to this DKIMKey, or opts
+// in to being adopted and contains all the expected private keys.
+func (c *dkimKeyChecker) checkSecret(ctx context.Context, namespace string, dk *dkimmanagerv2.DKIMKey) admission.Response {
+	s := &corev1.Secret{}
+	err := c.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: dk.Spec.SecretName}, s)
+	if apierrors.IsNotFound(err) {
+		return admission.Allowed("")
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, owner := range s.GetOwnerReferences() {
+		if isDKIMKeyOwner(owner) && owner.Name == dk.Name {
+			return admission.Allowed("")
+		}
+	}
+	if s.Annotations[dkimmanagerv2.AdoptSecretAnnotation] != "true" {
+		return admission.Denied(fmt.Sprintf("secret %s already exists and is not owned by this dkimkey", dk.Spec.SecretName))
+	}
+	if owner := v1.GetControllerOf(s); owner != nil {
+		return admission.Denied(fmt.Sprintf("secret %s is already controlled by %s %s", dk.Spec.SecretName, owner.Kind, owner.Name))
+	}
+	for _, k := range dk.Keys() {
+		if _, ok := s.Data[k.PrivateKeyFilename()]; !ok {
+			return admission.Denied(fmt.Sprintf("secret %s does not contain a private key for %s", dk.Spec.SecretName, k.RecordName()))
+		}
+	}
+	return admission.Allowed("")
+}
+
+func (c *dkimKeyChecker) checkDomainPolicy(ctx context.Context, namespace, domain string) admission.Response {
+	if err := policy.CheckDomain(ctx, c.Client, namespace, domain); err != nil {
+		if errors.Is(err, policy.ErrDomainNotAllowed) {
+			return admission.Denied(err.Error())
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.Allowed("")
+}
+
+func (c *dkimKeyChecker) checkRecordConflicts(ctx context.Context, key client.ObjectKey, dk *dkimmanagerv2.DKIMKey) admission.Response {
+	conflicts, err := policy.FindRecordConflicts(ctx, c.Client, key, dk.RecordNames())
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(conflicts) > 0 {
+		recordName := policy.SharedRecordName(dk, &conflicts[0])
+		return admission.Denied(fmt.Sprintf("record %s is already claimed by DKIMKey %s", recordName, client.ObjectKeyFromObject(&conflicts[0])))
+	}
+	return admission.Allowed("")
+}
+
+//+kubebuilder:webhook:path=/validate-dkim-manager-atelierhsn-com-v1-dkimkey,mutating=false,failurePolicy=fail,sideEffects=None,groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=create;update,versions=v1,name=vdkimkey.kb.io,admissionReviewVersions={v1}
+
+type dkimKeyValidator struct {
+	dkimKeyChecker
 	dec *admission.Decoder
 }
 
@@ -44,14 +291,28 @@ var _ admission.Handler = &dkimKeyValidator{}
 // Handle validates DKIMKeys.
 func (v *dkimKeyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	switch req.Operation {
+	case admissionv1.Create:
+		return v.handleCreate(ctx, req)
 	case admissionv1.Update:
-		return v.handleUpdate(req)
+		return v.handleUpdate(ctx, req)
 	default:
 		return admission.Allowed("")
 	}
 }
 
-func (v *dkimKeyValidator) handleUpdate(req admission.Request) admission.Response {
+func (v *dkimKeyValidator) handleCreate(ctx context.Context, req admission.Request) admission.Response {
+	dk := &dkimmanagerv1.DKIMKey{}
+	if err := (*v.dec).Decode(req, dk); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	hub := &dkimmanagerv2.DKIMKey{}
+	if err := dk.ConvertTo(hub); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	return v.validateCreate(ctx, req.Namespace, hub)
+}
+
+func (v *dkimKeyValidator) handleUpdate(ctx context.Context, req admission.Request) admission.Response {
 	dkNew := &dkimmanagerv1.DKIMKey{}
 	decoder := *v.dec
 	if err := decoder.Decode(req, dkNew); err != nil {
@@ -79,22 +340,46 @@ func (v *dkimKeyValidator) handleUpdate(req admission.Request) admission.Respons
 	if dkNew.Spec.Selector != dkOld.Spec.Selector {
 		return admission.Denied("changing dkimkey selector is not allowed")
 	}
-	return admission.Allowed("")
+	hub := &dkimmanagerv2.DKIMKey{}
+	if err := dkNew.ConvertTo(hub); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	hubOld := &dkimmanagerv2.DKIMKey{}
+	if err := dkOld.ConvertTo(hubOld); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if hub.Spec.ED25519Selector != hubOld.Spec.ED25519Selector {
+		return admission.Denied("changing dkimkey ed25519 selector is not allowed")
+	}
+	if hub.IsARC() != hubOld.IsARC() {
+		return admission.Denied("changing dkimkey purpose is not allowed")
+	}
+	if hubOld.Spec.Revoked && !hub.Spec.Revoked {
+		return admission.Denied("un-revoking a dkimkey is not allowed")
+	}
+	if delegationZone(hub) != delegationZone(hubOld) {
+		return admission.Denied("changing dkimkey delegation zone is not allowed")
+	}
+	return v.validateUpdate(ctx, req.Namespace, hub, hubOld)
 }
 
-func SetupDKIMKeyWebhook(mgr manager.Manager, dec *admission.Decoder) {
+func SetupDKIMKeyWebhook(mgr manager.Manager, dec *admission.Decoder, opts DKIMKeyValidatorOptions) {
 	v := &dkimKeyValidator{
-		Client: mgr.GetClient(),
-		dec:    dec,
+		dkimKeyChecker: dkimKeyChecker{
+			Client: mgr.GetClient(),
+			reader: mgr.GetAPIReader(),
+			opts:   opts,
+		},
+		dec: dec,
 	}
 	srv := mgr.GetWebhookServer()
 	srv.Register("/validate-dkim-manager-atelierhsn-com-v1-dkimkey", &webhook.Admission{Handler: v})
 }
 
-//+kubebuilder:webhook:path=/validate-dkim-manager-atelierhsn-com-v2-dkimkey,mutating=false,failurePolicy=fail,sideEffects=None,groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=update,versions=v2,name=vdkimkeyv2.kb.io,admissionReviewVersions={v1}
+//+kubebuilder:webhook:path=/validate-dkim-manager-atelierhsn-com-v2-dkimkey,mutating=false,failurePolicy=fail,sideEffects=None,groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=create;update,versions=v2,name=vdkimkeyv2.kb.io,admissionReviewVersions={v1}
 
 type dkimKeyV2Validator struct {
-	client.Client
+	dkimKeyChecker
 	dec *admission.Decoder
 }
 
@@ -103,14 +388,24 @@ var _ admission.Handler = &dkimKeyV2Validator{}
 // Handle validates v2 DKIMKeys.
 func (v *dkimKeyV2Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	switch req.Operation {
+	case admissionv1.Create:
+		return v.handleCreate(ctx, req)
 	case admissionv1.Update:
-		return v.handleUpdate(req)
+		return v.handleUpdate(ctx, req)
 	default:
 		return admission.Allowed("")
 	}
 }
 
-func (v *dkimKeyV2Validator) handleUpdate(req admission.Request) admission.Response {
+func (v *dkimKeyV2Validator) handleCreate(ctx context.Context, req admission.Request) admission.Response {
+	dk := &dkimmanagerv2.DKIMKey{}
+	if err := (*v.dec).Decode(req, dk); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	return v.validateCreate(ctx, req.Namespace, dk)
+}
+
+func (v *dkimKeyV2Validator) handleUpdate(ctx context.Context, req admission.Request) admission.Response {
 	dkNew := &dkimmanagerv2.DKIMKey{}
 	decoder := *v.dec
 	if err := decoder.Decode(req, dkNew); err != nil {
@@ -138,13 +433,29 @@ func (v *dkimKeyV2Validator) handleUpdate(req admission.Request) admission.Respo
 	if dkNew.Spec.Selector != dkOld.Spec.Selector {
 		return admission.Denied("changing dkimkey selector is not allowed")
 	}
-	return admission.Allowed("")
+	if dkNew.Spec.ED25519Selector != dkOld.Spec.ED25519Selector {
+		return admission.Denied("changing dkimkey ed25519 selector is not allowed")
+	}
+	if dkNew.IsARC() != dkOld.IsARC() {
+		return admission.Denied("changing dkimkey purpose is not allowed")
+	}
+	if dkOld.Spec.Revoked && !dkNew.Spec.Revoked {
+		return admission.Denied("un-revoking a dkimkey is not allowed")
+	}
+	if delegationZone(dkNew) != delegationZone(dkOld) {
+		return admission.Denied("changing dkimkey delegation zone is not allowed")
+	}
+	return v.validateUpdate(ctx, req.Namespace, dkNew, dkOld)
 }
 
-func SetupDKIMKeyV2Webhook(mgr manager.Manager, dec *admission.Decoder) {
+func SetupDKIMKeyV2Webhook(mgr manager.Manager, dec *admission.Decoder, opts DKIMKeyValidatorOptions) {
 	v := &dkimKeyV2Validator{
-		Client: mgr.GetClient(),
-		dec:    dec,
+		dkimKeyChecker: dkimKeyChecker{
+			Client: mgr.GetClient(),
+			reader: mgr.GetAPIReader(),
+			opts:   opts,
+		},
+		dec: dec,
 	}
 	srv := mgr.GetWebhookServer()
 	srv.Register("/validate-dkim-manager-atelierhsn-com-v2-dkimkey", &webhook.Admission{Handler: v})
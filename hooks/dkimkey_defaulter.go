@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+const nameTemplateDateFormat = "20060102"
+
+// DKIMKeyDefaulterOptions configures the defaults applied to DKIMKeys.
+type DKIMKeyDefaulterOptions struct {
+	// SelectorTemplate generates the selector when it is omitted. Nil disables defaulting.
+	SelectorTemplate *template.Template
+	// ARCSelectorTemplate generates the selector of ARC keys when it is omitted. Nil falls back to SelectorTemplate.
+	ARCSelectorTemplate *template.Template
+	// SecretNameTemplate generates the secret name when it is omitted. Nil disables defaulting.
+	SecretNameTemplate *template.Template
+}
+
+// NameTemplateData is the data available to selector and secret name templates.
+type NameTemplateData struct {
+	// Name is the name of the DKIMKey.
+	Name string
+	// Namespace is the namespace of the DKIMKey.
+	Namespace string
+	// Domain is the normalized domain of the DKIMKey.
+	Domain string
+	// Date is the current UTC date in YYYYMMDD format.
+	Date string
+	// Purpose is the purpose of the DKIMKey, dkim or arc.
+	Purpose string
+}
+
+// ParseNameTemplate parses a selector or secret name template. An empty text returns a nil template.
+func ParseNameTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+func executeNameTemplate(tmpl *template.Template, data NameTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+//+kubebuilder:webhook:path=/mutate-dkim-manager-atelierhsn-com-v2-dkimkey,mutating=true,failurePolicy=fail,sideEffects=None,groups=dkim-manager.atelierhsn.com,resources=dkimkeys,verbs=create;update,versions=v2,name=mdkimkeyv2.kb.io,admissionReviewVersions={v1}
+
+type dkimKeyV2Defaulter struct {
+	dec  *admission.Decoder
+	opts DKIMKeyDefaulterOptions
+}
+
+var _ admission.Handler = &dkimKeyV2Defaulter{}
+
+// Handle fills in omitted fields and normalizes v2 DKIMKeys.
+func (d *dkimKeyV2Defaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	dk := &dkimmanagerv2.DKIMKey{}
+	decoder := *d.dec
+	if err := decoder.Decode(req, dk); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := d.defaultCreate(req.Namespace, dk); err != nil {
+			return admission.Denied(err.Error())
+		}
+	case admissionv1.Update:
+		dkOld := &dkimmanagerv2.DKIMKey{}
+		if err := decoder.DecodeRaw(req.OldObject, dkOld); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		defaultUpdate(dk, dkOld)
+	default:
+		return admission.Allowed("")
+	}
+
+	marshaled, err := json.Marshal(dk)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+func (d *dkimKeyV2Defaulter) defaultCreate(namespace string, dk *dkimmanagerv2.DKIMKey) error {
+	dk.Spec.Domain = normalizeDomain(dk.Spec.Domain)
+	data := NameTemplateData{
+		Name:      dk.Name,
+		Namespace: namespace,
+		Domain:    dk.Spec.Domain,
+		Date:      time.Now().UTC().Format(nameTemplateDateFormat),
+		Purpose:   string(dk.Spec.Purpose),
+	}
+	selectorTemplate := d.opts.SelectorTemplate
+	if dk.IsARC() && d.opts.ARCSelectorTemplate != nil {
+		selectorTemplate = d.opts.ARCSelectorTemplate
+	}
+	if dk.Spec.Selector == "" && selectorTemplate != nil {
+		selector, err := executeNameTemplate(selectorTemplate, data)
+		if err != nil {
+			return err
+		}
+		dk.Spec.Selector = selector
+	}
+	if dk.Spec.SecretName == "" && d.opts.SecretNameTemplate != nil {
+		secretName, err := executeNameTemplate(d.opts.SecretNameTemplate, data)
+		if err != nil {
+			return err
+		}
+		dk.Spec.SecretName = secretName
+	}
+	return nil
+}
+
+// defaultUpdate keeps generated fields stable across updates, since templates may
+// depend on the current date, and treats domains differing only in case or trailing
+// dot as unchanged so that the immutability checks are not tripped.
+func defaultUpdate(dk, dkOld *dkimmanagerv2.DKIMKey) {
+	if normalizeDomain(dk.Spec.Domain) == normalizeDomain(dkOld.Spec.Domain) {
+		dk.Spec.Domain = dkOld.Spec.Domain
+	} else {
+		dk.Spec.Domain = normalizeDomain(dk.Spec.Domain)
+	}
+	if dk.Spec.Selector == "" {
+		dk.Spec.Selector = dkOld.Spec.Selector
+	}
+	if dk.Spec.SecretName == "" {
+		dk.Spec.SecretName = dkOld.Spec.SecretName
+	}
+}
+
+func SetupDKIMKeyV2Defaulter(mgr manager.Manager, dec *admission.Decoder, opts DKIMKeyDefaulterOptions) {
+	d := &dkimKeyV2Defaulter{
+		dec:  dec,
+		opts: opts,
+	}
+	srv := mgr.GetWebhookServer()
+	srv.Register("/mutate-dkim-manager-atelierhsn-com-v2-dkimkey", &webhook.Admission{Handler: d})
+}
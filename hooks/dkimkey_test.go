@@ -2,10 +2,14 @@ package hooks
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	dkimmanagerv1 "github.com/hsn723/dkim-manager/api/v1"
@@ -178,6 +182,52 @@ var _ = Describe("DKIMKey v2 webhook", func() {
 				dk.Spec.TTL = 100
 			},
 		},
+		{
+			title: "should deny changing ed25519Selector",
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.ED25519Selector = "selector2"
+			},
+		},
+		{
+			title: "should deny enabling delegation",
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: "dkim.example.net"}
+			},
+		},
+		{
+			title: "should deny changing purpose",
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.Purpose = dkim.KeyPurposeARC
+			},
+		},
+		{
+			title:  "should allow changing domain case and trailing dot",
+			accept: true,
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.Domain = "AtelierHSN.com."
+			},
+		},
+		{
+			title:  "should allow revoking",
+			accept: true,
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.Revoked = true
+			},
+		},
+		{
+			title:  "should allow omitting selector and secretName",
+			accept: true,
+			mutator: func(dk *dkimmanagerv2.DKIMKey) {
+				By("changing spec")
+				dk.Spec.Selector = ""
+				dk.Spec.SecretName = ""
+			},
+		},
 	}
 	for _, c := range cases {
 		It(c.title, func() {
@@ -204,4 +254,436 @@ var _ = Describe("DKIMKey v2 webhook", func() {
 			}
 		})
 	}
+
+	It("should deny un-revoking", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		spec := dummyDKIMKeySpec(name)
+		spec.Revoked = true
+		shouldCreateDKIMKey(ctx, name, namespace, spec)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("un-revoking")
+		dk.Spec.Revoked = false
+		err = k8sClient.Update(ctx, dk)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("DKIMKey domain policy", func() {
+	ctx := context.Background()
+
+	It("should only allow domains permitted for the namespace", func() {
+		allowedNamespace := uuid.NewString()
+		otherNamespace := uuid.NewString()
+		shouldCreateNamespace(ctx, allowedNamespace)
+		shouldCreateNamespace(ctx, otherNamespace)
+
+		By("creating DKIMDomainPolicy")
+		p := &dkimmanagerv2.DKIMDomainPolicy{}
+		p.SetName(uuid.NewString())
+		p.Spec = dkimmanagerv2.DKIMDomainPolicySpec{
+			Namespaces:      []string{allowedNamespace},
+			Domains:         []string{"atelierhsn.com"},
+			AllowSubdomains: true,
+		}
+		err := k8sClient.Create(ctx, p)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			By("deleting DKIMDomainPolicy")
+			Expect(k8sClient.Delete(ctx, p)).To(Succeed())
+			Eventually(func() error {
+				dk := &dkimmanagerv2.DKIMKey{}
+				dk.SetName(uuid.NewString())
+				dk.SetNamespace(otherNamespace)
+				dk.Spec = dummyDKIMKeySpec(dk.Name)
+				return k8sClient.Create(ctx, dk, client.DryRunAll)
+			}).Should(Succeed())
+		})
+
+		Eventually(func() error {
+			name := uuid.NewString()
+			dk := &dkimmanagerv2.DKIMKey{}
+			dk.SetName(name)
+			dk.SetNamespace(otherNamespace)
+			dk.Spec = dummyDKIMKeySpec(name)
+			return k8sClient.Create(ctx, dk)
+		}).ShouldNot(Succeed())
+
+		name := uuid.NewString()
+		spec := dummyDKIMKeySpec(name)
+		spec.Domain = "mail.atelierhsn.com"
+		shouldCreateDKIMKey(ctx, name, allowedNamespace, spec)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(uuid.NewString())
+		dk.SetNamespace(allowedNamespace)
+		dk.Spec = dummyDKIMKeySpec(dk.Name)
+		dk.Spec.Domain = "example.com"
+		err = k8sClient.Create(ctx, dk)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("DKIMKey record conflicts", func() {
+	ctx := context.Background()
+
+	It("should deny claiming a record already claimed in another namespace", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		otherNamespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		shouldCreateNamespace(ctx, otherNamespace)
+		shouldCreateDKIMKey(ctx, name, namespace, dummyDKIMKeySpec(name))
+
+		Eventually(func() error {
+			dk := &dkimmanagerv2.DKIMKey{}
+			dk.SetName(name)
+			dk.SetNamespace(otherNamespace)
+			dk.Spec = dummyDKIMKeySpec(name)
+			return k8sClient.Create(ctx, dk)
+		}).ShouldNot(Succeed())
+
+		By("creating DKIMKey with another selector")
+		spec := dummyDKIMKeySpec(name)
+		spec.Selector = uuid.NewString()
+		shouldCreateDKIMKey(ctx, name, otherNamespace, spec)
+	})
+})
+
+var _ = Describe("DKIMKey v2 webhook on create", func() {
+	ctx := context.Background()
+
+	cases := []struct {
+		accept  bool
+		mutator func(spec *dkimmanagerv2.DKIMKeySpec)
+		title   string
+	}{
+		{
+			title:   "should allow valid DKIMKeys",
+			accept:  true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {},
+		},
+		{
+			title: "should deny invalid selectors",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Selector = "selector_1"
+			},
+		},
+		{
+			title: "should deny invalid domains",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Domain = "atelierhsn..com"
+			},
+		},
+		{
+			title: "should deny record names exceeding 253 characters",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				label := strings.Repeat("a", 63)
+				spec.Selector = label
+				spec.Domain = strings.Join([]string{label, label, label}, ".")
+			},
+		},
+		{
+			title: "should deny invalid secret names",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.SecretName = "Invalid_Secret"
+			},
+		},
+		{
+			title: "should deny TTLs below the minimum",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.TTL = 10
+			},
+		},
+		{
+			title: "should deny TTLs above the maximum",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.TTL = 604800
+			},
+		},
+		{
+			title:  "should allow sibling ed25519 selectors for rsa keys",
+			accept: true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.ED25519Selector = spec.Selector + "-ed25519"
+			},
+		},
+		{
+			title: "should deny sibling ed25519 selectors for ed25519 keys",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.KeyType = dkim.KeyTypeED25519
+				spec.ED25519Selector = spec.Selector + "-ed25519"
+			},
+		},
+		{
+			title: "should deny sibling ed25519 selectors equal to the selector",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.ED25519Selector = spec.Selector
+			},
+		},
+		{
+			title: "should deny invalid sibling ed25519 selectors",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.ED25519Selector = "selector_1"
+			},
+		},
+		{
+			title:  "should allow DNSEndpoint labels, annotations and provider-specific properties",
+			accept: true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.DNSEndpoint = &dkimmanagerv2.DNSEndpointOptions{
+					Labels:        map[string]string{"dns": "internal"},
+					Annotations:   map[string]string{"external-dns.alpha.kubernetes.io/class": "internal"},
+					SetIdentifier: "primary",
+					ProviderSpecific: []dkimmanagerv2.ProviderSpecificProperty{
+						{Name: "aws/weight", Value: "100"},
+					},
+				}
+			},
+		},
+		{
+			title: "should deny invalid DNSEndpoint labels",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.DNSEndpoint = &dkimmanagerv2.DNSEndpointOptions{
+					Labels: map[string]string{"dns": "not a valid value"},
+				}
+			},
+		},
+		{
+			title: "should deny duplicate DNSEndpoint provider-specific properties",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.DNSEndpoint = &dkimmanagerv2.DNSEndpointOptions{
+					ProviderSpecific: []dkimmanagerv2.ProviderSpecificProperty{
+						{Name: "aws/weight", Value: "100"},
+						{Name: "aws/weight", Value: "0"},
+					},
+				}
+			},
+		},
+		{
+			title:  "should allow DKIM record tags",
+			accept: true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Tags = &dkimmanagerv2.DKIMRecordTags{
+					Testing:        true,
+					Strict:         true,
+					ServiceTypes:   []string{"email"},
+					HashAlgorithms: []string{"sha1", "sha256"},
+					Notes:          "onboarding; contact postmaster",
+				}
+			},
+		},
+		{
+			title: "should deny duplicate hash algorithms",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Tags = &dkimmanagerv2.DKIMRecordTags{
+					HashAlgorithms: []string{"sha256", "sha256"},
+				}
+			},
+		},
+		{
+			title:  "should allow delegating DKIM records",
+			accept: true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: "dkim.example.net"}
+			},
+		},
+		{
+			title: "should deny invalid delegation zones",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: "dkim..example.net"}
+			},
+		},
+		{
+			title: "should deny delegated record names exceeding 253 characters",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				label := strings.Repeat("a", 63)
+				spec.Delegation = &dkimmanagerv2.DKIMDelegation{Zone: strings.Join([]string{label, label, label}, ".")}
+			},
+		},
+		{
+			title: "should deny RSA keys weaker than the key policy allows",
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.KeyLength = dkim.KeyLength1024
+			},
+		},
+		{
+			title:  "should allow ed25519 keys regardless of the minimum RSA key length",
+			accept: true,
+			mutator: func(spec *dkimmanagerv2.DKIMKeySpec) {
+				spec.KeyType = dkim.KeyTypeED25519
+				spec.KeyLength = dkim.KeyLength1024
+			},
+		},
+	}
+	for _, c := range cases {
+		It(c.title, func() {
+			name := uuid.NewString()
+			namespace := uuid.NewString()
+			shouldCreateNamespace(ctx, namespace)
+
+			dk := &dkimmanagerv2.DKIMKey{}
+			dk.SetName(name)
+			dk.SetNamespace(namespace)
+			dk.Spec = dummyDKIMKeySpec(name)
+			c.mutator(&dk.Spec)
+
+			err := k8sClient.Create(ctx, dk)
+			if c.accept {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	}
+
+	It("should deny using an existing unrelated Secret", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		By("creating Secret")
+		s := &corev1.Secret{}
+		s.SetName(name)
+		s.SetNamespace(namespace)
+		s.Data = map[string][]byte{
+			"dummy": []byte("secret"),
+		}
+		err := k8sClient.Create(ctx, s)
+		Expect(err).NotTo(HaveOccurred())
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dummyDKIMKeySpec(name)
+		err = k8sClient.Create(ctx, dk)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should deny using an existing Secret containing the private key without the adopt annotation", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		spec := dummyDKIMKeySpec(name)
+
+		By("creating Secret")
+		key, _, err := dkim.GenRSA(dkim.KeyLength2048)
+		Expect(err).NotTo(HaveOccurred())
+		s := &corev1.Secret{}
+		s.SetName(name)
+		s.SetNamespace(namespace)
+		s.Data = map[string][]byte{
+			dkim.PrivateKeyFilename(spec.Selector, spec.Domain): key,
+		}
+		err = k8sClient.Create(ctx, s)
+		Expect(err).NotTo(HaveOccurred())
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = spec
+		err = k8sClient.Create(ctx, dk)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should allow adopting an existing Secret containing the private key with the adopt annotation", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+		spec := dummyDKIMKeySpec(name)
+
+		By("creating Secret")
+		key, _, err := dkim.GenRSA(dkim.KeyLength2048)
+		Expect(err).NotTo(HaveOccurred())
+		s := &corev1.Secret{}
+		s.SetName(name)
+		s.SetNamespace(namespace)
+		s.SetAnnotations(map[string]string{dkimmanagerv2.AdoptSecretAnnotation: "true"})
+		s.Data = map[string][]byte{
+			dkim.PrivateKeyFilename(spec.Selector, spec.Domain): key,
+		}
+		err = k8sClient.Create(ctx, s)
+		Expect(err).NotTo(HaveOccurred())
+
+		shouldCreateDKIMKey(ctx, name, namespace, spec)
+	})
+})
+
+var _ = Describe("DKIMKey v2 defaulting webhook", func() {
+	ctx := context.Background()
+
+	It("should generate omitted selector and secretName from templates", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		domain := fmt.Sprintf("%s.atelierhsn.com", name)
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dummyDKIMKeySpec(name)
+		dk.Spec.Selector = ""
+		dk.Spec.SecretName = ""
+		dk.Spec.Domain = domain
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		date := time.Now().UTC().Format("20060102")
+		Expect(dk.Spec.Selector).To(Equal(fmt.Sprintf("%s-%s", domain, date)))
+		Expect(dk.Spec.SecretName).To(Equal("dkim-" + name))
+	})
+
+	It("should generate omitted ARC selectors from the ARC template", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dummyDKIMKeySpec(name)
+		dk.Spec.Selector = ""
+		dk.Spec.Domain = fmt.Sprintf("%s.atelierhsn.com", name)
+		dk.Spec.Purpose = dkim.KeyPurposeARC
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+
+		date := time.Now().UTC().Format("20060102")
+		Expect(dk.Spec.Selector).To(Equal("arc-" + date))
+	})
+
+	It("should keep explicit selector and secretName", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dummyDKIMKeySpec(name)
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dk.Spec.Selector).To(Equal(name))
+		Expect(dk.Spec.SecretName).To(Equal(name))
+	})
+
+	It("should normalize domain case and trailing dot", func() {
+		name := uuid.NewString()
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		dk := &dkimmanagerv2.DKIMKey{}
+		dk.SetName(name)
+		dk.SetNamespace(namespace)
+		dk.Spec = dummyDKIMKeySpec(name)
+		dk.Spec.Domain = "AtelierHSN.com."
+		err := k8sClient.Create(ctx, dk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dk.Spec.Domain).To(Equal("atelierhsn.com"))
+	})
 })
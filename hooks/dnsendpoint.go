@@ -3,8 +3,11 @@ package hooks
 import (
 	"context"
 	"net/http"
+	"slices"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -13,7 +16,23 @@ import (
 	"github.com/hsn723/dkim-manager/pkg/externaldns"
 )
 
-//+kubebuilder:webhook:path=/validate-externaldns-k8s-io-v1alpha1-dnsendpoint,mutating=false,failurePolicy=fail,sideEffects=None,groups=externaldns.k8s.io,resources=dnsendpoints,verbs=delete,versions=v1alpha1,name=vdnsendpoint.kb.io,admissionReviewVersions={v1}
+// The webhook marker only covers the default DNSEndpoint API. The helm chart patches the generated
+// manifest with the configured group, version and resource.
+//+kubebuilder:webhook:path=/validate-dnsendpoint,mutating=false,failurePolicy=fail,sideEffects=None,groups=externaldns.k8s.io,resources=dnsendpoints,verbs=delete,versions=v1alpha1,name=vdnsendpoint.kb.io,admissionReviewVersions={v1}
+
+// recordOwnerKinds are the kinds publishing their records through DNSEndpoints.
+var recordOwnerKinds = []string{dkimKeyKind, "DMARCPolicy", "SPFRecord", "MTASTSPolicy", "TLSRPTRecord", "BIMIRecord"}
+
+func isRecordOwner(owner v1.OwnerReference) bool {
+	if !slices.Contains(recordOwnerKinds, owner.Kind) {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == apiGroup
+}
 
 type dnsEndpointValidator struct {
 	client.Client
@@ -40,11 +59,11 @@ func (v *dnsEndpointValidator) handleDelete(req admission.Request) admission.Res
 	}
 	owners := de.GetOwnerReferences()
 	for _, owner := range owners {
-		if isDKIMKeyOwner(owner) {
+		if isRecordOwner(owner) {
 			if req.UserInfo.Username == v.serviceAccountName {
 				return admission.Allowed("deletion by service account allowed")
 			}
-			return admission.Denied("directly deleting DKIM record is not allowed")
+			return admission.Denied("directly deleting " + owner.Kind + " record is not allowed")
 		}
 	}
 	return admission.Allowed("")
@@ -57,5 +76,7 @@ func SetupDNSEndpointWebhook(mgr manager.Manager, dec *admission.Decoder, sa str
 		serviceAccountName: sa,
 	}
 	srv := mgr.GetWebhookServer()
+	srv.Register("/validate-dnsendpoint", &webhook.Admission{Handler: v})
+	// Kept for webhook configurations created before the DNSEndpoint group and version became configurable.
 	srv.Register("/validate-externaldns-k8s-io-v1alpha1-dnsendpoint", &webhook.Admission{Handler: v})
 }
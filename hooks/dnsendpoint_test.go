@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
@@ -91,4 +92,42 @@ var _ = Describe("DNSEndpoint webhook", func() {
 		err = k8sClient.Delete(ctx, de)
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("should prevent deleting DNSEndpoints owned by other record kinds", func() {
+		namespace := uuid.NewString()
+		shouldCreateNamespace(ctx, namespace)
+
+		for _, kind := range []string{"DMARCPolicy", "SPFRecord", "MTASTSPolicy", "TLSRPTRecord", "BIMIRecord"} {
+			By("creating DNSEndpoint owned by " + kind)
+			name := uuid.NewString()
+			de := externaldns.DNSEndpoint()
+			de.SetName(name)
+			de.SetNamespace(namespace)
+			de.UnstructuredContent()["spec"] = map[string]interface{}{
+				"endpoints": []map[string]interface{}{
+					{
+						"dnsName":    "hoge",
+						"recordTTL":  3600,
+						"recordType": "TXT",
+						"targets":    []string{"hoge"},
+					},
+				},
+			}
+			de.SetOwnerReferences([]v1.OwnerReference{
+				{
+					APIVersion: dkimmanagerv2.GroupVersion.String(),
+					Kind:       kind,
+					Name:       name,
+					UID:        types.UID(uuid.NewString()),
+				},
+			})
+
+			err := k8sClient.Create(ctx, de)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("deleting endpoint")
+			err = k8sClient.Delete(ctx, de)
+			Expect(err).To(HaveOccurred())
+		}
+	})
 })
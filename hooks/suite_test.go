@@ -26,6 +26,8 @@ import (
 
 	dkimmanagerv1 "github.com/hsn723/dkim-manager/api/v1"
 	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+	"github.com/hsn723/dkim-manager/pkg/policy"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -97,8 +99,26 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	dec := admission.NewDecoder(scheme)
-	SetupDKIMKeyWebhook(mgr, &dec)
-	SetupDKIMKeyV2Webhook(mgr, &dec)
+	validatorOpts := DKIMKeyValidatorOptions{
+		MinTTL: 60,
+		MaxTTL: 86400,
+		KeyPolicy: policy.KeyPolicy{
+			MinRSAKeyLength: dkim.KeyLength2048,
+		},
+	}
+	SetupDKIMKeyWebhook(mgr, &dec, validatorOpts)
+	SetupDKIMKeyV2Webhook(mgr, &dec, validatorOpts)
+	selectorTmpl, err := ParseNameTemplate("selector", "{{.Domain}}-{{.Date}}")
+	Expect(err).NotTo(HaveOccurred())
+	arcSelectorTmpl, err := ParseNameTemplate("ARC selector", "{{.Purpose}}-{{.Date}}")
+	Expect(err).NotTo(HaveOccurred())
+	secretNameTmpl, err := ParseNameTemplate("secret name", "dkim-{{.Name}}")
+	Expect(err).NotTo(HaveOccurred())
+	SetupDKIMKeyV2Defaulter(mgr, &dec, DKIMKeyDefaulterOptions{
+		SelectorTemplate:    selectorTmpl,
+		ARCSelectorTemplate: arcSelectorTmpl,
+		SecretNameTemplate:  secretNameTmpl,
+	})
 	SetupDNSEndpointWebhook(mgr, &dec, "dummy")
 	SetupSecretWebhook(mgr, &dec, "dummy")
 
@@ -0,0 +1,221 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+// Archive holds DKIMKeys along with their private keys, and the DKIMKeySets provisioning them.
+type Archive struct {
+	// Created is the time the archive was created.
+	Created metav1.Time `json:"created"`
+	// Keys are the backed up DKIMKeys.
+	Keys []Key `json:"keys"`
+	// KeySets are the backed up DKIMKeySets.
+	KeySets []dkimmanagerv2.DKIMKeySet `json:"keySets,omitempty"`
+}
+
+// Key is a DKIMKey and the Secret holding its private keys.
+type Key struct {
+	DKIMKey dkimmanagerv2.DKIMKey `json:"dkimKey"`
+	// Secret holds the private keys. It is nil if the private keys were not generated yet, or were revoked.
+	Secret *corev1.Secret `json:"secret,omitempty"`
+	// SecretOwned is true if the Secret is controlled by the DKIMKey, rather than waiting to be adopted.
+	SecretOwned bool `json:"secretOwned,omitempty"`
+	// KeySet is the name of the DKIMKeySet controlling the DKIMKey, if any.
+	KeySet string `json:"keySet,omitempty"`
+}
+
+// Result describes what Restore did with an object of an archive.
+type Result struct {
+	Kind string
+	client.ObjectKey
+	// Restored is false if the object already existed, and was left unchanged.
+	Restored bool
+}
+
+// objectMeta returns the metadata of an object that is meaningful once restored in another cluster.
+func objectMeta(m metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        m.Name,
+		Namespace:   m.Namespace,
+		Labels:      m.Labels,
+		Annotations: m.Annotations,
+	}
+}
+
+// Collect returns an archive of the DKIMKeys and DKIMKeySets of the given namespaces, or of all
+// namespaces if none is given, along with the Secrets holding the private keys of the DKIMKeys.
+func Collect(ctx context.Context, c client.Reader, namespaces []string, now time.Time) (*Archive, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	a := &Archive{Created: metav1.NewTime(now)}
+	for _, ns := range namespaces {
+		dkl := &dkimmanagerv2.DKIMKeyList{}
+		if err := c.List(ctx, dkl, client.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("failed to list DKIMKeys: %w", err)
+		}
+		for _, dk := range dkl.Items {
+			k, err := collectKey(ctx, c, &dk)
+			if err != nil {
+				return nil, err
+			}
+			a.Keys = append(a.Keys, k)
+		}
+		ksl := &dkimmanagerv2.DKIMKeySetList{}
+		if err := c.List(ctx, ksl, client.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("failed to list DKIMKeySets: %w", err)
+		}
+		for _, ks := range ksl.Items {
+			a.KeySets = append(a.KeySets, dkimmanagerv2.DKIMKeySet{
+				ObjectMeta: objectMeta(ks.ObjectMeta),
+				Spec:       ks.Spec,
+			})
+		}
+	}
+	return a, nil
+}
+
+func collectKey(ctx context.Context, c client.Reader, dk *dkimmanagerv2.DKIMKey) (Key, error) {
+	k := Key{
+		DKIMKey: dkimmanagerv2.DKIMKey{
+			ObjectMeta: objectMeta(dk.ObjectMeta),
+			Spec:       dk.Spec,
+		},
+	}
+	if owner := metav1.GetControllerOf(dk); owner != nil && owner.Kind == dkimmanagerv2.DKIMKeySetKind {
+		k.KeySet = owner.Name
+	}
+	if dk.Spec.SecretName == "" {
+		return k, nil
+	}
+	s := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: dk.Namespace, Name: dk.Spec.SecretName}, s); err != nil {
+		if apierrors.IsNotFound(err) {
+			return k, nil
+		}
+		return k, fmt.Errorf("failed to get Secret: %w", err)
+	}
+	k.Secret = &corev1.Secret{
+		ObjectMeta: objectMeta(s.ObjectMeta),
+		Immutable:  s.Immutable,
+		Type:       s.Type,
+		Data:       s.Data,
+	}
+	if owner := metav1.GetControllerOf(s); owner != nil && owner.Kind == dkimmanagerv2.DKIMKeyKind && owner.Name == dk.Name {
+		k.SecretOwned = true
+	}
+	return k, nil
+}
+
+// Restore recreates the objects of an archive, leaving existing objects unchanged. The Secret of
+// each DKIMKey is created before the DKIMKey, annotated for the controller to adopt the private keys
+// rather than generating new ones, then the ownership of the Secrets and DKIMKeys is restored.
+func Restore(ctx context.Context, c client.Client, a *Archive) ([]Result, error) {
+	var results []Result
+	restored := map[client.ObjectKey]*dkimmanagerv2.DKIMKey{}
+	for _, k := range a.Keys {
+		dk := k.DKIMKey.DeepCopy()
+		secretRestored := false
+		res, err := create(ctx, c, dk, dkimmanagerv2.DKIMKeyKind, func() error {
+			if k.Secret == nil {
+				return nil
+			}
+			s := k.Secret.DeepCopy()
+			if s.Annotations == nil {
+				s.Annotations = map[string]string{}
+			}
+			s.Annotations[dkimmanagerv2.AdoptSecretAnnotation] = "true"
+			sRes, err := create(ctx, c, s, "Secret", nil)
+			results = append(results, sRes)
+			secretRestored = sRes.Restored
+			return err
+		})
+		results = append(results, res)
+		if err != nil {
+			return results, err
+		}
+		if !res.Restored {
+			continue
+		}
+		restored[res.ObjectKey] = dk
+		// A Secret which already existed is left unchanged, as it may not be the one backed up.
+		if k.SecretOwned && secretRestored {
+			if err := setController(ctx, c, dk, &corev1.Secret{}, client.ObjectKeyFromObject(k.Secret)); err != nil {
+				return results, err
+			}
+		}
+	}
+	for _, ks := range a.KeySets {
+		res, err := create(ctx, c, ks.DeepCopy(), dkimmanagerv2.DKIMKeySetKind, nil)
+		results = append(results, res)
+		if err != nil {
+			return results, err
+		}
+	}
+	for _, k := range a.Keys {
+		dk, ok := restored[client.ObjectKeyFromObject(&k.DKIMKey)]
+		if !ok || k.KeySet == "" {
+			continue
+		}
+		ks := &dkimmanagerv2.DKIMKeySet{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: dk.Namespace, Name: k.KeySet}, ks); err != nil {
+			return results, fmt.Errorf("failed to get DKIMKeySet %s: %w", k.KeySet, err)
+		}
+		if err := setController(ctx, c, ks, &dkimmanagerv2.DKIMKey{}, client.ObjectKeyFromObject(dk)); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// create creates obj unless an object of the same name exists, after calling before if it is not nil
+// and the object does not exist yet.
+func create(ctx context.Context, c client.Client, obj client.Object, kind string, before func() error) (Result, error) {
+	res := Result{Kind: kind, ObjectKey: client.ObjectKeyFromObject(obj)}
+	err := c.Get(ctx, res.ObjectKey, obj.DeepCopyObject().(client.Object))
+	if err == nil {
+		return res, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return res, fmt.Errorf("failed to get %s %s: %w", kind, res.ObjectKey, err)
+	}
+	if before != nil {
+		if err := before(); err != nil {
+			return res, err
+		}
+	}
+	if err := c.Create(ctx, obj); err != nil {
+		return res, fmt.Errorf("failed to create %s %s: %w", kind, res.ObjectKey, err)
+	}
+	res.Restored = true
+	return res, nil
+}
+
+// setController makes owner the controller of the object identified by key.
+func setController(ctx context.Context, c client.Client, owner client.Object, obj client.Object, key client.ObjectKey) error {
+	if err := c.Get(ctx, key, obj); err != nil {
+		return fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	if metav1.GetControllerOf(obj) != nil {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	if err := controllerutil.SetControllerReference(owner, obj, c.Scheme()); err != nil {
+		return err
+	}
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("failed to restore the owner of %s: %w", key, err)
+	}
+	return nil
+}
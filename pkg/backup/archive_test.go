@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(dkimmanagerv2.AddToScheme(scheme))
+	return scheme
+}
+
+func testKey(name, namespace string) *dkimmanagerv2.DKIMKey {
+	return &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID("uid-" + name), ResourceVersion: "42", Labels: map[string]string{"app": name}},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   "sel1",
+			Domain:     name + ".example.com",
+			TTL:        3600,
+			KeyLength:  dkim.KeyLength2048,
+			KeyType:    dkim.KeyTypeED25519,
+			Purpose:    dkim.KeyPurposeDKIM,
+		},
+		Status: dkimmanagerv2.DKIMKeyStatus{ObservedGeneration: 1},
+	}
+}
+
+func testSecret(dk *dkimmanagerv2.DKIMKey, owned bool) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: dk.Spec.SecretName, Namespace: dk.Namespace, UID: types.UID("uid-secret-" + dk.Name)},
+		Immutable:  ptr.To(true),
+		Data:       map[string][]byte{dk.Keys()[0].PrivateKeyFilename(): []byte("key of " + dk.Name)},
+	}
+	if owned {
+		s.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(dk, dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeyKind))}
+	}
+	return s
+}
+
+func TestCollectRestore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scheme := newScheme()
+
+	ks := &dkimmanagerv2.DKIMKeySet{
+		ObjectMeta: metav1.ObjectMeta{Name: "set", Namespace: "default", UID: "uid-set"},
+		Spec:       dkimmanagerv2.DKIMKeySetSpec{Domains: []string{"set.example.com"}},
+	}
+	owned := testKey("owned", "default")
+	adopted := testKey("adopted", "default")
+	pending := testKey("pending", "default")
+	provisioned := testKey("set", "default")
+	provisioned.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(ks, dkimmanagerv2.GroupVersion.WithKind(dkimmanagerv2.DKIMKeySetKind))}
+	other := testKey("other", "other")
+	src := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		ks, owned, testSecret(owned, true), adopted, testSecret(adopted, false), pending,
+		provisioned, testSecret(provisioned, true), other, testSecret(other, true),
+	).Build()
+
+	now := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	a, err := Collect(ctx, src, []string{"default"}, now)
+	require.NoError(t, err)
+	assert.True(t, a.Created.Time.Equal(now))
+	require.Len(t, a.Keys, 4)
+	require.Len(t, a.KeySets, 1)
+	keys := map[string]Key{}
+	for _, k := range a.Keys {
+		keys[k.DKIMKey.Name] = k
+		assert.Empty(t, k.DKIMKey.UID)
+		assert.Empty(t, k.DKIMKey.ResourceVersion)
+		assert.Empty(t, k.DKIMKey.OwnerReferences)
+		assert.Empty(t, k.DKIMKey.Status)
+	}
+	assert.True(t, keys["owned"].SecretOwned)
+	assert.False(t, keys["adopted"].SecretOwned)
+	assert.Nil(t, keys["pending"].Secret)
+	assert.Equal(t, "set", keys["set"].KeySet)
+	assert.Equal(t, []byte("key of owned"), keys["owned"].Secret.Data["owned.example.com.sel1.key"])
+	assert.Empty(t, keys["owned"].Secret.OwnerReferences)
+	assert.Empty(t, a.KeySets[0].UID)
+
+	data, err := json.Marshal(a)
+	require.NoError(t, err)
+	a = &Archive{}
+	require.NoError(t, json.Unmarshal(data, a))
+
+	existing := testKey("owned", "default")
+	existing.Spec.Selector = "existing"
+	dst := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	results, err := Restore(ctx, dst, a)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Result{
+		{Kind: "Secret", ObjectKey: client.ObjectKey{Namespace: "default", Name: "adopted"}, Restored: true},
+		{Kind: dkimmanagerv2.DKIMKeyKind, ObjectKey: client.ObjectKey{Namespace: "default", Name: "adopted"}, Restored: true},
+		{Kind: dkimmanagerv2.DKIMKeyKind, ObjectKey: client.ObjectKey{Namespace: "default", Name: "owned"}},
+		{Kind: dkimmanagerv2.DKIMKeyKind, ObjectKey: client.ObjectKey{Namespace: "default", Name: "pending"}, Restored: true},
+		{Kind: "Secret", ObjectKey: client.ObjectKey{Namespace: "default", Name: "set"}, Restored: true},
+		{Kind: dkimmanagerv2.DKIMKeyKind, ObjectKey: client.ObjectKey{Namespace: "default", Name: "set"}, Restored: true},
+		{Kind: dkimmanagerv2.DKIMKeySetKind, ObjectKey: client.ObjectKey{Namespace: "default", Name: "set"}, Restored: true},
+	}, results)
+
+	dk := &dkimmanagerv2.DKIMKey{}
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "owned"}, dk))
+	assert.Equal(t, "existing", dk.Spec.Selector, "existing DKIMKeys must be left unchanged")
+	s := &corev1.Secret{}
+	assert.Error(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "owned"}, s), "the Secret of an existing DKIMKey must not be restored")
+
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "adopted"}, s))
+	assert.Equal(t, []byte("key of adopted"), s.Data["adopted.example.com.sel1.key"])
+	assert.Empty(t, s.OwnerReferences)
+	assert.Equal(t, "true", s.Annotations[dkimmanagerv2.AdoptSecretAnnotation], "restored Secrets must opt in to adoption")
+
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "set"}, dk))
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "set"}, s))
+	assert.True(t, metav1.IsControlledBy(s, dk))
+	restoredSet := &dkimmanagerv2.DKIMKeySet{}
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "set"}, restoredSet))
+	assert.True(t, metav1.IsControlledBy(dk, restoredSet))
+	assert.Equal(t, map[string]string{"app": "set"}, dk.Labels)
+
+	require.NoError(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "pending"}, dk))
+	assert.Error(t, dst.Get(ctx, client.ObjectKey{Namespace: "default", Name: "pending"}, s))
+
+	results, err = Restore(ctx, dst, a)
+	require.NoError(t, err)
+	for _, r := range results {
+		assert.False(t, r.Restored, "restoring again must leave %s %s unchanged", r.Kind, r.ObjectKey)
+	}
+}
@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	magic      = "dkim-manager-backup/v1\n"
+	saltSize   = 16
+	keySize    = 32
+	headerSize = len(magic) + 4 + saltSize
+
+	// Iterations is the number of PBKDF2-HMAC-SHA256 iterations deriving the encryption key
+	// from the passphrase, as recommended by OWASP.
+	Iterations = 600000
+	// maxIterations bounds the iteration count read from the unauthenticated header of a backup,
+	// so that a crafted backup cannot make key derivation arbitrarily expensive.
+	maxIterations = 2 * Iterations
+)
+
+// ErrDecrypt is returned when a backup cannot be decrypted, either because the passphrase is wrong
+// or because the backup is corrupted.
+var ErrDecrypt = errors.New("failed to decrypt backup: wrong passphrase or corrupted data")
+
+// Encrypt encrypts data with AES-256-GCM, using a key derived from the passphrase with PBKDF2.
+// The output starts with a header holding the format version, the iteration count and the salt,
+// which is authenticated along with the data.
+func Encrypt(data, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = binary.BigEndian.AppendUint32(header, Iterations)
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	header = append(header, salt...)
+
+	aead, err := newAEAD(passphrase, salt, Iterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, data, header), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("not a dkim-manager backup")
+	}
+	if len(data) < headerSize {
+		return nil, ErrDecrypt
+	}
+	header := data[:headerSize]
+	iterations := binary.BigEndian.Uint32(header[len(magic):])
+	if iterations < Iterations || iterations > maxIterations {
+		return nil, fmt.Errorf("unsupported iteration count %d", iterations)
+	}
+	aead, err := newAEAD(passphrase, header[len(magic)+4:], int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	rest := data[headerSize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func newAEAD(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
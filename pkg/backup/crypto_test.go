@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+	data := []byte("private keys")
+	passphrase := []byte("correct horse battery staple")
+
+	encrypted, err := Encrypt(data, passphrase)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), string(data))
+	other, err := Encrypt(data, passphrase)
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, other, "salt and nonce must be random")
+
+	decrypted, err := Decrypt(encrypted, passphrase)
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	_, err = Decrypt(encrypted, []byte("wrong passphrase"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = Decrypt(tampered, passphrase)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	tampered = append([]byte{}, encrypted...)
+	tampered[headerSize-1] ^= 1
+	_, err = Decrypt(tampered, passphrase)
+	assert.ErrorIs(t, err, ErrDecrypt, "the salt must be authenticated")
+
+	_, err = Decrypt(encrypted[:headerSize+4], passphrase)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestEncryptInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := Encrypt([]byte("data"), nil)
+	assert.Error(t, err)
+	_, err = Decrypt([]byte("not a backup"), []byte("passphrase"))
+	assert.Error(t, err)
+	_, err = Decrypt([]byte(magic+"\x00\x00\x00\x00"+"0123456789abcdef"), []byte("passphrase"))
+	assert.Error(t, err)
+
+	encrypted, err := Encrypt([]byte("data"), []byte("passphrase"))
+	require.NoError(t, err)
+	for _, iterations := range []uint32{Iterations - 1, maxIterations + 1} {
+		tampered := append([]byte{}, encrypted...)
+		binary.BigEndian.PutUint32(tampered[len(magic):], iterations)
+		_, err = Decrypt(tampered, []byte("passphrase"))
+		assert.ErrorContains(t, err, "unsupported iteration count")
+	}
+}
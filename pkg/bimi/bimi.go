@@ -0,0 +1,65 @@
+package bimi
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// Selector is the BIMI selector used when messages do not carry a BIMI-Selector header.
+const Selector = "default"
+
+// Record holds the tags of a BIMI assertion record.
+type Record struct {
+	// LogoURL is the https URL of the SVG Tiny PS logo (l=).
+	LogoURL string
+	// AuthorityURL is the https URL of the PEM-encoded Verified Mark Certificate (a=). Omitted when empty.
+	AuthorityURL string
+}
+
+// RecordName returns the DNS name under which the BIMI record of the domain is published.
+func RecordName(domain string) string {
+	return Selector + "._bimi." + domain
+}
+
+// Validate checks that the record only contains valid URLs.
+func (r Record) Validate() error {
+	if err := validateURL("logo", r.LogoURL, ".svg"); err != nil {
+		return err
+	}
+	if r.AuthorityURL == "" {
+		return nil
+	}
+	return validateURL("authority", r.AuthorityURL, ".pem")
+}
+
+// validateURL checks that u is an https URL to a file with the given extension, which can be
+// listed in a BIMI record without escaping.
+func validateURL(kind, u, ext string) error {
+	if strings.ContainsAny(u, " \t,;\"\\") {
+		return fmt.Errorf("%s URL %q contains characters not allowed in BIMI records", kind, u)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("invalid %s URL %q: %w", kind, u, err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("%s URL %q must be an https URL", kind, u)
+	}
+	if !strings.EqualFold(path.Ext(parsed.Path), ext) {
+		return fmt.Errorf("%s URL %q must point to a %s file", kind, u, ext)
+	}
+	return nil
+}
+
+// GenTXTValue generates the BIMI record in presentation format.
+func (r Record) GenTXTValue() string {
+	tags := []string{"v=BIMI1", "l=" + r.LogoURL}
+	if r.AuthorityURL != "" {
+		tags = append(tags, "a="+r.AuthorityURL)
+	}
+	return dkim.FormatTXTValue(strings.Join(tags, "; ") + ";")
+}
@@ -0,0 +1,48 @@
+package bimi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "default._bimi.example.com", RecordName("example.com"))
+}
+
+func TestGenTXTValue(t *testing.T) {
+	t.Parallel()
+	r := Record{LogoURL: "https://example.com/logo.svg"}
+	assert.Equal(t, `"v=BIMI1; l=https://example.com/logo.svg;"`, r.GenTXTValue())
+	r.AuthorityURL = "https://example.com/vmc.pem"
+	assert.Equal(t, `"v=BIMI1; l=https://example.com/logo.svg; a=https://example.com/vmc.pem;"`, r.GenTXTValue())
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title  string
+		record Record
+		valid  bool
+	}{
+		{title: "LogoOnly", record: Record{LogoURL: "https://example.com/logo.svg"}, valid: true},
+		{title: "WithAuthority", record: Record{LogoURL: "https://example.com/bimi/Logo.SVG", AuthorityURL: "https://example.com/vmc.pem"}, valid: true},
+		{title: "MissingLogo", record: Record{}},
+		{title: "HTTPLogo", record: Record{LogoURL: "http://example.com/logo.svg"}},
+		{title: "PNGLogo", record: Record{LogoURL: "https://example.com/logo.png"}},
+		{title: "Separator", record: Record{LogoURL: "https://example.com/a;b.svg"}},
+		{title: "InvalidAuthority", record: Record{LogoURL: "https://example.com/logo.svg", AuthorityURL: "https://example.com/vmc.crt"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := tc.record.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
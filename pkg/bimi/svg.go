@@ -0,0 +1,117 @@
+package bimi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxLogoSize is the largest logo accepted, as recommended by the BIMI specification.
+	MaxLogoSize = 32 * 1024
+
+	svgNamespace = "http://www.w3.org/2000/svg"
+)
+
+// forbiddenElements are the elements excluded from the SVG Tiny Portable/Secure profile,
+// i.e. scripts, animations, embedded media and foreign content.
+var forbiddenElements = []string{
+	"animate", "animateColor", "animateMotion", "animateTransform", "audio",
+	"foreignObject", "image", "script", "set", "video",
+}
+
+// ValidateSVGTinyPS checks that data is an SVG logo following the SVG Tiny Portable/Secure profile
+// required by BIMI: the root svg element declares version 1.2 and the tiny-ps base profile, has no
+// x or y attributes and contains a title, and the document has no scripts, animations, event
+// handlers, embedded media or references to external resources.
+func ValidateSVGTinyPS(data []byte) error {
+	if len(data) > MaxLogoSize {
+		return fmt.Errorf("logo is %d bytes, exceeding the maximum of %d", len(data), MaxLogoSize)
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	seenRoot := false
+	hasTitle := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid SVG: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if seenRoot {
+					return errors.New("invalid SVG: multiple root elements")
+				}
+				seenRoot = true
+				if err := validateRoot(t); err != nil {
+					return err
+				}
+			}
+			if depth == 2 && t.Name.Local == "title" {
+				hasTitle = true
+			}
+			if err := validateElement(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if !seenRoot {
+		return errors.New("invalid SVG: no root element")
+	}
+	if !hasTitle {
+		return errors.New("SVG Tiny PS logos must have a title")
+	}
+	return nil
+}
+
+func validateRoot(e xml.StartElement) error {
+	if e.Name.Space != svgNamespace || e.Name.Local != "svg" {
+		return fmt.Errorf("root element must be an svg element in the %s namespace", svgNamespace)
+	}
+	if v := attr(e, "version"); v != "1.2" {
+		return fmt.Errorf("svg version must be 1.2, got %q", v)
+	}
+	if p := attr(e, "baseProfile"); p != "tiny-ps" {
+		return fmt.Errorf("svg baseProfile must be tiny-ps, got %q", p)
+	}
+	if attr(e, "x") != "" || attr(e, "y") != "" {
+		return errors.New("svg element must not have x or y attributes")
+	}
+	return nil
+}
+
+func validateElement(e xml.StartElement) error {
+	if slices.Contains(forbiddenElements, e.Name.Local) {
+		return fmt.Errorf("%s elements are not allowed in SVG Tiny PS logos", e.Name.Local)
+	}
+	for _, a := range e.Attr {
+		if strings.HasPrefix(strings.ToLower(a.Name.Local), "on") {
+			return fmt.Errorf("event handler attribute %s is not allowed in SVG Tiny PS logos", a.Name.Local)
+		}
+		if a.Name.Local == "href" && !strings.HasPrefix(a.Value, "#") {
+			return fmt.Errorf("external reference %q is not allowed in SVG Tiny PS logos", a.Value)
+		}
+	}
+	return nil
+}
+
+// attr returns the value of the unqualified attribute of e with the given name.
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package bimi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validLogo = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.2" baseProfile="tiny-ps" viewBox="0 0 100 100">
+  <title>Example</title>
+  <defs><circle id="c" cx="50" cy="50" r="40"/></defs>
+  <use xlink:href="#c" fill="#336699"/>
+</svg>`
+
+func TestValidateSVGTinyPS(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title string
+		svg   string
+		valid bool
+	}{
+		{title: "Valid", svg: validLogo, valid: true},
+		{title: "NotXML", svg: "<svg"},
+		{title: "Empty", svg: ""},
+		{title: "NotSVG", svg: `<html xmlns="http://www.w3.org/2000/svg" version="1.2" baseProfile="tiny-ps"><title>x</title></html>`},
+		{title: "MissingNamespace", svg: `<svg version="1.2" baseProfile="tiny-ps"><title>x</title></svg>`},
+		{title: "WrongProfile", svg: strings.Replace(validLogo, `baseProfile="tiny-ps"`, `baseProfile="tiny"`, 1)},
+		{title: "WrongVersion", svg: strings.Replace(validLogo, `version="1.2"`, `version="1.1"`, 1)},
+		{title: "RootPosition", svg: strings.Replace(validLogo, `viewBox=`, `x="0" viewBox=`, 1)},
+		{title: "MissingTitle", svg: strings.Replace(validLogo, "<title>Example</title>", "", 1)},
+		{title: "Script", svg: strings.Replace(validLogo, "<defs>", "<script>alert(1)</script><defs>", 1)},
+		{title: "Image", svg: strings.Replace(validLogo, "<defs>", `<image xlink:href="#c"/><defs>`, 1)},
+		{title: "Animation", svg: strings.Replace(validLogo, `r="40"/>`, `r="40"><animate attributeName="r"/></circle>`, 1)},
+		{title: "EventHandler", svg: strings.Replace(validLogo, `fill="#336699"`, `onclick="x()"`, 1)},
+		{title: "ExternalReference", svg: strings.Replace(validLogo, `xlink:href="#c"`, `xlink:href="https://example.com/c.svg#c"`, 1)},
+		{title: "TooLarge", svg: strings.Replace(validLogo, "Example", strings.Repeat("x", MaxLogoSize), 1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateSVGTinyPS([]byte(tc.svg))
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChainValidation is the status of the validation of the previous ARC sets (cv=).
+type ChainValidation string
+
+const (
+	ChainValidationNone ChainValidation = "none"
+	ChainValidationPass ChainValidation = "pass"
+	ChainValidationFail ChainValidation = "fail"
+)
+
+const (
+	HeaderARCAuthenticationResults = "ARC-Authentication-Results"
+	HeaderARCMessageSignature      = "ARC-Message-Signature"
+	HeaderARCSeal                  = "ARC-Seal"
+
+	// MaxARCInstance is the highest ARC instance number, as defined in RFC 8617 section 4.2.1.
+	MaxARCInstance = 50
+)
+
+// ARCSet holds the header fields added by one ARC instance, each including its name,
+// e.g. "ARC-Seal: i=1; a=rsa-sha256; ...".
+type ARCSet struct {
+	AuthenticationResults string
+	MessageSignature      string
+	Seal                  string
+}
+
+// ARCSigner generates the ARC-Message-Signature and ARC-Seal header fields of messages, as defined in RFC 8617,
+// with the private key of an ARC key published under the selector and domain.
+type ARCSigner struct {
+	Domain    string
+	Selector  string
+	algorithm string
+	key       crypto.Signer
+}
+
+// NewARCSigner returns an ARCSigner using the PEM-encoded private key, as generated by GenRSA or GenED25519.
+func NewARCSigner(domain, selector string, privateKey []byte) (*ARCSigner, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block containing private key")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &ARCSigner{Domain: domain, Selector: selector}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.algorithm, s.key = "rsa-sha256", k
+	case ed25519.PrivateKey:
+		s.algorithm, s.key = "ed25519-sha256", k
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return s, nil
+}
+
+// ARCAuthenticationResults returns the ARC-Authentication-Results header field of the given instance,
+// recording the results of the authentication checks performed by authServID, e.g. "dkim=pass header.d=example.com".
+func ARCAuthenticationResults(instance int, authServID, results string) string {
+	return fmt.Sprintf("%s: i=%d; %s; %s", HeaderARCAuthenticationResults, instance, authServID, results)
+}
+
+// MessageSignature returns the ARC-Message-Signature header field of the given instance, signing the body and
+// the given header fields of the message with relaxed canonicalization. The message must use CRLF line endings,
+// and the header fields must include From but no ARC-Seal.
+func (s *ARCSigner) MessageSignature(instance int, message []byte, headers []string, t time.Time) (string, error) {
+	if err := checkInstance(instance); err != nil {
+		return "", err
+	}
+	if !slices.ContainsFunc(headers, func(h string) bool { return strings.EqualFold(h, "From") }) {
+		return "", fmt.Errorf("signed header fields must include From")
+	}
+	if slices.ContainsFunc(headers, func(h string) bool { return strings.EqualFold(h, HeaderARCSeal) }) {
+		return "", fmt.Errorf("signed header fields must not include ARC-Seal")
+	}
+	fields, body := splitMessage(message)
+	bh := sha256.Sum256(canonicalizeBodyRelaxed(body))
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		names[i] = strings.ToLower(h)
+	}
+	field := fmt.Sprintf("%s: i=%d; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		HeaderARCMessageSignature, instance, s.algorithm, s.Domain, s.Selector, t.Unix(),
+		strings.Join(names, ":"), base64.StdEncoding.EncodeToString(bh[:]))
+	var data strings.Builder
+	for _, f := range selectHeaderFields(fields, headers) {
+		data.WriteString(canonicalizeHeaderRelaxed(f))
+	}
+	data.WriteString(strings.TrimSuffix(canonicalizeHeaderRelaxed(field), "\r\n"))
+	return s.sign(field, data.String())
+}
+
+// Seal returns the ARC-Seal header field sealing the given ARC sets, ordered by instance. The last set is
+// the one being added, without seal, and cv is the result of the validation of the previous sets.
+func (s *ARCSigner) Seal(sets []ARCSet, cv ChainValidation, t time.Time) (string, error) {
+	instance := len(sets)
+	if err := checkInstance(instance); err != nil {
+		return "", err
+	}
+	switch {
+	case instance == 1 && cv != ChainValidationNone:
+		return "", fmt.Errorf("chain validation status of the first instance must be %s", ChainValidationNone)
+	case instance > 1 && cv != ChainValidationPass && cv != ChainValidationFail:
+		return "", fmt.Errorf("chain validation status must be %s or %s", ChainValidationPass, ChainValidationFail)
+	}
+	field := fmt.Sprintf("%s: i=%d; a=%s; t=%d; cv=%s; d=%s; s=%s; b=",
+		HeaderARCSeal, instance, s.algorithm, t.Unix(), cv, s.Domain, s.Selector)
+	var data strings.Builder
+	for i, set := range sets {
+		if set.AuthenticationResults == "" || set.MessageSignature == "" {
+			return "", fmt.Errorf("ARC set %d is incomplete", i+1)
+		}
+		data.WriteString(canonicalizeHeaderRelaxed(set.AuthenticationResults))
+		data.WriteString(canonicalizeHeaderRelaxed(set.MessageSignature))
+		if i == instance-1 {
+			break
+		}
+		if set.Seal == "" {
+			return "", fmt.Errorf("ARC set %d is not sealed", i+1)
+		}
+		data.WriteString(canonicalizeHeaderRelaxed(set.Seal))
+	}
+	data.WriteString(strings.TrimSuffix(canonicalizeHeaderRelaxed(field), "\r\n"))
+	return s.sign(field, data.String())
+}
+
+// sign appends the signature of data to field, whose b= tag must be last and empty.
+func (s *ARCSigner) sign(field, data string) (string, error) {
+	digest := sha256.Sum256([]byte(data))
+	var opts crypto.SignerOpts = crypto.SHA256
+	if s.algorithm == "ed25519-sha256" {
+		// RFC 8463 signs the SHA-256 digest itself with PureEdDSA.
+		opts = crypto.Hash(0)
+	}
+	sig, err := s.key.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		return "", err
+	}
+	return field + base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func checkInstance(instance int) error {
+	if instance < 1 || instance > MaxARCInstance {
+		return fmt.Errorf("ARC instance %d is not between 1 and %d", instance, MaxARCInstance)
+	}
+	return nil
+}
+
+// splitMessage splits a message into its header fields, keeping folded lines, and its body.
+func splitMessage(message []byte) ([]string, []byte) {
+	header, body, _ := strings.Cut(string(message), "\r\n\r\n")
+	var fields []string
+	for _, line := range strings.Split(header, "\r\n") {
+		if len(fields) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+		if line != "" {
+			fields = append(fields, line)
+		}
+	}
+	return fields, []byte(body)
+}
+
+// selectHeaderFields returns the header fields signed for the given names. As defined in RFC 6376
+// section 5.4.2, repeated names select fields from the bottom of the header up.
+func selectHeaderFields(fields, names []string) []string {
+	used := make([]bool, len(fields))
+	var res []string
+	for _, name := range names {
+		for i := len(fields) - 1; i >= 0; i-- {
+			fieldName, _, _ := strings.Cut(fields[i], ":")
+			if used[i] || !strings.EqualFold(strings.TrimRight(fieldName, " \t"), name) {
+				continue
+			}
+			used[i] = true
+			res = append(res, fields[i])
+			break
+		}
+	}
+	return res
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// canonicalizeHeaderRelaxed applies the relaxed header canonicalization of RFC 6376 section 3.4.2.
+func canonicalizeHeaderRelaxed(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	name = strings.ToLower(strings.TrimRight(name, " \t"))
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return name + ":" + strings.Join(strings.FieldsFunc(value, isWSP), " ") + "\r\n"
+}
+
+// canonicalizeBodyRelaxed applies the relaxed body canonicalization of RFC 6376 section 3.4.4.
+func canonicalizeBodyRelaxed(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		var b strings.Builder
+		pending := false
+		for _, r := range line {
+			if isWSP(r) {
+				pending = true
+				continue
+			}
+			if pending {
+				b.WriteByte(' ')
+				pending = false
+			}
+			b.WriteRune(r)
+		}
+		lines[i] = b.String()
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
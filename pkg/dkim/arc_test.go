@@ -0,0 +1,166 @@
+package dkim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const arcTestMessage = "Received: from a.example.org\r\n" +
+	"From: Alice <alice@example.org>\r\n" +
+	"To: list@example.com\r\n" +
+	"Subject: Hello\r\n" +
+	"\tworld\r\n" +
+	"Received: from b.example.org\r\n" +
+	"\r\n" +
+	"Hi  there \r\n" +
+	"\r\n" +
+	"\r\n"
+
+func TestCanonicalizeRelaxed(t *testing.T) {
+	t.Parallel()
+	// Example from RFC 6376 section 3.4.5.
+	fields, body := splitMessage([]byte("A: X\r\nB : Y\t\r\n\tZ  \r\n\r\n C \r\nD \t E\r\n\r\n\r\n"))
+	var header strings.Builder
+	for _, f := range fields {
+		header.WriteString(canonicalizeHeaderRelaxed(f))
+	}
+	assert.Equal(t, "a:X\r\nb:Y Z\r\n", header.String())
+	assert.Equal(t, " C\r\nD E\r\n", string(canonicalizeBodyRelaxed(body)))
+	assert.Empty(t, canonicalizeBodyRelaxed([]byte("\r\n\r\n")))
+}
+
+func TestSelectHeaderFields(t *testing.T) {
+	t.Parallel()
+	fields, _ := splitMessage([]byte(arcTestMessage))
+	selected := selectHeaderFields(fields, []string{"from", "received", "received", "received", "subject"})
+	assert.Equal(t, []string{
+		"From: Alice <alice@example.org>",
+		"Received: from b.example.org",
+		"Received: from a.example.org",
+		"Subject: Hello\r\n\tworld",
+	}, selected)
+}
+
+// verifyARC checks the signature in the b= tag, which must be last, of an ARC header field over the data
+// preceding the header field.
+func verifyARC(t *testing.T, pub crypto.PublicKey, data, field string) {
+	t.Helper()
+	i := strings.LastIndex(field, "b=")
+	if !assert.NotEqual(t, -1, i) {
+		return
+	}
+	sig, err := base64.StdEncoding.DecodeString(field[i+2:])
+	assert.NoError(t, err)
+	data += strings.TrimSuffix(canonicalizeHeaderRelaxed(field[:i+2]), "\r\n")
+	digest := sha256.Sum256([]byte(data))
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		assert.NoError(t, rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig))
+	case ed25519.PublicKey:
+		assert.True(t, ed25519.Verify(k, digest[:], sig))
+	default:
+		t.Fatalf("unexpected public key type %T", pub)
+	}
+}
+
+func TestARCSigner(t *testing.T) {
+	t.Parallel()
+	rsaPriv, rsaPub, err := GenRSA(KeyLength2048)
+	assert.NoError(t, err)
+	edPriv, edPub, err := GenED25519()
+	assert.NoError(t, err)
+
+	cases := []struct {
+		title     string
+		priv      []byte
+		pub       string
+		algorithm string
+	}{
+		{title: "RSA", priv: rsaPriv, pub: rsaPub, algorithm: "rsa-sha256"},
+		{title: "ED25519", priv: edPriv, pub: edPub, algorithm: "ed25519-sha256"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewARCSigner("example.com", "arc1", tc.priv)
+			assert.NoError(t, err)
+			der, err := base64.StdEncoding.DecodeString(tc.pub)
+			assert.NoError(t, err)
+			pub, err := x509.ParsePKIXPublicKey(der)
+			assert.NoError(t, err)
+			now := time.Unix(1700000000, 0)
+
+			aar := ARCAuthenticationResults(1, "lists.example.com", "spf=pass smtp.mailfrom=example.org")
+			assert.Equal(t, "ARC-Authentication-Results: i=1; lists.example.com; spf=pass smtp.mailfrom=example.org", aar)
+
+			headers := []string{"From", "To", "Subject"}
+			ams, err := s.MessageSignature(1, []byte(arcTestMessage), headers, now)
+			assert.NoError(t, err)
+			bh := sha256.Sum256([]byte("Hi there\r\n"))
+			assert.True(t, strings.HasPrefix(ams, "ARC-Message-Signature: i=1; a="+tc.algorithm+
+				"; c=relaxed/relaxed; d=example.com; s=arc1; t=1700000000; h=from:to:subject; bh="+
+				base64.StdEncoding.EncodeToString(bh[:])+"; b="), ams)
+			verifyARC(t, pub, "from:Alice <alice@example.org>\r\nto:list@example.com\r\nsubject:Hello world\r\n", ams)
+
+			sets := []ARCSet{{AuthenticationResults: aar, MessageSignature: ams}}
+			seal, err := s.Seal(sets, ChainValidationNone, now)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(seal, "ARC-Seal: i=1; a="+tc.algorithm+
+				"; t=1700000000; cv=none; d=example.com; s=arc1; b="), seal)
+			verifyARC(t, pub, canonicalizeHeaderRelaxed(aar)+canonicalizeHeaderRelaxed(ams), seal)
+
+			sets[0].Seal = seal
+			aar2 := ARCAuthenticationResults(2, "lists.example.com", "arc=pass")
+			ams2, err := s.MessageSignature(2, []byte(arcTestMessage), headers, now)
+			assert.NoError(t, err)
+			sets = append(sets, ARCSet{AuthenticationResults: aar2, MessageSignature: ams2})
+			seal2, err := s.Seal(sets, ChainValidationPass, now)
+			assert.NoError(t, err)
+			verifyARC(t, pub, canonicalizeHeaderRelaxed(aar)+canonicalizeHeaderRelaxed(ams)+canonicalizeHeaderRelaxed(seal)+
+				canonicalizeHeaderRelaxed(aar2)+canonicalizeHeaderRelaxed(ams2), seal2)
+		})
+	}
+}
+
+func TestARCSignerErrors(t *testing.T) {
+	t.Parallel()
+	_, err := NewARCSigner("example.com", "arc1", []byte("not a key"))
+	assert.Error(t, err)
+
+	priv, _, err := GenED25519()
+	assert.NoError(t, err)
+	s, err := NewARCSigner("example.com", "arc1", priv)
+	assert.NoError(t, err)
+	now := time.Now()
+	msg := []byte(arcTestMessage)
+
+	_, err = s.MessageSignature(0, msg, []string{"From"}, now)
+	assert.Error(t, err)
+	_, err = s.MessageSignature(MaxARCInstance+1, msg, []string{"From"}, now)
+	assert.Error(t, err)
+	_, err = s.MessageSignature(1, msg, []string{"To"}, now)
+	assert.Error(t, err)
+	_, err = s.MessageSignature(1, msg, []string{"From", "ARC-Seal"}, now)
+	assert.Error(t, err)
+
+	set := ARCSet{AuthenticationResults: "ARC-Authentication-Results: i=1; a; none", MessageSignature: "ARC-Message-Signature: i=1; b=x"}
+	_, err = s.Seal(nil, ChainValidationNone, now)
+	assert.Error(t, err)
+	_, err = s.Seal([]ARCSet{set}, ChainValidationPass, now)
+	assert.Error(t, err)
+	_, err = s.Seal([]ARCSet{set, set}, ChainValidationNone, now)
+	assert.Error(t, err)
+	_, err = s.Seal([]ARCSet{set, set}, ChainValidationPass, now)
+	assert.Error(t, err, "previous set is not sealed")
+	_, err = s.Seal([]ARCSet{{AuthenticationResults: set.AuthenticationResults}}, ChainValidationNone, now)
+	assert.Error(t, err)
+}
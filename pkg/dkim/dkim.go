@@ -14,6 +14,9 @@ import (
 type KeyLength uint
 type KeyType string
 
+// KeyPurpose is what a key is used for. ARC keys are published in the same format as DKIM keys.
+type KeyPurpose string
+
 const (
 	KeyLength1024 KeyLength = 1024
 	KeyLength2048 KeyLength = 2048
@@ -21,6 +24,9 @@ const (
 
 	KeyTypeRSA     KeyType = "rsa"
 	KeyTypeED25519 KeyType = "ed25519"
+
+	KeyPurposeDKIM KeyPurpose = "dkim"
+	KeyPurposeARC  KeyPurpose = "arc"
 )
 
 // GenRSA generates an RSA key pair.
@@ -63,13 +69,47 @@ func GenED25519() ([]byte, string, error) {
 	return key, base64.StdEncoding.EncodeToString(pub), nil
 }
 
-// DeriveRSAPublicKey computes the RSA public key from the private key.
-func DeriveRSAPublicKey(priv []byte, size KeyLength) (string, error) {
+// GenKeyPair generates a key pair of the given type. The key length only applies to RSA keys.
+func GenKeyPair(keyType KeyType, keyLength KeyLength) ([]byte, string, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		return GenRSA(keyLength)
+	case KeyTypeED25519:
+		return GenED25519()
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+// parseRSAPrivateKey decodes a PEM-encoded PKCS #1 RSA private key, as generated by GenRSA.
+func parseRSAPrivateKey(priv []byte) (*rsa.PrivateKey, error) {
 	block, _ := pem.Decode(priv)
 	if block == nil || block.Type != "RSA PRIVATE KEY" {
-		return "", fmt.Errorf("failed to decode PEM block containing RSA private key")
+		return nil, fmt.Errorf("failed to decode PEM block containing RSA private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// parseED25519PrivateKey decodes a PEM-encoded PKCS #8 ed25519 private key, as generated by GenED25519.
+func parseED25519PrivateKey(priv []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(priv)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("failed to decode PEM block containing ed25519 private key")
 	}
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an ed25519 private key")
+	}
+	return privKey, nil
+}
+
+// DeriveRSAPublicKey computes the RSA public key from the private key.
+func DeriveRSAPublicKey(priv []byte, size KeyLength) (string, error) {
+	privateKey, err := parseRSAPrivateKey(priv)
 	if err != nil {
 		return "", err
 	}
@@ -85,18 +125,10 @@ func DeriveRSAPublicKey(priv []byte, size KeyLength) (string, error) {
 
 // DeriveED25519PublicKey computes the ed25519 public key from the private key.
 func DeriveED25519PublicKey(priv []byte) (string, error) {
-	block, _ := pem.Decode(priv)
-	if block == nil || block.Type != "PRIVATE KEY" {
-		return "", fmt.Errorf("failed to decode PEM block containing ed25519 private key")
-	}
-	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	privKey, err := parseED25519PrivateKey(priv)
 	if err != nil {
 		return "", err
 	}
-	privKey, ok := key.(ed25519.PrivateKey)
-	if !ok {
-		return "", fmt.Errorf("not an ed25519 private key")
-	}
 	pub, err := x509.MarshalPKIXPublicKey(privKey.Public().(ed25519.PublicKey))
 	if err != nil {
 		return "", err
@@ -104,24 +136,160 @@ func DeriveED25519PublicKey(priv []byte) (string, error) {
 	return base64.StdEncoding.EncodeToString(pub), nil
 }
 
+// DetectKeyType returns the type and length of a private key in the format generated by GenRSA
+// or GenED25519, as expected by DerivePublicKey. The length of ed25519 keys is 0.
+func DetectKeyType(priv []byte) (KeyType, KeyLength, error) {
+	if key, err := parseRSAPrivateKey(priv); err == nil {
+		return KeyTypeRSA, KeyLength(key.N.BitLen()), nil
+	}
+	if _, err := parseED25519PrivateKey(priv); err == nil {
+		return KeyTypeED25519, 0, nil
+	}
+	return "", 0, fmt.Errorf("not an RSA or ed25519 private key")
+}
+
+// NormalizePrivateKey re-encodes a private key in the format generated by GenRSA or GenED25519.
+// Besides that format, PKCS #8 RSA keys and base64-encoded DER keys without PEM armor, as accepted
+// by OpenDKIM, are supported. The key itself is unchanged, and so is its public key.
+func NormalizePrivateKey(priv []byte) ([]byte, error) {
+	der, err := privateKeyDER(priv)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported private key: %w", err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	case ed25519.PrivateKey:
+		data, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// privateKeyDER returns the DER encoding of a PEM-encoded or base64-encoded private key.
+func privateKeyDER(priv []byte) ([]byte, error) {
+	if block, _ := pem.Decode(priv); block != nil {
+		if block.Type != "RSA PRIVATE KEY" && block.Type != "PRIVATE KEY" {
+			return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+		}
+		return block.Bytes, nil
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(priv)), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+	return der, nil
+}
+
+// DerivePublicKey computes the public key of the given type from the private key.
+func DerivePublicKey(priv []byte, keyType KeyType, keyLength KeyLength) (string, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		return DeriveRSAPublicKey(priv, keyLength)
+	case KeyTypeED25519:
+		return DeriveED25519PublicKey(priv)
+	default:
+		return "", fmt.Errorf("unsupported key type %q", keyType)
+	}
+}
+
+// Tags are the optional tags of a DKIM key record, as defined in RFC 6376 section 3.6.1.
+type Tags struct {
+	// HashAlgorithms are the acceptable hash algorithms (h=). Defaults to sha256 for RSA keys,
+	// and is omitted for ed25519 keys.
+	HashAlgorithms []string
+	// ServiceTypes are the service types the key applies to (s=), e.g. email. Omitted by default.
+	ServiceTypes []string
+	// Testing marks the domain as testing DKIM (t=y), so that verifiers treat failures leniently.
+	Testing bool
+	// Strict forbids using the key for subdomains of the signing domain (t=s).
+	Strict bool
+	// Notes are human-readable notes (n=).
+	Notes string
+}
+
 // GenTXTValue generates the DKIM record for the given public key.
 func GenTXTValue(pub string, keyType KeyType) string {
-	res := []string{}
-	p := fmt.Sprintf("p=%s", pub)
+	return GenTXTValueWithTags(pub, keyType, Tags{})
+}
+
+// GenTXTValueWithTags generates the DKIM record for the given public key, including the given tags.
+func GenTXTValueWithTags(pub string, keyType KeyType, tags Tags) string {
+	hashAlgorithms := tags.HashAlgorithms
 	switch keyType {
 	case KeyTypeRSA:
-		res = append(res, fmt.Sprintf("\"v=DKIM1; h=sha256; k=%s;\"", keyType))
+		if len(hashAlgorithms) == 0 {
+			hashAlgorithms = []string{"sha256"}
+		}
 	case KeyTypeED25519:
-		res = append(res, fmt.Sprintf("\"v=DKIM1; k=%s;\"", keyType))
 	default:
 		return ""
 	}
 
-	res = append(res, splitKey(p)...)
+	header := []string{"v=DKIM1"}
+	if len(hashAlgorithms) > 0 {
+		header = append(header, "h="+strings.Join(hashAlgorithms, ":"))
+	}
+	header = append(header, fmt.Sprintf("k=%s", keyType))
+	if len(tags.ServiceTypes) > 0 {
+		header = append(header, "s="+strings.Join(tags.ServiceTypes, ":"))
+	}
+	var flags []string
+	if tags.Testing {
+		flags = append(flags, "y")
+	}
+	if tags.Strict {
+		flags = append(flags, "s")
+	}
+	if len(flags) > 0 {
+		header = append(header, "t="+strings.Join(flags, ":"))
+	}
+	if tags.Notes != "" {
+		header = append(header, "n="+encodeQPSection(tags.Notes))
+	}
+
+	res := splitKey(strings.Join(header, "; ") + ";")
+	res = append(res, splitKey(fmt.Sprintf("p=%s", pub))...)
 
 	return strings.Join(res, " ")
 }
 
+// GenRevokedTXTValue generates the DKIM record of a revoked key, whose public key is empty,
+// as defined in RFC 6376 section 3.6.1.
+func GenRevokedTXTValue(keyType KeyType, tags Tags) string {
+	return GenTXTValueWithTags("", keyType, tags)
+}
+
+// encodeQPSection encodes a tag value as a DKIM quoted-printable section, as defined in RFC 6376
+// section 2.11. Characters which would need escaping in TXT records are encoded as well.
+func encodeQPSection(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		trailing := i == len(s)-1
+		switch {
+		case c == ' ' && !trailing:
+			b.WriteByte(c)
+		case c > ' ' && c <= '~' && c != ';' && c != '=' && c != '"' && c != '\\':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "=%02X", c)
+		}
+	}
+	return b.String()
+}
+
 func splitKey(pub string) []string {
 	var res []string
 	parts := len(pub) / 255
@@ -137,3 +305,76 @@ func splitKey(pub string) []string {
 	}
 	return res
 }
+
+// SplitTXTValue splits a TXT record value in presentation format, such as the output of GenTXTValue,
+// into its character-strings. Unquoted values are returned as a single character-string.
+func SplitTXTValue(value string) []string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "\"") {
+		return []string{value}
+	}
+	var res []string
+	var cur strings.Builder
+	inQuotes, escaped := false, false
+	for _, c := range value {
+		switch {
+		case escaped:
+			cur.WriteRune(c)
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			if inQuotes {
+				res = append(res, cur.String())
+				cur.Reset()
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			cur.WriteRune(c)
+		}
+	}
+	if inQuotes {
+		res = append(res, cur.String())
+	}
+	return res
+}
+
+// JoinTXTValue reassembles a TXT record value in presentation format into the single string
+// seen by DKIM verifiers, which concatenate all character-strings of the record.
+func JoinTXTValue(value string) string {
+	return strings.Join(SplitTXTValue(value), "")
+}
+
+// GenZoneRecord formats a TXT record as a line of a BIND zone file. The value is in presentation
+// format, such as the output of GenTXTValue, and long character-strings are kept split.
+func GenZoneRecord(name string, ttl uint, value string) string {
+	parts := SplitTXTValue(value)
+	quoted := make([]string, len(parts))
+	for i, p := range parts {
+		quoted[i] = quoteTXTString(p)
+	}
+	return fmt.Sprintf("%s.\t%d\tIN\tTXT\t%s", strings.TrimSuffix(name, "."), ttl, strings.Join(quoted, " "))
+}
+
+// GenCNAMEZoneRecord formats a CNAME record, such as the record of a delegated DKIM key,
+// as a line of a BIND zone file.
+func GenCNAMEZoneRecord(name string, ttl uint, target string) string {
+	return fmt.Sprintf("%s.\t%d\tIN\tCNAME\t%s.", strings.TrimSuffix(name, "."), ttl, strings.TrimSuffix(target, "."))
+}
+
+// FormatTXTValue formats an arbitrary TXT record value in presentation format, split into
+// character-strings of at most 255 characters, as expected by publishers.
+func FormatTXTValue(value string) string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, quoteTXTString(value[:255]))
+		value = value[255:]
+	}
+	parts = append(parts, quoteTXTString(value))
+	return strings.Join(parts, " ")
+}
+
+func quoteTXTString(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "\"" + strings.ReplaceAll(s, "\"", "\\\"") + "\""
+}
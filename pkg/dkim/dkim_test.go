@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"strings"
 	"testing"
 
@@ -30,6 +31,19 @@ func TestGenED25519(t *testing.T) {
 	assert.Equal(t, pub, derivedPub)
 }
 
+func TestGenKeyPair(t *testing.T) {
+	t.Parallel()
+	for _, keyType := range []KeyType{KeyTypeRSA, KeyTypeED25519} {
+		priv, pub, err := GenKeyPair(keyType, KeyLength1024)
+		assert.NoError(t, err)
+		derivedPub, err := DerivePublicKey(priv, keyType, KeyLength1024)
+		assert.NoError(t, err)
+		assert.Equal(t, pub, derivedPub)
+	}
+	_, _, err := GenKeyPair("dsa", KeyLength2048)
+	assert.Error(t, err)
+}
+
 func TestDeriveRSAPublicKey(t *testing.T) {
 	t.Parallel()
 
@@ -170,6 +184,121 @@ BGcscISjwRvQHrE6JigS1SMVHQ==
 	}
 }
 
+func TestDerivePublicKey(t *testing.T) {
+	t.Parallel()
+
+	rsaPriv, rsaPub, err := GenRSA(KeyLength2048)
+	assert.NoError(t, err)
+	edPriv, edPub, err := GenED25519()
+	assert.NoError(t, err)
+
+	pub, err := DerivePublicKey(rsaPriv, KeyTypeRSA, KeyLength2048)
+	assert.NoError(t, err)
+	assert.Equal(t, rsaPub, pub)
+
+	pub, err = DerivePublicKey(edPriv, KeyTypeED25519, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, edPub, pub)
+
+	_, err = DerivePublicKey(edPriv, KeyTypeRSA, KeyLength2048)
+	assert.Error(t, err)
+	_, err = DerivePublicKey(rsaPriv, "dsa", KeyLength2048)
+	assert.Error(t, err)
+}
+
+func TestDetectKeyType(t *testing.T) {
+	t.Parallel()
+
+	rsaPriv, _, err := GenRSA(KeyLength1024)
+	assert.NoError(t, err)
+	edPriv, _, err := GenED25519()
+	assert.NoError(t, err)
+
+	keyType, keyLength, err := DetectKeyType(rsaPriv)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyTypeRSA, keyType)
+	assert.Equal(t, KeyLength1024, keyLength)
+
+	keyType, keyLength, err = DetectKeyType(edPriv)
+	assert.NoError(t, err)
+	assert.Equal(t, KeyTypeED25519, keyType)
+	assert.Equal(t, KeyLength(0), keyLength)
+
+	_, _, err = DetectKeyType([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestNormalizePrivateKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, int(KeyLength1024))
+	assert.NoError(t, err)
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	assert.NoError(t, err)
+	rsaPriv := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	rsaPub, err := DeriveRSAPublicKey(rsaPriv, KeyLength1024)
+	assert.NoError(t, err)
+	edPriv, edPub, err := GenED25519()
+	assert.NoError(t, err)
+	edBlock, _ := pem.Decode(edPriv)
+
+	cases := []struct {
+		title       string
+		priv        []byte
+		keyType     KeyType
+		expectedPub string
+	}{
+		{
+			title:       "PKCS1RSA",
+			priv:        rsaPriv,
+			keyType:     KeyTypeRSA,
+			expectedPub: rsaPub,
+		},
+		{
+			title:       "PKCS8RSA",
+			priv:        pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaPKCS8}),
+			keyType:     KeyTypeRSA,
+			expectedPub: rsaPub,
+		},
+		{
+			title:       "Base64RSA",
+			priv:        []byte(base64.StdEncoding.EncodeToString(rsaPKCS8)[:64] + "\n" + base64.StdEncoding.EncodeToString(rsaPKCS8)[64:] + "\n"),
+			keyType:     KeyTypeRSA,
+			expectedPub: rsaPub,
+		},
+		{
+			title:       "ED25519",
+			priv:        edPriv,
+			keyType:     KeyTypeED25519,
+			expectedPub: edPub,
+		},
+		{
+			title:       "Base64ED25519",
+			priv:        []byte(base64.StdEncoding.EncodeToString(edBlock.Bytes)),
+			keyType:     KeyTypeED25519,
+			expectedPub: edPub,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			priv, err := NormalizePrivateKey(tc.priv)
+			assert.NoError(t, err)
+			keyType, keyLength, err := DetectKeyType(priv)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.keyType, keyType)
+			pub, err := DerivePublicKey(priv, keyType, keyLength)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPub, pub)
+		})
+	}
+
+	_, err = NormalizePrivateKey([]byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"))
+	assert.Error(t, err)
+	_, err = NormalizePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
 func TestGenTXTValueRSA(t *testing.T) {
 	t.Parallel()
 	key, err := rsa.GenerateKey(rand.Reader, int(KeyLength2048))
@@ -246,3 +375,155 @@ func TestSplitKey(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitTXTValue(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title    string
+		value    string
+		expected []string
+	}{
+		{
+			title:    "Unquoted",
+			value:    "v=DKIM1; k=ed25519; p=abc",
+			expected: []string{"v=DKIM1; k=ed25519; p=abc"},
+		},
+		{
+			title:    "MultipleStrings",
+			value:    "\"v=DKIM1; k=rsa;\" \"p=abc\" \"def\"",
+			expected: []string{"v=DKIM1; k=rsa;", "p=abc", "def"},
+		},
+		{
+			title:    "EscapedQuote",
+			value:    "\"n=say \\\"hi\\\"\"",
+			expected: []string{"n=say \"hi\""},
+		},
+		{
+			title:    "Unterminated",
+			value:    "\"p=abc",
+			expected: []string{"p=abc"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, SplitTXTValue(tc.value))
+		})
+	}
+}
+
+func TestJoinTXTValue(t *testing.T) {
+	t.Parallel()
+	pub := strings.Repeat("a", 300)
+	value := GenTXTValue(pub, KeyTypeRSA)
+	assert.Equal(t, "v=DKIM1; h=sha256; k=rsa;p="+pub, JoinTXTValue(value))
+}
+
+func TestGenZoneRecord(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title    string
+		name     string
+		value    string
+		expected string
+	}{
+		{
+			title:    "Quoted",
+			name:     "selector1._domainkey.example.com",
+			value:    GenTXTValue("abc", KeyTypeED25519),
+			expected: "selector1._domainkey.example.com.\t3600\tIN\tTXT\t\"v=DKIM1; k=ed25519;\" \"p=abc\"",
+		},
+		{
+			title:    "Unquoted",
+			name:     "selector1._domainkey.example.com.",
+			value:    "v=DKIM1; k=ed25519; p=abc",
+			expected: "selector1._domainkey.example.com.\t3600\tIN\tTXT\t\"v=DKIM1; k=ed25519; p=abc\"",
+		},
+		{
+			title:    "Escaped",
+			name:     "selector1._domainkey.example.com",
+			value:    "\"n=say \\\"hi\\\"\"",
+			expected: "selector1._domainkey.example.com.\t3600\tIN\tTXT\t\"n=say \\\"hi\\\"\"",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, GenZoneRecord(tc.name, 3600, tc.value))
+		})
+	}
+}
+
+func TestGenTXTValueWithTags(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title    string
+		keyType  KeyType
+		tags     Tags
+		expected string
+	}{
+		{
+			title:    "NoTags",
+			keyType:  KeyTypeRSA,
+			expected: "\"v=DKIM1; h=sha256; k=rsa;\" \"p=abc\"",
+		},
+		{
+			title:    "Testing",
+			keyType:  KeyTypeED25519,
+			tags:     Tags{Testing: true},
+			expected: "\"v=DKIM1; k=ed25519; t=y;\" \"p=abc\"",
+		},
+		{
+			title:    "TestingAndStrict",
+			keyType:  KeyTypeRSA,
+			tags:     Tags{Testing: true, Strict: true, ServiceTypes: []string{"email"}},
+			expected: "\"v=DKIM1; h=sha256; k=rsa; s=email; t=y:s;\" \"p=abc\"",
+		},
+		{
+			title:    "HashAlgorithms",
+			keyType:  KeyTypeRSA,
+			tags:     Tags{HashAlgorithms: []string{"sha1", "sha256"}},
+			expected: "\"v=DKIM1; h=sha1:sha256; k=rsa;\" \"p=abc\"",
+		},
+		{
+			title:    "Notes",
+			keyType:  KeyTypeED25519,
+			tags:     Tags{Notes: "contact a=b; \"ops\" "},
+			expected: "\"v=DKIM1; k=ed25519; n=contact a=3Db=3B =22ops=22=20;\" \"p=abc\"",
+		},
+		{
+			title:   "UnknownKeyType",
+			keyType: KeyType("dsa"),
+			tags:    Tags{Testing: true},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, GenTXTValueWithTags("abc", tc.keyType, tc.tags))
+		})
+	}
+}
+
+func TestGenCNAMEZoneRecord(t *testing.T) {
+	t.Parallel()
+	expected := "sel1._domainkey.example.com.\t3600\tIN\tCNAME\tsel1._domainkey.example.com.dkim.example.net."
+	assert.Equal(t, expected, GenCNAMEZoneRecord("sel1._domainkey.example.com", 3600, "sel1._domainkey.example.com.dkim.example.net"))
+	assert.Equal(t, expected, GenCNAMEZoneRecord("sel1._domainkey.example.com.", 3600, "sel1._domainkey.example.com.dkim.example.net."))
+}
+
+func TestFormatTXTValue(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"v=DMARC1; p=none;"`, FormatTXTValue("v=DMARC1; p=none;"))
+	assert.Equal(t, `"say \"hi\" \\"`, FormatTXTValue(`say "hi" \`))
+	long := strings.Repeat("a", 300)
+	value := FormatTXTValue(long)
+	assert.Equal(t, []string{strings.Repeat("a", 255), strings.Repeat("a", 45)}, SplitTXTValue(value))
+	assert.Equal(t, long, JoinTXTValue(value))
+}
+
+func TestGenRevokedTXTValue(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, `"v=DKIM1; h=sha256; k=rsa;" "p="`, GenRevokedTXTValue(KeyTypeRSA, Tags{}))
+	assert.Equal(t, `"v=DKIM1; k=ed25519; t=y;" "p="`, GenRevokedTXTValue(KeyTypeED25519, Tags{Testing: true}))
+}
@@ -0,0 +1,104 @@
+package dkim
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxLabelLength   = 63
+	maxDNSNameLength = 253
+)
+
+// RecordName returns the DNS name under which the DKIM record for the given selector and domain is published.
+func RecordName(selector, domain string) string {
+	return fmt.Sprintf("%s._domainkey.%s", selector, domain)
+}
+
+// DelegatedRecordName returns the DNS name, under the delegation zone, of the TXT record
+// the DKIM record name of the given selector and domain points to when delegated.
+func DelegatedRecordName(selector, domain, zone string) string {
+	return fmt.Sprintf("%s.%s", RecordName(selector, domain), zone)
+}
+
+// PrivateKeyFilename returns the name of the Secret entry containing the private key for the given selector and domain.
+func PrivateKeyFilename(selector, domain string) string {
+	return fmt.Sprintf("%s.%s.key", domain, selector)
+}
+
+// ValidateSelector checks that the selector is a valid DKIM selector, i.e. one or more DNS labels.
+func ValidateSelector(selector string) error {
+	if selector == "" {
+		return fmt.Errorf("selector must not be empty")
+	}
+	if err := validateName(selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return nil
+}
+
+// ValidateDomain checks that the domain is a valid DNS name with at least two labels.
+func ValidateDomain(domain string) error {
+	if domain == "" {
+		return fmt.Errorf("domain must not be empty")
+	}
+	if err := validateName(domain); err != nil {
+		return fmt.Errorf("invalid domain %q: %w", domain, err)
+	}
+	if !strings.Contains(domain, ".") {
+		return fmt.Errorf("invalid domain %q: must contain at least two labels", domain)
+	}
+	return nil
+}
+
+// ValidateRecordName checks that the full DKIM record name fits in a DNS name.
+func ValidateRecordName(selector, domain string) error {
+	return validateNameLength(RecordName(selector, domain))
+}
+
+// ValidateDelegatedRecordName checks that the delegation zone is a valid DNS name,
+// and that the delegated record name fits in a DNS name.
+func ValidateDelegatedRecordName(selector, domain, zone string) error {
+	if err := ValidateDomain(zone); err != nil {
+		return fmt.Errorf("invalid delegation zone: %w", err)
+	}
+	return validateNameLength(DelegatedRecordName(selector, domain, zone))
+}
+
+func validateNameLength(name string) error {
+	if l := len(name); l > maxDNSNameLength {
+		return fmt.Errorf("record name %s is %d characters long, exceeding the maximum of %d", name, l, maxDNSNameLength)
+	}
+	return nil
+}
+
+func validateName(name string) error {
+	for _, label := range strings.Split(name, ".") {
+		if err := validateLabel(label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateLabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("empty label")
+	}
+	if len(label) > maxLabelLength {
+		return fmt.Errorf("label %q exceeds %d characters", label, maxLabelLength)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("label %q must not start or end with a hyphen", label)
+	}
+	for _, c := range label {
+		if !isLetterOrDigit(c) && c != '-' {
+			return fmt.Errorf("label %q contains invalid character %q", label, c)
+		}
+	}
+	return nil
+}
+
+func isLetterOrDigit(c rune) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
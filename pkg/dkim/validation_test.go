@@ -0,0 +1,79 @@
+package dkim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSelector(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		title    string
+		selector string
+		errFunc  assert.ErrorAssertionFunc
+	}{
+		{title: "Simple", selector: "selector1", errFunc: assert.NoError},
+		{title: "MultipleLabels", selector: "2024.mail", errFunc: assert.NoError},
+		{title: "Hyphen", selector: "s-1", errFunc: assert.NoError},
+		{title: "Empty", selector: "", errFunc: assert.Error},
+		{title: "LeadingHyphen", selector: "-s1", errFunc: assert.Error},
+		{title: "Underscore", selector: "s_1", errFunc: assert.Error},
+		{title: "EmptyLabel", selector: "s1..mail", errFunc: assert.Error},
+		{title: "LongLabel", selector: strings.Repeat("a", 64), errFunc: assert.Error},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			tc.errFunc(t, ValidateSelector(tc.selector))
+		})
+	}
+}
+
+func TestValidateDomain(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		title   string
+		domain  string
+		errFunc assert.ErrorAssertionFunc
+	}{
+		{title: "Simple", domain: "example.com", errFunc: assert.NoError},
+		{title: "Subdomain", domain: "dkim.Example.com", errFunc: assert.NoError},
+		{title: "Empty", domain: "", errFunc: assert.Error},
+		{title: "SingleLabel", domain: "localhost", errFunc: assert.Error},
+		{title: "TrailingDot", domain: "example.com.", errFunc: assert.Error},
+		{title: "InvalidCharacter", domain: "exa mple.com", errFunc: assert.Error},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			tc.errFunc(t, ValidateDomain(tc.domain))
+		})
+	}
+}
+
+func TestValidateRecordName(t *testing.T) {
+	t.Parallel()
+
+	label := strings.Repeat("a", 63)
+	longDomain := strings.Join([]string{label, label, label, "com"}, ".")
+	assert.NoError(t, ValidateRecordName("selector1", "example.com"))
+	assert.NoError(t, ValidateRecordName("selector1", longDomain))
+	assert.Error(t, ValidateRecordName(label, longDomain))
+}
+
+func TestValidateDelegatedRecordName(t *testing.T) {
+	t.Parallel()
+
+	label := strings.Repeat("a", 63)
+	longDomain := strings.Join([]string{label, label, label, "com"}, ".")
+	assert.Equal(t, "selector1._domainkey.example.com.dkim.example.net", DelegatedRecordName("selector1", "example.com", "dkim.example.net"))
+	assert.NoError(t, ValidateDelegatedRecordName("selector1", "example.com", "dkim.example.net"))
+	assert.NoError(t, ValidateDelegatedRecordName("selector1", longDomain, "dkim.example.net"))
+	assert.Error(t, ValidateDelegatedRecordName("selector1", longDomain, label+".example.net"))
+	assert.Error(t, ValidateDelegatedRecordName("selector1", "example.com", "dkim_example.net"))
+	assert.Error(t, ValidateDelegatedRecordName("selector1", "example.com", "net"))
+}
@@ -0,0 +1,167 @@
+package dmarc
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// Policy is the action requested from receivers for messages failing DMARC checks.
+type Policy string
+
+// Alignment is an identifier alignment mode.
+type Alignment string
+
+const (
+	PolicyNone       Policy = "none"
+	PolicyQuarantine Policy = "quarantine"
+	PolicyReject     Policy = "reject"
+
+	AlignmentRelaxed Alignment = "relaxed"
+	AlignmentStrict  Alignment = "strict"
+)
+
+var (
+	policies                = []Policy{PolicyNone, PolicyQuarantine, PolicyReject}
+	alignments              = []Alignment{AlignmentRelaxed, AlignmentStrict}
+	failureReportingOptions = []string{"0", "1", "d", "s"}
+)
+
+// Record holds the tags of a DMARC record, as defined in RFC 7489 section 6.3.
+type Record struct {
+	// Policy is the policy for the domain (p=).
+	Policy Policy
+	// SubdomainPolicy is the policy for subdomains (sp=). Omitted by default, in which case Policy applies.
+	SubdomainPolicy Policy
+	// Percentage is the percentage of failing messages the policy applies to (pct=). Omitted when nil.
+	Percentage *int
+	// AggregateReportURIs are the URIs aggregate reports are sent to (rua=).
+	AggregateReportURIs []string
+	// FailureReportURIs are the URIs failure reports are sent to (ruf=).
+	FailureReportURIs []string
+	// DKIMAlignment is the DKIM identifier alignment mode (adkim=). Omitted by default, i.e. relaxed.
+	DKIMAlignment Alignment
+	// SPFAlignment is the SPF identifier alignment mode (aspf=). Omitted by default, i.e. relaxed.
+	SPFAlignment Alignment
+	// FailureReportingOptions select the failures that trigger failure reports (fo=), among 0, 1, d and s.
+	FailureReportingOptions []string
+}
+
+// RecordName returns the DNS name under which the DMARC record of the domain is published.
+func RecordName(domain string) string {
+	return "_dmarc." + domain
+}
+
+// Validate checks that the record only contains valid tag values.
+func (r Record) Validate() error {
+	if !slices.Contains(policies, r.Policy) {
+		return fmt.Errorf("invalid policy %q", r.Policy)
+	}
+	if r.SubdomainPolicy != "" && !slices.Contains(policies, r.SubdomainPolicy) {
+		return fmt.Errorf("invalid subdomain policy %q", r.SubdomainPolicy)
+	}
+	if r.Percentage != nil && (*r.Percentage < 0 || *r.Percentage > 100) {
+		return fmt.Errorf("percentage %d is not between 0 and 100", *r.Percentage)
+	}
+	for _, uri := range slices.Concat(r.AggregateReportURIs, r.FailureReportURIs) {
+		if err := ValidateReportURI(uri); err != nil {
+			return err
+		}
+	}
+	if r.DKIMAlignment != "" && !slices.Contains(alignments, r.DKIMAlignment) {
+		return fmt.Errorf("invalid DKIM alignment %q", r.DKIMAlignment)
+	}
+	if r.SPFAlignment != "" && !slices.Contains(alignments, r.SPFAlignment) {
+		return fmt.Errorf("invalid SPF alignment %q", r.SPFAlignment)
+	}
+	for _, fo := range r.FailureReportingOptions {
+		if !slices.Contains(failureReportingOptions, fo) {
+			return fmt.Errorf("invalid failure reporting option %q", fo)
+		}
+	}
+	return nil
+}
+
+// ValidateReportURI checks that a report URI is a mailto URI, optionally followed by a size limit
+// such as !10m, which can be listed in a DMARC record without escaping.
+func ValidateReportURI(uri string) error {
+	address, ok := strings.CutPrefix(uri, "mailto:")
+	if !ok {
+		return fmt.Errorf("report URI %q must be a mailto URI", uri)
+	}
+	if strings.ContainsAny(address, " \t,;\"\\") {
+		return fmt.Errorf("report URI %q contains characters not allowed in DMARC records", uri)
+	}
+	address, limit, hasLimit := strings.Cut(address, "!")
+	if local, domain, ok := strings.Cut(address, "@"); !ok || local == "" || domain == "" {
+		return fmt.Errorf("report URI %q does not contain a valid email address", uri)
+	}
+	if hasLimit && !validSizeLimit(limit) {
+		return fmt.Errorf("report URI %q has an invalid size limit", uri)
+	}
+	return nil
+}
+
+// validSizeLimit checks a report size limit, a number optionally followed by a k, m, g or t unit.
+func validSizeLimit(limit string) bool {
+	if n := len(limit); n > 0 && strings.IndexByte("kmgt", limit[n-1]) >= 0 {
+		limit = limit[:n-1]
+	}
+	_, err := strconv.ParseUint(limit, 10, 64)
+	return err == nil
+}
+
+// Enforced returns true if the record requests quarantining or rejecting all failing messages,
+// as required by BIMI. When subdomain is true, the record is the one of a parent domain and only
+// its subdomain policy applies; otherwise both the policy and the subdomain policy must enforce.
+func (r Record) Enforced(subdomain bool) bool {
+	if r.Percentage != nil && *r.Percentage != 100 {
+		return false
+	}
+	sp := r.SubdomainPolicy
+	if sp == "" {
+		sp = r.Policy
+	}
+	if subdomain {
+		return sp != PolicyNone
+	}
+	return r.Policy != PolicyNone && sp != PolicyNone
+}
+
+// GenTXTValue generates the DMARC record in presentation format. Tags are listed in the order of
+// RFC 7489 section 6.3, starting with v and p as required.
+func (r Record) GenTXTValue() string {
+	tags := []string{"v=DMARC1", "p=" + string(r.Policy)}
+	if r.SubdomainPolicy != "" {
+		tags = append(tags, "sp="+string(r.SubdomainPolicy))
+	}
+	if r.Percentage != nil {
+		tags = append(tags, "pct="+strconv.Itoa(*r.Percentage))
+	}
+	if len(r.AggregateReportURIs) > 0 {
+		tags = append(tags, "rua="+strings.Join(r.AggregateReportURIs, ","))
+	}
+	if len(r.FailureReportURIs) > 0 {
+		tags = append(tags, "ruf="+strings.Join(r.FailureReportURIs, ","))
+	}
+	if r.DKIMAlignment != "" {
+		tags = append(tags, "adkim="+alignmentTag(r.DKIMAlignment))
+	}
+	if r.SPFAlignment != "" {
+		tags = append(tags, "aspf="+alignmentTag(r.SPFAlignment))
+	}
+	if len(r.FailureReportingOptions) > 0 {
+		tags = append(tags, "fo="+strings.Join(r.FailureReportingOptions, ":"))
+	}
+	return dkim.FormatTXTValue(strings.Join(tags, "; ") + ";")
+}
+
+func alignmentTag(a Alignment) string {
+	if a == AlignmentStrict {
+		return "s"
+	}
+	return "r"
+}
@@ -0,0 +1,148 @@
+package dmarc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+)
+
+func TestRecordName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "_dmarc.example.com", RecordName("example.com"))
+}
+
+func TestGenTXTValue(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title    string
+		record   Record
+		expected string
+	}{
+		{
+			title:    "PolicyOnly",
+			record:   Record{Policy: PolicyNone},
+			expected: `"v=DMARC1; p=none;"`,
+		},
+		{
+			title: "AllTags",
+			record: Record{
+				Policy:                  PolicyReject,
+				SubdomainPolicy:         PolicyQuarantine,
+				Percentage:              ptr.To(50),
+				AggregateReportURIs:     []string{"mailto:dmarc@example.com", "mailto:dmarc@example.net!10m"},
+				FailureReportURIs:       []string{"mailto:forensics@example.com"},
+				DKIMAlignment:           AlignmentStrict,
+				SPFAlignment:            AlignmentRelaxed,
+				FailureReportingOptions: []string{"1", "d"},
+			},
+			expected: `"v=DMARC1; p=reject; sp=quarantine; pct=50; rua=mailto:dmarc@example.com,mailto:dmarc@example.net!10m; ruf=mailto:forensics@example.com; adkim=s; aspf=r; fo=1:d;"`,
+		},
+		{
+			title:    "ZeroPercentage",
+			record:   Record{Policy: PolicyQuarantine, Percentage: ptr.To(0)},
+			expected: `"v=DMARC1; p=quarantine; pct=0;"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, tc.record.GenTXTValue())
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title  string
+		record Record
+		valid  bool
+	}{
+		{
+			title: "Valid",
+			record: Record{
+				Policy:                  PolicyReject,
+				SubdomainPolicy:         PolicyNone,
+				Percentage:              ptr.To(100),
+				AggregateReportURIs:     []string{"mailto:dmarc@example.com!1g"},
+				DKIMAlignment:           AlignmentStrict,
+				FailureReportingOptions: []string{"0", "s"},
+			},
+			valid: true,
+		},
+		{
+			title:  "MissingPolicy",
+			record: Record{},
+		},
+		{
+			title:  "InvalidSubdomainPolicy",
+			record: Record{Policy: PolicyNone, SubdomainPolicy: "drop"},
+		},
+		{
+			title:  "PercentageOutOfRange",
+			record: Record{Policy: PolicyNone, Percentage: ptr.To(101)},
+		},
+		{
+			title:  "InvalidAlignment",
+			record: Record{Policy: PolicyNone, SPFAlignment: "s"},
+		},
+		{
+			title:  "InvalidFailureReportingOption",
+			record: Record{Policy: PolicyNone, FailureReportingOptions: []string{"2"}},
+		},
+		{
+			title:  "InvalidReportURI",
+			record: Record{Policy: PolicyNone, FailureReportURIs: []string{"https://example.com/dmarc"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := tc.record.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestEnforced(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title     string
+		record    Record
+		domain    bool
+		subdomain bool
+	}{
+		{title: "Reject", record: Record{Policy: PolicyReject}, domain: true, subdomain: true},
+		{title: "Quarantine", record: Record{Policy: PolicyQuarantine, Percentage: ptr.To(100)}, domain: true, subdomain: true},
+		{title: "None", record: Record{Policy: PolicyNone}},
+		{title: "Partial", record: Record{Policy: PolicyReject, Percentage: ptr.To(50)}},
+		{title: "SubdomainNone", record: Record{Policy: PolicyReject, SubdomainPolicy: PolicyNone}},
+		{title: "SubdomainOnly", record: Record{Policy: PolicyNone, SubdomainPolicy: PolicyReject}, subdomain: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.domain, tc.record.Enforced(false))
+			assert.Equal(t, tc.subdomain, tc.record.Enforced(true))
+		})
+	}
+}
+
+func TestValidateReportURI(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, ValidateReportURI("mailto:dmarc@example.com"))
+	assert.NoError(t, ValidateReportURI("mailto:dmarc@example.com!10m"))
+	assert.NoError(t, ValidateReportURI("mailto:dmarc@example.com!1024"))
+	assert.Error(t, ValidateReportURI("dmarc@example.com"))
+	assert.Error(t, ValidateReportURI("mailto:dmarc"))
+	assert.Error(t, ValidateReportURI("mailto:@example.com"))
+	assert.Error(t, ValidateReportURI("mailto:dmarc@example.com,mailto:other@example.com"))
+	assert.Error(t, ValidateReportURI("mailto:dmarc@example.com; p=none"))
+	assert.Error(t, ValidateReportURI("mailto:dmarc@example.com!10x"))
+	assert.Error(t, ValidateReportURI("mailto:dmarc@example.com!m"))
+}
@@ -0,0 +1,78 @@
+package dnsprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+const defaultPort = "53"
+
+// ErrNotPublished is returned when a nameserver does not serve the expected record.
+var ErrNotPublished = errors.New("record not published")
+
+// Prober queries a fixed set of nameservers for TXT records.
+type Prober struct {
+	nameservers []string
+	timeout     time.Duration
+}
+
+// NewProber returns a Prober querying the given nameservers, given as host or host:port.
+func NewProber(nameservers []string, timeout time.Duration) *Prober {
+	p := &Prober{timeout: timeout}
+	for _, ns := range nameservers {
+		if _, _, err := net.SplitHostPort(ns); err != nil {
+			ns = net.JoinHostPort(ns, defaultPort)
+		}
+		p.nameservers = append(p.nameservers, ns)
+	}
+	return p
+}
+
+func (p *Prober) resolver(nameserver string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: p.timeout}
+			return d.DialContext(ctx, network, nameserver)
+		},
+	}
+}
+
+// Verify checks that every nameserver serves the expected TXT value under the given name.
+// The expected value may be in presentation format, e.g. as produced by dkim.GenTXTValue.
+func (p *Prober) Verify(ctx context.Context, name, expected string) error {
+	fqdn := strings.TrimSuffix(name, ".") + "."
+	want := normalize(expected)
+	for _, ns := range p.nameservers {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		values, err := p.resolver(ns).LookupTXT(ctx, fqdn)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("%w: failed to query %s for %s: %v", ErrNotPublished, ns, name, err)
+		}
+		if !containsValue(values, want) {
+			return fmt.Errorf("%w: %s does not serve the expected value for %s", ErrNotPublished, ns, name)
+		}
+	}
+	return nil
+}
+
+func containsValue(values []string, want string) bool {
+	for _, v := range values {
+		if normalize(v) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize reassembles a TXT value and strips whitespace, which is not significant in DKIM records.
+func normalize(value string) string {
+	return strings.Join(strings.Fields(dkim.JoinTXTValue(value)), "")
+}
@@ -0,0 +1,134 @@
+package dnsprobe
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveTXT starts a UDP nameserver answering TXT queries from records, and returns its address.
+func serveTXT(t *testing.T, records map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp, err := answer(buf[:n], records)
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func answer(query []byte, records map[string][]string) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+	strs, ok := records[q.Name.String()]
+	h.Response = true
+	h.Authoritative = true
+	if !ok {
+		h.RCode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, h)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if ok && q.Type == dnsmessage.TypeTXT {
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		if err := b.TXTResource(rh, dnsmessage.TXTResource{TXT: strs}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	pub := strings.Repeat("a", 300)
+	published := serveTXT(t, map[string][]string{
+		"selector1._domainkey.example.com.": {"v=DKIM1; h=sha256; k=rsa;", "p=" + pub[:253], pub[253:]},
+		"stale._domainkey.example.com.":     {"v=DKIM1; h=sha256; k=rsa;", "p=stale"},
+	})
+	empty := serveTXT(t, nil)
+	expected := "\"v=DKIM1; h=sha256; k=rsa;\" \"p=" + pub + "\""
+
+	cases := []struct {
+		title       string
+		nameservers []string
+		name        string
+		errFunc     assert.ErrorAssertionFunc
+	}{
+		{
+			title:       "Published",
+			nameservers: []string{published},
+			name:        "selector1._domainkey.example.com",
+			errFunc:     assert.NoError,
+		},
+		{
+			title:       "Stale",
+			nameservers: []string{published},
+			name:        "stale._domainkey.example.com",
+			errFunc:     assert.Error,
+		},
+		{
+			title:       "NotFound",
+			nameservers: []string{empty},
+			name:        "selector1._domainkey.example.com",
+			errFunc:     assert.Error,
+		},
+		{
+			title:       "PartiallyPropagated",
+			nameservers: []string{published, empty},
+			name:        "selector1._domainkey.example.com",
+			errFunc:     assert.Error,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			p := NewProber(tc.nameservers, time.Second)
+			err := p.Verify(context.Background(), tc.name, expected)
+			tc.errFunc(t, err)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrNotPublished)
+			}
+		})
+	}
+}
+
+func TestNewProber(t *testing.T) {
+	t.Parallel()
+	p := NewProber([]string{"192.0.2.1", "192.0.2.2:5353", "2001:db8::1"}, time.Second)
+	assert.Equal(t, []string{"192.0.2.1:53", "192.0.2.2:5353", "[2001:db8::1]:53"}, p.nameservers)
+}
@@ -3,4 +3,5 @@ package externaldns
 const (
 	DNSEndpointGroup   = "externaldns.k8s.io"
 	DNSEndpointVersion = "v1alpha1"
+	DNSEndpointKind    = "DNSEndpoint"
 )
@@ -1,26 +1,54 @@
 package externaldns
 
 import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// GroupVersionKind is the default GroupVersionKind of DNSEndpoints, as served by upstream external-dns.
+var GroupVersionKind = schema.GroupVersionKind{
+	Group:   DNSEndpointGroup,
+	Version: DNSEndpointVersion,
+	Kind:    DNSEndpointKind,
+}
+
+// DNSEndpoint returns an empty DNSEndpoint of the default GroupVersionKind.
 func DNSEndpoint() *unstructured.Unstructured {
+	return NewDNSEndpoint(GroupVersionKind)
+}
+
+// DNSEndpointList returns an empty DNSEndpoint list of the default GroupVersionKind.
+func DNSEndpointList() *unstructured.UnstructuredList {
+	return NewDNSEndpointList(GroupVersionKind)
+}
+
+// NewDNSEndpoint returns an empty DNSEndpoint of the given GroupVersionKind.
+func NewDNSEndpoint(gvk schema.GroupVersionKind) *unstructured.Unstructured {
 	de := &unstructured.Unstructured{}
-	de.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   DNSEndpointGroup,
-		Version: DNSEndpointVersion,
-		Kind:    "DNSEndpoint",
-	})
+	de.SetGroupVersionKind(gvk)
 	return de
 }
 
-func DNSEndpointList() *unstructured.UnstructuredList {
+// NewDNSEndpointList returns an empty DNSEndpoint list of the given GroupVersionKind.
+func NewDNSEndpointList(gvk schema.GroupVersionKind) *unstructured.UnstructuredList {
 	del := &unstructured.UnstructuredList{}
-	del.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   DNSEndpointGroup,
-		Version: DNSEndpointVersion,
-		Kind:    "DNSEndpointList",
-	})
+	del.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
 	return del
 }
+
+// Discover looks up the DNSEndpoint kind in the given group on the API server. An empty version
+// selects the preferred version of the group, otherwise the version must be served.
+func Discover(mapper meta.RESTMapper, group, version, kind string) (schema.GroupVersionKind, error) {
+	var versions []string
+	if version != "" {
+		versions = append(versions, version)
+	}
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: group, Kind: kind}, versions...)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to discover %s in group %s: %w", kind, group, err)
+	}
+	return mapping.GroupVersionKind, nil
+}
@@ -0,0 +1,75 @@
+package externaldns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	v1 := schema.GroupVersion{Group: DNSEndpointGroup, Version: "v1"}
+	v1alpha1 := schema.GroupVersion{Group: DNSEndpointGroup, Version: DNSEndpointVersion}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{v1, v1alpha1})
+	mapper.Add(v1.WithKind(DNSEndpointKind), meta.RESTScopeNamespace)
+	mapper.Add(v1alpha1.WithKind(DNSEndpointKind), meta.RESTScopeNamespace)
+
+	cases := []struct {
+		title    string
+		group    string
+		version  string
+		kind     string
+		expected schema.GroupVersionKind
+		isErr    bool
+	}{
+		{
+			title:    "PreferredVersion",
+			group:    DNSEndpointGroup,
+			kind:     DNSEndpointKind,
+			expected: v1.WithKind(DNSEndpointKind),
+		},
+		{
+			title:    "ExplicitVersion",
+			group:    DNSEndpointGroup,
+			version:  DNSEndpointVersion,
+			kind:     DNSEndpointKind,
+			expected: GroupVersionKind,
+		},
+		{
+			title:   "UnservedVersion",
+			group:   DNSEndpointGroup,
+			version: "v2",
+			kind:    DNSEndpointKind,
+			isErr:   true,
+		},
+		{
+			title: "UnknownGroup",
+			group: "dns.example.com",
+			kind:  DNSEndpointKind,
+			isErr: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			gvk, err := Discover(mapper, tc.group, tc.version, tc.kind)
+			if tc.isErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, gvk)
+		})
+	}
+}
+
+func TestNewDNSEndpointList(t *testing.T) {
+	t.Parallel()
+	gvk := schema.GroupVersionKind{Group: "dns.example.com", Version: "v1", Kind: "Endpoint"}
+	assert.Equal(t, "EndpointList", NewDNSEndpointList(gvk).GetKind())
+	assert.Equal(t, "dns.example.com/v1", NewDNSEndpointList(gvk).GetAPIVersion())
+	assert.Equal(t, gvk, NewDNSEndpoint(gvk).GroupVersionKind())
+}
@@ -0,0 +1,59 @@
+package mtasts
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PolicyGetter returns the MTA-STS policy of a domain, or nil if the domain has none.
+type PolicyGetter interface {
+	GetPolicy(ctx context.Context, domain string) (*Policy, error)
+}
+
+type handler struct {
+	getter PolicyGetter
+}
+
+// NewHandler returns an HTTP handler serving the policy of a domain under WellKnownPath,
+// when requested on its policy host, e.g. https://mta-sts.example.com/.well-known/mta-sts.txt.
+// TLS is expected to be terminated in front of the handler, e.g. by an Ingress.
+func NewHandler(getter PolicyGetter) http.Handler {
+	return &handler{getter: getter}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != WellKnownPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	domain, ok := strings.CutPrefix(strings.ToLower(strings.TrimSuffix(host, ".")), PolicyHost(""))
+	if !ok || domain == "" {
+		http.NotFound(w, r)
+		return
+	}
+	p, err := h.getter.GetPolicy(r.Context(), domain)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "failed to get MTA-STS policy", "domain", domain)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(p.Text()))
+}
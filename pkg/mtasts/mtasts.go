@@ -0,0 +1,93 @@
+package mtasts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// Mode is the mode of an MTA-STS policy.
+type Mode string
+
+const (
+	ModeEnforce Mode = "enforce"
+	ModeTesting Mode = "testing"
+	ModeNone    Mode = "none"
+)
+
+const (
+	// MaxMaxAge is the highest max_age of a policy, about a year, as defined in RFC 8461 section 3.2.
+	MaxMaxAge = 31557600
+
+	// WellKnownPath is the path under which policies are served on the policy host.
+	WellKnownPath = "/.well-known/mta-sts.txt"
+)
+
+var modes = []Mode{ModeEnforce, ModeTesting, ModeNone}
+
+// Policy holds the fields of an MTA-STS policy, as defined in RFC 8461 section 3.2.
+type Policy struct {
+	// Mode is the mode of the policy.
+	Mode Mode
+	// MX are the MX host patterns, e.g. mail.example.com or *.example.net.
+	MX []string
+	// MaxAge is the number of seconds senders may cache the policy for.
+	MaxAge uint
+}
+
+// RecordName returns the DNS name under which the MTA-STS record of the domain is published.
+func RecordName(domain string) string {
+	return "_mta-sts." + domain
+}
+
+// PolicyHost returns the host the MTA-STS policy of the domain is fetched from.
+func PolicyHost(domain string) string {
+	return "mta-sts." + domain
+}
+
+// Validate checks that the policy only contains valid fields.
+func (p Policy) Validate() error {
+	if !slices.Contains(modes, p.Mode) {
+		return fmt.Errorf("invalid mode %q", p.Mode)
+	}
+	if p.Mode != ModeNone && len(p.MX) == 0 {
+		return fmt.Errorf("at least one MX host pattern is required in %s mode", p.Mode)
+	}
+	for _, mx := range p.MX {
+		if err := dkim.ValidateDomain(strings.TrimPrefix(mx, "*.")); err != nil {
+			return fmt.Errorf("invalid MX host pattern %q: %w", mx, err)
+		}
+	}
+	if p.MaxAge > MaxMaxAge {
+		return fmt.Errorf("max age %d exceeds the maximum of %d", p.MaxAge, MaxMaxAge)
+	}
+	return nil
+}
+
+// Text returns the policy file served on the policy host.
+func (p Policy) Text() string {
+	var b strings.Builder
+	b.WriteString("version: STSv1\r\n")
+	b.WriteString("mode: " + string(p.Mode) + "\r\n")
+	for _, mx := range p.MX {
+		b.WriteString("mx: " + mx + "\r\n")
+	}
+	b.WriteString("max_age: " + strconv.FormatUint(uint64(p.MaxAge), 10) + "\r\n")
+	return b.String()
+}
+
+// ID returns the identifier of the policy, derived from its content so that it changes whenever the policy does.
+func (p Policy) ID() string {
+	sum := sha256.Sum256([]byte(p.Text()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// GenTXTValue generates the MTA-STS record announcing the policy in presentation format.
+func (p Policy) GenTXTValue() string {
+	return dkim.FormatTXTValue("v=STSv1; id=" + p.ID() + ";")
+}
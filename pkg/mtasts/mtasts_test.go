@@ -0,0 +1,112 @@
+package mtasts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "_mta-sts.example.com", RecordName("example.com"))
+	assert.Equal(t, "mta-sts.example.com", PolicyHost("example.com"))
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := Policy{Mode: ModeEnforce, MX: []string{"mail.example.com", "*.example.net"}, MaxAge: 604800}
+	assert.NoError(t, p.Validate())
+	assert.Equal(t, "version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\nmax_age: 604800\r\n", p.Text())
+	assert.Len(t, p.ID(), 32)
+	assert.Equal(t, `"v=STSv1; id=`+p.ID()+`;"`, p.GenTXTValue())
+
+	changed := p
+	changed.Mode = ModeTesting
+	assert.NotEqual(t, p.ID(), changed.ID())
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title  string
+		policy Policy
+		valid  bool
+	}{
+		{title: "NoneWithoutMX", policy: Policy{Mode: ModeNone, MaxAge: 86400}, valid: true},
+		{title: "InvalidMode", policy: Policy{Mode: "strict", MX: []string{"mail.example.com"}}},
+		{title: "EnforceWithoutMX", policy: Policy{Mode: ModeEnforce, MaxAge: 86400}},
+		{title: "InvalidMX", policy: Policy{Mode: ModeTesting, MX: []string{"mail.*.example.com"}}},
+		{title: "MaxAgeTooLong", policy: Policy{Mode: ModeNone, MaxAge: MaxMaxAge + 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := tc.policy.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+type policyGetterFunc func(ctx context.Context, domain string) (*Policy, error)
+
+func (f policyGetterFunc) GetPolicy(ctx context.Context, domain string) (*Policy, error) {
+	return f(ctx, domain)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	policy := &Policy{Mode: ModeEnforce, MX: []string{"mail.example.com"}, MaxAge: 86400}
+	h := NewHandler(policyGetterFunc(func(_ context.Context, domain string) (*Policy, error) {
+		switch domain {
+		case "example.com":
+			return policy, nil
+		case "broken.example.com":
+			return nil, errors.New("broken")
+		default:
+			return nil, nil
+		}
+	}))
+
+	cases := []struct {
+		title  string
+		method string
+		host   string
+		path   string
+		status int
+	}{
+		{title: "Served", method: http.MethodGet, host: "mta-sts.example.com", path: WellKnownPath, status: http.StatusOK},
+		{title: "ServedWithPort", method: http.MethodGet, host: "MTA-STS.Example.com:8082", path: WellKnownPath, status: http.StatusOK},
+		{title: "Head", method: http.MethodHead, host: "mta-sts.example.com", path: WellKnownPath, status: http.StatusOK},
+		{title: "UnknownDomain", method: http.MethodGet, host: "mta-sts.example.org", path: WellKnownPath, status: http.StatusNotFound},
+		{title: "NotPolicyHost", method: http.MethodGet, host: "www.example.com", path: WellKnownPath, status: http.StatusNotFound},
+		{title: "OtherPath", method: http.MethodGet, host: "mta-sts.example.com", path: "/", status: http.StatusNotFound},
+		{title: "Post", method: http.MethodPost, host: "mta-sts.example.com", path: WellKnownPath, status: http.StatusMethodNotAllowed},
+		{title: "GetterError", method: http.MethodGet, host: "mta-sts.broken.example.com", path: WellKnownPath, status: http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+				if tc.method == http.MethodGet {
+					assert.Equal(t, policy.Text(), rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+// NormalizeRecordName returns the canonical form of a DNS name for comparison purposes.
+func NormalizeRecordName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// FindRecordConflicts returns the DKIMKeys in any namespace, other than the one identified by key,
+// that publish a DKIM record under any of the given record names.
+func FindRecordConflicts(ctx context.Context, c client.Reader, key client.ObjectKey, recordNames []string) ([]dkimmanagerv2.DKIMKey, error) {
+	dkl := &dkimmanagerv2.DKIMKeyList{}
+	if err := c.List(ctx, dkl); err != nil {
+		return nil, fmt.Errorf("failed to list DKIMKeys: %w", err)
+	}
+	claimed := make(map[string]struct{}, len(recordNames))
+	for _, name := range recordNames {
+		claimed[NormalizeRecordName(name)] = struct{}{}
+	}
+	var conflicts []dkimmanagerv2.DKIMKey
+	for _, dk := range dkl.Items {
+		if client.ObjectKeyFromObject(&dk) == key {
+			continue
+		}
+		for _, name := range dk.RecordNames() {
+			if _, ok := claimed[NormalizeRecordName(name)]; ok {
+				conflicts = append(conflicts, dk)
+				break
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// FindDomainConflicts returns the resources of list in any namespace, other than the one identified by key,
+// whose domain, as returned by domainOf, is the given domain. The type of list must hold resources of type T.
+func FindDomainConflicts[T client.Object](ctx context.Context, c client.Reader, list client.ObjectList, key client.ObjectKey, domain string, domainOf func(T) string) ([]T, error) {
+	if err := c.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list %T: %w", list, err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []T
+	for _, item := range items {
+		o, ok := item.(T)
+		if !ok {
+			return nil, fmt.Errorf("unexpected item type %T in %T", item, list)
+		}
+		if client.ObjectKeyFromObject(o) == key {
+			continue
+		}
+		if NormalizeRecordName(domainOf(o)) == NormalizeRecordName(domain) {
+			conflicts = append(conflicts, o)
+		}
+	}
+	return conflicts, nil
+}
+
+// SharedRecordName returns the first record name of a that is also published by b,
+// or an empty string if they do not share any.
+func SharedRecordName(a, b *dkimmanagerv2.DKIMKey) string {
+	for _, an := range a.RecordNames() {
+		for _, bn := range b.RecordNames() {
+			if NormalizeRecordName(an) == NormalizeRecordName(bn) {
+				return an
+			}
+		}
+	}
+	return ""
+}
+
+// ClaimedBefore returns true if a claimed its record name before b, i.e. a was created first.
+// Ties are broken by namespace and name so that exactly one resource wins.
+func ClaimedBefore(a, b metav1.Object) bool {
+	at, bt := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !at.Equal(&bt) {
+		return at.Before(&bt)
+	}
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
@@ -0,0 +1,244 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+func dkimKey(namespace, name, selector, domain string, created time.Time) *dkimmanagerv2.DKIMKey {
+	return &dkimmanagerv2.DKIMKey{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			SecretName: name,
+			Selector:   selector,
+			Domain:     domain,
+		},
+	}
+}
+
+func TestFindRecordConflicts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	dual := dkimKey("team-d", "dual", "rsa", "example.org", now)
+	dual.Spec.ED25519Selector = "ed25519"
+	c := newFakeClient(t,
+		dkimKey("team-a", "default", "default", "example.com", now),
+		dkimKey("team-b", "default", "Default", "Example.com.", now),
+		dkimKey("team-b", "other", "selector2", "example.com", now),
+		dual,
+	)
+
+	cases := []struct {
+		title       string
+		key         client.ObjectKey
+		recordNames []string
+		expected    []client.ObjectKey
+	}{
+		{
+			title:       "ConflictAcrossNamespaces",
+			key:         client.ObjectKey{Namespace: "team-a", Name: "default"},
+			recordNames: []string{"default._domainkey.example.com"},
+			expected:    []client.ObjectKey{{Namespace: "team-b", Name: "default"}},
+		},
+		{
+			title:       "NewKey",
+			key:         client.ObjectKey{Namespace: "team-c", Name: "default"},
+			recordNames: []string{"DEFAULT._domainkey.example.com."},
+			expected: []client.ObjectKey{
+				{Namespace: "team-a", Name: "default"},
+				{Namespace: "team-b", Name: "default"},
+			},
+		},
+		{
+			title:       "NoConflict",
+			key:         client.ObjectKey{Namespace: "team-b", Name: "other"},
+			recordNames: []string{"selector2._domainkey.example.com"},
+			expected:    nil,
+		},
+		{
+			title:       "ConflictWithSiblingSelector",
+			key:         client.ObjectKey{Namespace: "team-e", Name: "default"},
+			recordNames: []string{"other._domainkey.example.org", "ed25519._domainkey.example.org"},
+			expected:    []client.ObjectKey{{Namespace: "team-d", Name: "dual"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			conflicts, err := FindRecordConflicts(context.Background(), c, tc.key, tc.recordNames)
+			assert.NoError(t, err)
+			var actual []client.ObjectKey
+			for _, dk := range conflicts {
+				actual = append(actual, client.ObjectKeyFromObject(&dk))
+			}
+			assert.ElementsMatch(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestFindDomainConflictsDMARCPolicy(t *testing.T) {
+	t.Parallel()
+
+	dmarcPolicy := func(namespace, name, domain string) *dkimmanagerv2.DMARCPolicy {
+		return &dkimmanagerv2.DMARCPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       dkimmanagerv2.DMARCPolicySpec{Domain: domain},
+		}
+	}
+	c := newFakeClient(t,
+		dmarcPolicy("team-a", "default", "example.com"),
+		dmarcPolicy("team-b", "default", "Example.com."),
+		dmarcPolicy("team-b", "other", "example.org"),
+	)
+
+	domainOf := func(dp *dkimmanagerv2.DMARCPolicy) string { return dp.Spec.Domain }
+	conflicts, err := FindDomainConflicts(context.Background(), c, &dkimmanagerv2.DMARCPolicyList{}, client.ObjectKey{Namespace: "team-a", Name: "default"}, "example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, client.ObjectKey{Namespace: "team-b", Name: "default"}, client.ObjectKeyFromObject(conflicts[0]))
+
+	conflicts, err = FindDomainConflicts(context.Background(), c, &dkimmanagerv2.DMARCPolicyList{}, client.ObjectKey{Namespace: "team-b", Name: "other"}, "example.org", domainOf)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestFindDomainConflictsSPFRecord(t *testing.T) {
+	t.Parallel()
+
+	spfRecord := func(namespace, name, domain string) *dkimmanagerv2.SPFRecord {
+		return &dkimmanagerv2.SPFRecord{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       dkimmanagerv2.SPFRecordSpec{Domain: domain},
+		}
+	}
+	c := newFakeClient(t,
+		spfRecord("team-a", "default", "example.com"),
+		spfRecord("team-b", "default", "EXAMPLE.com"),
+		spfRecord("team-b", "other", "mail.example.com"),
+	)
+
+	domainOf := func(sr *dkimmanagerv2.SPFRecord) string { return sr.Spec.Domain }
+	conflicts, err := FindDomainConflicts(context.Background(), c, &dkimmanagerv2.SPFRecordList{}, client.ObjectKey{Namespace: "team-a", Name: "default"}, "example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, client.ObjectKey{Namespace: "team-b", Name: "default"}, client.ObjectKeyFromObject(conflicts[0]))
+
+	conflicts, err = FindDomainConflicts(context.Background(), c, &dkimmanagerv2.SPFRecordList{}, client.ObjectKey{Namespace: "team-b", Name: "other"}, "mail.example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestFindDomainConflictsMTASTSPolicy(t *testing.T) {
+	t.Parallel()
+
+	mtastsPolicy := func(namespace, name, domain string) *dkimmanagerv2.MTASTSPolicy {
+		return &dkimmanagerv2.MTASTSPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       dkimmanagerv2.MTASTSPolicySpec{Domain: domain},
+		}
+	}
+	c := newFakeClient(t,
+		mtastsPolicy("team-a", "default", "example.com"),
+		mtastsPolicy("team-b", "default", "example.com."),
+		mtastsPolicy("team-b", "other", "mail.example.com"),
+	)
+
+	domainOf := func(mp *dkimmanagerv2.MTASTSPolicy) string { return mp.Spec.Domain }
+	conflicts, err := FindDomainConflicts(context.Background(), c, &dkimmanagerv2.MTASTSPolicyList{}, client.ObjectKey{Namespace: "team-a", Name: "default"}, "example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, client.ObjectKey{Namespace: "team-b", Name: "default"}, client.ObjectKeyFromObject(conflicts[0]))
+
+	conflicts, err = FindDomainConflicts(context.Background(), c, &dkimmanagerv2.MTASTSPolicyList{}, client.ObjectKey{Namespace: "team-b", Name: "other"}, "mail.example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestFindDomainConflictsTLSRPTRecord(t *testing.T) {
+	t.Parallel()
+
+	tlsrptRecord := func(namespace, name, domain string) *dkimmanagerv2.TLSRPTRecord {
+		return &dkimmanagerv2.TLSRPTRecord{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       dkimmanagerv2.TLSRPTRecordSpec{Domain: domain},
+		}
+	}
+	c := newFakeClient(t,
+		tlsrptRecord("team-a", "default", "example.com"),
+		tlsrptRecord("team-b", "default", "EXAMPLE.com"),
+		tlsrptRecord("team-b", "other", "mail.example.com"),
+	)
+
+	domainOf := func(tr *dkimmanagerv2.TLSRPTRecord) string { return tr.Spec.Domain }
+	conflicts, err := FindDomainConflicts(context.Background(), c, &dkimmanagerv2.TLSRPTRecordList{}, client.ObjectKey{Namespace: "team-a", Name: "default"}, "example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, client.ObjectKey{Namespace: "team-b", Name: "default"}, client.ObjectKeyFromObject(conflicts[0]))
+
+	conflicts, err = FindDomainConflicts(context.Background(), c, &dkimmanagerv2.TLSRPTRecordList{}, client.ObjectKey{Namespace: "team-b", Name: "other"}, "mail.example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestFindDomainConflictsBIMIRecord(t *testing.T) {
+	t.Parallel()
+
+	bimiRecord := func(namespace, name, domain string) *dkimmanagerv2.BIMIRecord {
+		return &dkimmanagerv2.BIMIRecord{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       dkimmanagerv2.BIMIRecordSpec{Domain: domain},
+		}
+	}
+	c := newFakeClient(t,
+		bimiRecord("team-a", "default", "example.com"),
+		bimiRecord("team-b", "default", "Example.com."),
+		bimiRecord("team-b", "other", "mail.example.com"),
+	)
+
+	domainOf := func(br *dkimmanagerv2.BIMIRecord) string { return br.Spec.Domain }
+	conflicts, err := FindDomainConflicts(context.Background(), c, &dkimmanagerv2.BIMIRecordList{}, client.ObjectKey{Namespace: "team-a", Name: "default"}, "example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, client.ObjectKey{Namespace: "team-b", Name: "default"}, client.ObjectKeyFromObject(conflicts[0]))
+
+	conflicts, err = FindDomainConflicts(context.Background(), c, &dkimmanagerv2.BIMIRecordList{}, client.ObjectKey{Namespace: "team-b", Name: "other"}, "mail.example.com", domainOf)
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+}
+
+func TestSharedRecordName(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	dual := dkimKey("team-a", "dual", "rsa", "example.com", now)
+	dual.Spec.ED25519Selector = "ed25519"
+
+	assert.Equal(t, "ed25519._domainkey.example.com", SharedRecordName(dual, dkimKey("team-b", "default", "ED25519", "example.com", now)))
+	assert.Empty(t, SharedRecordName(dual, dkimKey("team-b", "default", "default", "example.com", now)))
+}
+
+func TestClaimedBefore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	older := dkimKey("team-b", "default", "default", "example.com", now.Add(-time.Hour))
+	newer := dkimKey("team-a", "default", "default", "example.com", now)
+	tied := dkimKey("team-c", "default", "default", "example.com", now)
+
+	assert.True(t, ClaimedBefore(older, newer))
+	assert.False(t, ClaimedBefore(newer, older))
+	assert.True(t, ClaimedBefore(newer, tied))
+	assert.False(t, ClaimedBefore(tied, newer))
+}
@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+// ErrDMARCNotEnforced is returned when no DMARC policy requests quarantining or rejecting failing messages of a domain.
+var ErrDMARCNotEnforced = errors.New("DMARC not enforced")
+
+// CheckDMARCEnforcement verifies that the DMARC policy applying to the given domain is enforced, as required to
+// publish BIMI records. The policy applying to the domain is the one of the ready DMARCPolicy of the domain or,
+// failing that, the subdomain policy of the ready DMARCPolicy of its closest parent domain.
+func CheckDMARCEnforcement(ctx context.Context, c client.Reader, domain string) error {
+	dpl := &dkimmanagerv2.DMARCPolicyList{}
+	if err := c.List(ctx, dpl); err != nil {
+		return fmt.Errorf("failed to list DMARCPolicies: %w", err)
+	}
+	domain = NormalizeRecordName(domain)
+	var applied *dkimmanagerv2.DMARCPolicy
+	for i, dp := range dpl.Items {
+		if !dp.IsReady() || !dp.DeletionTimestamp.IsZero() {
+			continue
+		}
+		d := NormalizeRecordName(dp.Spec.Domain)
+		if d != domain && !strings.HasSuffix(domain, "."+d) {
+			continue
+		}
+		if applied == nil || len(d) > len(NormalizeRecordName(applied.Spec.Domain)) {
+			applied = &dpl.Items[i]
+		}
+	}
+	if applied == nil {
+		return fmt.Errorf("%w: no DMARCPolicy applies to %s", ErrDMARCNotEnforced, domain)
+	}
+	subdomain := NormalizeRecordName(applied.Spec.Domain) != domain
+	if !applied.Record().Enforced(subdomain) {
+		return fmt.Errorf("%w: DMARCPolicy %s does not quarantine or reject all failing messages of %s", ErrDMARCNotEnforced, client.ObjectKeyFromObject(applied), domain)
+	}
+	return nil
+}
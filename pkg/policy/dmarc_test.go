@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dmarc"
+)
+
+func dmarcPolicy(name, domain string, p, sp dmarc.Policy, ready bool) *dkimmanagerv2.DMARCPolicy {
+	dp := &dkimmanagerv2.DMARCPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "mail", Name: name},
+		Spec:       dkimmanagerv2.DMARCPolicySpec{Domain: domain, Policy: p, SubdomainPolicy: sp},
+	}
+	if ready {
+		dp.Status.Conditions = []metav1.Condition{{Type: dkimmanagerv2.ConditionReady, Status: metav1.ConditionTrue}}
+	}
+	return dp
+}
+
+func TestCheckDMARCEnforcement(t *testing.T) {
+	t.Parallel()
+
+	partial := dmarcPolicy("partial", "partial.example.com", dmarc.PolicyReject, "", true)
+	partial.Spec.Percentage = ptr.To[int32](50)
+	c := newFakeClient(t,
+		dmarcPolicy("org", "example.com", dmarc.PolicyReject, dmarc.PolicyQuarantine, true),
+		dmarcPolicy("monitored", "monitored.example.com", dmarc.PolicyNone, "", true),
+		dmarcPolicy("pending", "pending.example.com", dmarc.PolicyReject, "", false),
+		dmarcPolicy("relaxed", "example.net", dmarc.PolicyReject, dmarc.PolicyNone, true),
+		partial,
+	)
+
+	cases := []struct {
+		title    string
+		domain   string
+		enforced bool
+	}{
+		{title: "Domain", domain: "example.com", enforced: true},
+		{title: "Parent", domain: "mail.example.com", enforced: true},
+		{title: "ClosestParent", domain: "www.monitored.example.com"},
+		{title: "NotEnforced", domain: "monitored.example.com"},
+		{title: "NotReadyFallsBackToParent", domain: "pending.example.com", enforced: true},
+		{title: "Partial", domain: "partial.example.com"},
+		{title: "SubdomainNone", domain: "example.net"},
+		{title: "ParentSubdomainNone", domain: "mail.example.net"},
+		{title: "NoPolicy", domain: "example.org"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := CheckDMARCEnforcement(context.Background(), c, tc.domain)
+			if tc.enforced {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrDMARCNotEnforced)
+			}
+		})
+	}
+}
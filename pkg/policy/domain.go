@@ -0,0 +1,50 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+// ErrDomainNotAllowed is returned when no DKIMDomainPolicy allows a namespace to use a domain.
+var ErrDomainNotAllowed = errors.New("domain not allowed")
+
+// CheckDomain verifies that DKIMKeys in the given namespace may be created for the given domain.
+// When no DKIMDomainPolicy exists, all domains are allowed. Otherwise, the namespace must be
+// matched by at least one policy allowing the domain.
+func CheckDomain(ctx context.Context, c client.Reader, namespace, domain string) error {
+	pl := &dkimmanagerv2.DKIMDomainPolicyList{}
+	if err := c.List(ctx, pl); err != nil {
+		return fmt.Errorf("failed to list DKIMDomainPolicies: %w", err)
+	}
+	if len(pl.Items) == 0 {
+		return nil
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return fmt.Errorf("failed to get namespace: %w", err)
+	}
+	covered := false
+	for _, p := range pl.Items {
+		ok, err := p.MatchesNamespace(ns.Name, ns.Labels)
+		if err != nil {
+			return fmt.Errorf("invalid namespace selector in DKIMDomainPolicy %s: %w", p.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		covered = true
+		if p.AllowsDomain(domain) {
+			return nil
+		}
+	}
+	if !covered {
+		return fmt.Errorf("%w: namespace %s is not covered by any DKIMDomainPolicy", ErrDomainNotAllowed, namespace)
+	}
+	return fmt.Errorf("%w: namespace %s may not create DKIM keys for %s", ErrDomainNotAllowed, namespace, domain)
+}
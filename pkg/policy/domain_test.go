@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+)
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, dkimmanagerv2.AddToScheme(scheme))
+	copies := make([]client.Object, len(objs))
+	for i, o := range objs {
+		copies[i] = o.DeepCopyObject().(client.Object)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(copies...).Build()
+}
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
+
+func TestCheckDomain(t *testing.T) {
+	t.Parallel()
+
+	byName := &dkimmanagerv2.DKIMDomainPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "by-name"},
+		Spec: dkimmanagerv2.DKIMDomainPolicySpec{
+			Namespaces: []string{"team-a"},
+			Domains:    []string{"a.example.com"},
+		},
+	}
+	bySelector := &dkimmanagerv2.DKIMDomainPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "by-selector"},
+		Spec: dkimmanagerv2.DKIMDomainPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"team": "b"},
+			},
+			Domains:         []string{"b.example.com"},
+			AllowSubdomains: true,
+		},
+	}
+	namespaces := []client.Object{
+		namespace("team-a", nil),
+		namespace("team-b", map[string]string{"team": "b"}),
+		namespace("team-c", nil),
+	}
+
+	cases := []struct {
+		title     string
+		objs      []client.Object
+		namespace string
+		domain    string
+		errFunc   assert.ErrorAssertionFunc
+	}{
+		{
+			title:     "NoPolicy",
+			objs:      namespaces,
+			namespace: "team-c",
+			domain:    "anything.example.com",
+			errFunc:   assert.NoError,
+		},
+		{
+			title:     "AllowedByName",
+			objs:      append([]client.Object{byName, bySelector}, namespaces...),
+			namespace: "team-a",
+			domain:    "A.example.com.",
+			errFunc:   assert.NoError,
+		},
+		{
+			title:     "SubdomainNotAllowed",
+			objs:      append([]client.Object{byName, bySelector}, namespaces...),
+			namespace: "team-a",
+			domain:    "sub.a.example.com",
+			errFunc:   assert.Error,
+		},
+		{
+			title:     "AllowedBySelector",
+			objs:      append([]client.Object{byName, bySelector}, namespaces...),
+			namespace: "team-b",
+			domain:    "sub.b.example.com",
+			errFunc:   assert.NoError,
+		},
+		{
+			title:     "OtherTeamDomain",
+			objs:      append([]client.Object{byName, bySelector}, namespaces...),
+			namespace: "team-b",
+			domain:    "a.example.com",
+			errFunc:   assert.Error,
+		},
+		{
+			title:     "NamespaceNotCovered",
+			objs:      append([]client.Object{byName, bySelector}, namespaces...),
+			namespace: "team-c",
+			domain:    "a.example.com",
+			errFunc:   assert.Error,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			c := newFakeClient(t, tc.objs...)
+			err := CheckDomain(context.Background(), c, tc.namespace, tc.domain)
+			tc.errFunc(t, err)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrDomainNotAllowed)
+			}
+		})
+	}
+}
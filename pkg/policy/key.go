@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+var (
+	// ErrKeyPolicyViolation is returned when a key does not satisfy the KeyPolicy.
+	ErrKeyPolicyViolation = errors.New("key policy violation")
+	// ErrRotationRequired is returned when a key is older than the maximum key age.
+	ErrRotationRequired = errors.New("key rotation required")
+)
+
+// KeyPolicy represents organization-wide requirements on DKIM keys.
+type KeyPolicy struct {
+	// MinRSAKeyLength is the smallest allowed RSA key size. Zero allows any size.
+	MinRSAKeyLength dkim.KeyLength
+	// AllowedKeyTypes is the list of allowed key types. An empty list allows any type.
+	AllowedKeyTypes []dkim.KeyType
+	// MaxKeyAge is the age after which keys must be rotated. Zero disables rotation requirements.
+	MaxKeyAge time.Duration
+	// MaxARCKeyAge is the age after which ARC keys must be rotated. Zero applies MaxKeyAge.
+	MaxARCKeyAge time.Duration
+}
+
+// IsEmpty returns true if the policy does not impose any requirement.
+func (p KeyPolicy) IsEmpty() bool {
+	return p.MinRSAKeyLength == 0 && len(p.AllowedKeyTypes) == 0 && p.MaxKeyAge == 0 && p.MaxARCKeyAge == 0
+}
+
+// ForPurpose returns the effective policy applying to keys of the given purpose.
+// MaxARCKeyAge is resolved into MaxKeyAge and cleared, so that IsEmpty reports whether
+// keys of that purpose are subject to any requirement.
+func (p KeyPolicy) ForPurpose(purpose dkim.KeyPurpose) KeyPolicy {
+	if purpose == dkim.KeyPurposeARC && p.MaxARCKeyAge != 0 {
+		p.MaxKeyAge = p.MaxARCKeyAge
+	}
+	p.MaxARCKeyAge = 0
+	return p
+}
+
+// CheckKey verifies that a key of the given type and length is allowed.
+func (p KeyPolicy) CheckKey(keyType dkim.KeyType, keyLength dkim.KeyLength) error {
+	if len(p.AllowedKeyTypes) > 0 && !slices.Contains(p.AllowedKeyTypes, keyType) {
+		return fmt.Errorf("%w: key type %s is not allowed, allowed key types are %v", ErrKeyPolicyViolation, keyType, p.AllowedKeyTypes)
+	}
+	if keyType == dkim.KeyTypeRSA && keyLength < p.MinRSAKeyLength {
+		return fmt.Errorf("%w: RSA key length %d is lower than the minimum of %d", ErrKeyPolicyViolation, keyLength, p.MinRSAKeyLength)
+	}
+	return nil
+}
+
+// CheckDKIMKey verifies that all keys managed by the DKIMKey are allowed.
+func (p KeyPolicy) CheckDKIMKey(dk *dkimmanagerv2.DKIMKey) error {
+	for _, k := range dk.Keys() {
+		if err := p.CheckKey(k.KeyType, k.KeyLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RotationDeadline returns the time by which a key created at the given time must be rotated.
+// The zero time is returned if there is no maximum key age.
+func (p KeyPolicy) RotationDeadline(created time.Time) time.Time {
+	if p.MaxKeyAge == 0 {
+		return time.Time{}
+	}
+	return created.Add(p.MaxKeyAge)
+}
+
+// CheckAge verifies that a key created at the given time does not need to be rotated yet.
+func (p KeyPolicy) CheckAge(created, now time.Time) error {
+	deadline := p.RotationDeadline(created)
+	if deadline.IsZero() || now.Before(deadline) {
+		return nil
+	}
+	return fmt.Errorf("%w: key is older than the maximum age of %s", ErrRotationRequired, p.MaxKeyAge)
+}
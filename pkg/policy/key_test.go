@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	dkimmanagerv2 "github.com/hsn723/dkim-manager/api/v2"
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+func TestCheckKey(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		title     string
+		policy    KeyPolicy
+		keyType   dkim.KeyType
+		keyLength dkim.KeyLength
+		errFunc   assert.ErrorAssertionFunc
+	}{
+		{
+			title:     "EmptyPolicy",
+			keyType:   dkim.KeyTypeRSA,
+			keyLength: dkim.KeyLength1024,
+			errFunc:   assert.NoError,
+		},
+		{
+			title:     "WeakRSAKey",
+			policy:    KeyPolicy{MinRSAKeyLength: dkim.KeyLength2048},
+			keyType:   dkim.KeyTypeRSA,
+			keyLength: dkim.KeyLength1024,
+			errFunc:   assert.Error,
+		},
+		{
+			title:     "StrongRSAKey",
+			policy:    KeyPolicy{MinRSAKeyLength: dkim.KeyLength2048},
+			keyType:   dkim.KeyTypeRSA,
+			keyLength: dkim.KeyLength4096,
+			errFunc:   assert.NoError,
+		},
+		{
+			title:   "ED25519IgnoresKeyLength",
+			policy:  KeyPolicy{MinRSAKeyLength: dkim.KeyLength2048},
+			keyType: dkim.KeyTypeED25519,
+			errFunc: assert.NoError,
+		},
+		{
+			title:     "KeyTypeNotAllowed",
+			policy:    KeyPolicy{AllowedKeyTypes: []dkim.KeyType{dkim.KeyTypeED25519}},
+			keyType:   dkim.KeyTypeRSA,
+			keyLength: dkim.KeyLength2048,
+			errFunc:   assert.Error,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := tc.policy.CheckKey(tc.keyType, tc.keyLength)
+			tc.errFunc(t, err)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrKeyPolicyViolation)
+			}
+		})
+	}
+}
+
+func TestCheckDKIMKey(t *testing.T) {
+	t.Parallel()
+
+	dk := &dkimmanagerv2.DKIMKey{
+		Spec: dkimmanagerv2.DKIMKeySpec{
+			Selector:        "rsa",
+			Domain:          "example.com",
+			KeyType:         dkim.KeyTypeRSA,
+			KeyLength:       dkim.KeyLength2048,
+			ED25519Selector: "ed25519",
+		},
+	}
+	assert.NoError(t, KeyPolicy{MinRSAKeyLength: dkim.KeyLength2048}.CheckDKIMKey(dk))
+	err := KeyPolicy{AllowedKeyTypes: []dkim.KeyType{dkim.KeyTypeRSA}}.CheckDKIMKey(dk)
+	assert.ErrorIs(t, err, ErrKeyPolicyViolation)
+}
+
+func TestCheckAge(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	p := KeyPolicy{MaxKeyAge: 24 * time.Hour}
+	assert.NoError(t, p.CheckAge(now.Add(-time.Hour), now))
+	assert.ErrorIs(t, p.CheckAge(now.Add(-48*time.Hour), now), ErrRotationRequired)
+	assert.Equal(t, now.Add(24*time.Hour), p.RotationDeadline(now))
+	assert.NoError(t, KeyPolicy{}.CheckAge(now.Add(-48*time.Hour), now))
+	assert.True(t, KeyPolicy{}.RotationDeadline(now).IsZero())
+}
+
+func TestForPurpose(t *testing.T) {
+	t.Parallel()
+
+	p := KeyPolicy{MaxKeyAge: 180 * 24 * time.Hour, MaxARCKeyAge: 30 * 24 * time.Hour}
+	assert.Equal(t, p.MaxKeyAge, p.ForPurpose(dkim.KeyPurposeDKIM).MaxKeyAge)
+	assert.Equal(t, p.MaxARCKeyAge, p.ForPurpose(dkim.KeyPurposeARC).MaxKeyAge)
+	assert.Equal(t, p.MaxKeyAge, KeyPolicy{MaxKeyAge: p.MaxKeyAge}.ForPurpose(dkim.KeyPurposeARC).MaxKeyAge)
+	assert.False(t, KeyPolicy{MaxARCKeyAge: time.Hour}.IsEmpty())
+	assert.True(t, KeyPolicy{MaxARCKeyAge: time.Hour}.ForPurpose(dkim.KeyPurposeDKIM).IsEmpty())
+	assert.False(t, KeyPolicy{MaxARCKeyAge: time.Hour}.ForPurpose(dkim.KeyPurposeARC).IsEmpty())
+}
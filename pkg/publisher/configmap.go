@@ -0,0 +1,150 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+const (
+	zoneSuffix = ".zone"
+	jsonSuffix = ".json"
+)
+
+// ConfigMapRecord is the JSON representation of a record written by ConfigMapPublisher.
+type ConfigMapRecord struct {
+	Name    string   `json:"name"`
+	TTL     uint     `json:"ttl"`
+	Type    string   `json:"type"`
+	Targets []string `json:"targets"`
+}
+
+// zoneRecords formats the record as lines of a BIND zone file.
+func (r ConfigMapRecord) zoneRecords() string {
+	var b strings.Builder
+	for _, target := range r.Targets {
+		if r.Type == RecordTypeCNAME {
+			fmt.Fprintf(&b, "%s.\t%d\tIN\tCNAME\t%s\n", strings.TrimSuffix(r.Name, "."), r.TTL, canonicalName(target))
+			continue
+		}
+		b.WriteString(dkim.GenZoneRecord(r.Name, r.TTL, target))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ConfigMapPublisher writes records to a single ConfigMap, for DNS zones managed outside of the cluster.
+// Each owner gets a BIND zone file snippet under <namespace>_<name>.zone and the same records as JSON
+// under <namespace>_<name>.json.
+type ConfigMapPublisher struct {
+	store configMapStore
+}
+
+var _ Publisher = &ConfigMapPublisher{}
+
+// NewConfigMapPublisher returns a ConfigMapPublisher writing to the ConfigMap identified by key,
+// which is created if missing. The reader is used to get the ConfigMap, so that ConfigMaps do not need to be cached.
+func NewConfigMapPublisher(c client.Client, reader client.Reader, key types.NamespacedName) *ConfigMapPublisher {
+	return &ConfigMapPublisher{
+		store: configMapStore{client: c, reader: reader, key: key},
+	}
+}
+
+func ownerKey(owner client.Object) string {
+	return owner.GetNamespace() + "_" + recordSetName(owner)
+}
+
+// Publish writes the zone file snippet and JSON records of the owner.
+func (p *ConfigMapPublisher) Publish(ctx context.Context, owner client.Object, records []Record, _ PublishOptions) error {
+	var zone strings.Builder
+	jsonRecords := make([]ConfigMapRecord, 0, len(records))
+	for _, rec := range records {
+		jsonRecord := ConfigMapRecord{
+			Name:    rec.Name,
+			TTL:     rec.TTL,
+			Type:    rec.RecordType(),
+			Targets: rec.Targets,
+		}
+		zone.WriteString(jsonRecord.zoneRecords())
+		jsonRecords = append(jsonRecords, jsonRecord)
+	}
+	data, err := json.MarshalIndent(jsonRecords, "", "  ")
+	if err != nil {
+		return err
+	}
+	key := ownerKey(owner)
+	return p.store.update(ctx, func(cm *corev1.ConfigMap) error {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key+zoneSuffix] = zone.String()
+		cm.Data[key+jsonSuffix] = string(data) + "\n"
+		return nil
+	})
+}
+
+// Unpublish removes the entries of the owner.
+func (p *ConfigMapPublisher) Unpublish(ctx context.Context, owner client.Object, _ []string) error {
+	key := ownerKey(owner)
+	return p.store.update(ctx, func(cm *corev1.ConfigMap) error {
+		delete(cm.Data, key+zoneSuffix)
+		delete(cm.Data, key+jsonSuffix)
+		return nil
+	})
+}
+
+// Release does nothing, as the entries of the owner are not removed along with it.
+func (p *ConfigMapPublisher) Release(context.Context, client.Object) error {
+	return nil
+}
+
+// configMapStore updates a single ConfigMap shared by all owners.
+type configMapStore struct {
+	client client.Client
+	reader client.Reader
+	key    types.NamespacedName
+}
+
+// update applies mutate to the ConfigMap, creating it if needed, and retries on conflicts
+// with concurrent updates for other owners. The ConfigMap is left untouched if mutate does not change it.
+func (s *configMapStore) update(ctx context.Context, mutate func(cm *corev1.ConfigMap) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.reader.Get(ctx, s.key, cm)
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.key.Name, Namespace: s.key.Namespace},
+			}
+			if err := mutate(cm); err != nil {
+				return err
+			}
+			err = s.client.Create(ctx, cm)
+			if apierrors.IsAlreadyExists(err) {
+				return apierrors.NewConflict(corev1.Resource("configmaps"), s.key.Name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get ConfigMap %s: %w", s.key, err)
+		}
+		orig := cm.DeepCopy()
+		if err := mutate(cm); err != nil {
+			return err
+		}
+		if equality.Semantic.DeepEqual(orig, cm) {
+			return nil
+		}
+		return s.client.Update(ctx, cm)
+	})
+}
@@ -0,0 +1,93 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapPublisher(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	key := types.NamespacedName{Namespace: "dkim-manager", Name: "dkim-records"}
+	p := NewConfigMapPublisher(c, c, key)
+	ctx := context.Background()
+
+	owner1 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "selector1"}}
+	owner2 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "selector2"}}
+	records := []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=abc\""}},
+	}
+	require.NoError(t, p.Publish(ctx, owner1, records, PublishOptions{}))
+	require.NoError(t, p.Publish(ctx, owner2, []Record{
+		{Name: "selector2._domainkey.example.org", Type: RecordTypeCNAME, TTL: 60, Targets: []string{"selector2._domainkey.example.org.dkim.example.net"}},
+		{Name: "selector2._domainkey.example.org.dkim.example.net", TTL: 60, Targets: []string{"\"p=def\""}},
+	}, PublishOptions{}))
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Len(t, cm.Data, 4)
+	assert.Equal(t, "selector1._domainkey.example.com.\t3600\tIN\tTXT\t\"v=DKIM1; k=ed25519;\" \"p=abc\"\n", cm.Data["team-a_selector1.zone"])
+	var jsonRecords []ConfigMapRecord
+	require.NoError(t, json.Unmarshal([]byte(cm.Data["team-a_selector1.json"]), &jsonRecords))
+	assert.Equal(t, []ConfigMapRecord{{
+		Name:    "selector1._domainkey.example.com",
+		TTL:     3600,
+		Type:    "TXT",
+		Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=abc\""},
+	}}, jsonRecords)
+	assert.Equal(t, "selector2._domainkey.example.org.\t60\tIN\tCNAME\tselector2._domainkey.example.org.dkim.example.net.\n"+
+		"selector2._domainkey.example.org.dkim.example.net.\t60\tIN\tTXT\t\"p=def\"\n", cm.Data["team-b_selector2.zone"])
+
+	require.NoError(t, p.Unpublish(ctx, owner1, []string{"selector1._domainkey.example.com"}))
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Len(t, cm.Data, 2)
+	assert.Contains(t, cm.Data, "team-b_selector2.zone")
+	assert.Contains(t, cm.Data, "team-b_selector2.json")
+}
+
+// recordSetOwner is an owner keeping its records under a name of its own.
+type recordSetOwner struct {
+	corev1.ConfigMap
+}
+
+func (o *recordSetOwner) RecordSetName() string {
+	return o.Name + "-dmarc"
+}
+
+func TestConfigMapPublisherRecordSetName(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	key := types.NamespacedName{Namespace: "dkim-manager", Name: "dkim-records"}
+	p := NewConfigMapPublisher(c, c, key)
+	ctx := context.Background()
+
+	owner1 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "example"}}
+	owner2 := &recordSetOwner{ConfigMap: corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "example"}}}
+	require.NoError(t, p.Publish(ctx, owner1, []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"p=abc\""}},
+	}, PublishOptions{}))
+	require.NoError(t, p.Publish(ctx, owner2, []Record{
+		{Name: "_dmarc.example.com", TTL: 3600, Targets: []string{"\"v=DMARC1; p=reject;\""}},
+	}, PublishOptions{}))
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Contains(t, cm.Data["team-a_example.zone"], "selector1._domainkey.example.com.")
+	assert.Contains(t, cm.Data["team-a_example-dmarc.zone"], "_dmarc.example.com.")
+}
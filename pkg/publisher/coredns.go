@@ -0,0 +1,205 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ZoneSerialAnnotation records the SOA serial of the zone file maintained by CoreDNSPublisher.
+	ZoneSerialAnnotation = "dkim-manager.atelierhsn.com/zone-serial"
+
+	defaultZoneTTL = 3600
+	soaRefresh     = 7200
+	soaRetry       = 1800
+	soaExpire      = 1209600
+	soaNegativeTTL = 300
+	zoneFilePrefix = "db."
+)
+
+// CoreDNSOptions configures a CoreDNSPublisher.
+type CoreDNSOptions struct {
+	// ConfigMap is the ConfigMap holding the zone file, mounted by CoreDNS.
+	ConfigMap types.NamespacedName
+	// Zone is the zone served by CoreDNS, e.g. _domainkey.example.com. All records must belong to it.
+	Zone string
+	// Nameservers are the hostnames of the nameservers the zone is delegated to. The first one is the SOA primary.
+	Nameservers []string
+	// Hostmaster is the mailbox of the zone administrator, in domain name format. Defaults to hostmaster.<zone>.
+	Hostmaster string
+	// TTL is the TTL of the SOA and NS records. Defaults to 3600.
+	TTL uint
+}
+
+// CoreDNSPublisher maintains a complete zone file, for the file plugin of CoreDNS, in a ConfigMap.
+// The zone file is stored under db.<zone>, and the records of each owner are kept as JSON
+// under <namespace>_<name>.json so that the zone file can be regenerated on every change.
+// The SOA serial is increased whenever the records change, which makes CoreDNS reload the zone.
+type CoreDNSPublisher struct {
+	store       configMapStore
+	zone        string
+	nameservers []string
+	hostmaster  string
+	ttl         uint
+	now         func() time.Time
+}
+
+var _ Publisher = &CoreDNSPublisher{}
+
+// NewCoreDNSPublisher returns a CoreDNSPublisher. The reader is used to get the ConfigMap,
+// so that ConfigMaps do not need to be cached.
+func NewCoreDNSPublisher(c client.Client, reader client.Reader, opts CoreDNSOptions) (*CoreDNSPublisher, error) {
+	if opts.Zone == "" {
+		return nil, errors.New("CoreDNS zone must not be empty")
+	}
+	if len(opts.Nameservers) == 0 {
+		return nil, errors.New("CoreDNS zone requires at least one nameserver")
+	}
+	zone := canonicalName(opts.Zone)
+	hostmaster := opts.Hostmaster
+	if hostmaster == "" {
+		hostmaster = "hostmaster." + zone
+	}
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = defaultZoneTTL
+	}
+	nameservers := make([]string, len(opts.Nameservers))
+	for i, ns := range opts.Nameservers {
+		nameservers[i] = canonicalName(ns)
+	}
+	return &CoreDNSPublisher{
+		store:       configMapStore{client: c, reader: reader, key: opts.ConfigMap},
+		zone:        zone,
+		nameservers: nameservers,
+		hostmaster:  canonicalName(hostmaster),
+		ttl:         ttl,
+		now:         time.Now,
+	}, nil
+}
+
+// ZoneFileName returns the ConfigMap key, and file name once mounted, of the zone file.
+func (p *CoreDNSPublisher) ZoneFileName() string {
+	return zoneFilePrefix + strings.TrimSuffix(p.zone, ".")
+}
+
+// Publish stores the records of the owner and regenerates the zone file.
+// CNAME records outside of the zone are skipped, as they delegate records of other domains
+// to the zone and are managed by the owners of these domains.
+func (p *CoreDNSPublisher) Publish(ctx context.Context, owner client.Object, records []Record, _ PublishOptions) error {
+	jsonRecords := make([]ConfigMapRecord, 0, len(records))
+	for _, rec := range records {
+		if !inZone(rec.Name, p.zone) {
+			if rec.RecordType() == RecordTypeCNAME {
+				continue
+			}
+			return fmt.Errorf("record %s does not belong to zone %s", rec.Name, p.zone)
+		}
+		jsonRecords = append(jsonRecords, ConfigMapRecord{
+			Name:    rec.Name,
+			TTL:     rec.TTL,
+			Type:    rec.RecordType(),
+			Targets: rec.Targets,
+		})
+	}
+	data, err := json.Marshal(jsonRecords)
+	if err != nil {
+		return err
+	}
+	key := ownerKey(owner) + jsonSuffix
+	return p.store.update(ctx, func(cm *corev1.ConfigMap) error {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(data)
+		return p.updateZoneFile(cm)
+	})
+}
+
+// Unpublish removes the records of the owner and regenerates the zone file.
+func (p *CoreDNSPublisher) Unpublish(ctx context.Context, owner client.Object, _ []string) error {
+	key := ownerKey(owner) + jsonSuffix
+	return p.store.update(ctx, func(cm *corev1.ConfigMap) error {
+		delete(cm.Data, key)
+		return p.updateZoneFile(cm)
+	})
+}
+
+// Release does nothing, as the records of the owner are not removed along with it.
+func (p *CoreDNSPublisher) Release(context.Context, client.Object) error {
+	return nil
+}
+
+// updateZoneFile regenerates the zone file from the records of all owners,
+// increasing the serial only if the records changed.
+func (p *CoreDNSPublisher) updateZoneFile(cm *corev1.ConfigMap) error {
+	body, err := p.zoneRecords(cm.Data)
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	var serial uint32
+	if s, ok := cm.Annotations[ZoneSerialAnnotation]; ok {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid zone serial %q: %w", s, err)
+		}
+		serial = uint32(v)
+		if cm.Data[p.ZoneFileName()] == p.zoneFile(serial, body) {
+			return nil
+		}
+	}
+	// Serials are timestamps when possible, but must always increase.
+	serial = max(serial+1, uint32(p.now().Unix()))
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[ZoneSerialAnnotation] = strconv.FormatUint(uint64(serial), 10)
+	cm.Data[p.ZoneFileName()] = p.zoneFile(serial, body)
+	return nil
+}
+
+// zoneRecords renders the records of all owners, in a stable order.
+func (p *CoreDNSPublisher) zoneRecords(data map[string]string) (string, error) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if strings.HasSuffix(k, jsonSuffix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		var records []ConfigMapRecord
+		if err := json.Unmarshal([]byte(data[k]), &records); err != nil {
+			return "", fmt.Errorf("invalid records in %s: %w", k, err)
+		}
+		for _, rec := range records {
+			b.WriteString(rec.zoneRecords())
+		}
+	}
+	return b.String(), nil
+}
+
+func (p *CoreDNSPublisher) zoneFile(serial uint32, records string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ORIGIN %s\n", p.zone)
+	fmt.Fprintf(&b, "@\t%d\tIN\tSOA\t%s %s %d %d %d %d %d\n", p.ttl, p.nameservers[0], p.hostmaster, serial, soaRefresh, soaRetry, soaExpire, soaNegativeTTL)
+	for _, ns := range p.nameservers {
+		fmt.Fprintf(&b, "@\t%d\tIN\tNS\t%s\n", p.ttl, ns)
+	}
+	b.WriteString(records)
+	return b.String()
+}
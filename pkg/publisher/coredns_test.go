@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCoreDNSPublisher(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	key := types.NamespacedName{Namespace: "dkim-manager", Name: "dkim-zone"}
+	p, err := NewCoreDNSPublisher(c, c, CoreDNSOptions{
+		ConfigMap:   key,
+		Zone:        "_domainkey.example.com",
+		Nameservers: []string{"ns1.example.com", "ns2.example.com."},
+	})
+	require.NoError(t, err)
+	p.now = func() time.Time { return time.Unix(1000, 0) }
+	assert.Equal(t, "db._domainkey.example.com", p.ZoneFileName())
+	ctx := context.Background()
+
+	owner1 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "selector1"}}
+	owner2 := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "selector2"}}
+	require.NoError(t, p.Publish(ctx, owner2, []Record{
+		{Name: "selector2._domainkey.example.org", Type: RecordTypeCNAME, TTL: 60, Targets: []string{"selector2._domainkey.example.com"}},
+		{Name: "selector2._domainkey.example.com", TTL: 60, Targets: []string{"\"p=def\""}},
+	}, PublishOptions{}))
+	require.NoError(t, p.Publish(ctx, owner1, []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=abc\""}},
+	}, PublishOptions{}))
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Equal(t, "1001", cm.Annotations[ZoneSerialAnnotation])
+	assert.Equal(t, `$ORIGIN _domainkey.example.com.
+@	3600	IN	SOA	ns1.example.com. hostmaster._domainkey.example.com. 1001 7200 1800 1209600 300
+@	3600	IN	NS	ns1.example.com.
+@	3600	IN	NS	ns2.example.com.
+selector1._domainkey.example.com.	3600	IN	TXT	"v=DKIM1; k=ed25519;" "p=abc"
+selector2._domainkey.example.com.	60	IN	TXT	"p=def"
+`, cm.Data["db._domainkey.example.com"])
+
+	// Publishing the same records leaves the serial unchanged.
+	require.NoError(t, p.Publish(ctx, owner1, []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=abc\""}},
+	}, PublishOptions{}))
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Equal(t, "1001", cm.Annotations[ZoneSerialAnnotation])
+
+	p.now = func() time.Time { return time.Unix(2000, 0) }
+	require.NoError(t, p.Unpublish(ctx, owner2, []string{"selector2._domainkey.example.com"}))
+	require.NoError(t, c.Get(ctx, key, cm))
+	assert.Equal(t, "2000", cm.Annotations[ZoneSerialAnnotation])
+	assert.NotContains(t, cm.Data["db._domainkey.example.com"], "selector2")
+	assert.NotContains(t, cm.Data, "team-b_selector2.json")
+
+	err = p.Publish(ctx, owner1, []Record{{Name: "selector1._domainkey.example.org", Targets: []string{"\"p=abc\""}}}, PublishOptions{})
+	assert.Error(t, err)
+}
+
+func TestNewCoreDNSPublisher(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewCoreDNSPublisher(nil, nil, CoreDNSOptions{Nameservers: []string{"ns1.example.com"}})
+	assert.Error(t, err)
+	_, err = NewCoreDNSPublisher(nil, nil, CoreDNSOptions{Zone: "_domainkey.example.com"})
+	assert.Error(t, err)
+	p, err := NewCoreDNSPublisher(nil, nil, CoreDNSOptions{Zone: "_domainkey.example.com", Nameservers: []string{"ns1.example.com"}, Hostmaster: "admin.example.com", TTL: 60})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin.example.com.", p.hostmaster)
+	assert.Equal(t, uint(60), p.ttl)
+}
@@ -0,0 +1,173 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+// ReleasedLabel is set by ExternalDNSPublisher.Release on the DNSEndpoints it detaches from their owner,
+// to the kind of the owner, so that they can be found and removed once no longer needed.
+const ReleasedLabel = "dkim-manager.atelierhsn.com/released-by"
+
+// ExternalDNSPublisher publishes records through an external-dns DNSEndpoint named after,
+// and controlled by, the owner. Owners implementing RecordSetNamer choose the name of the DNSEndpoint.
+type ExternalDNSPublisher struct {
+	client     client.Client
+	reader     client.Reader
+	scheme     *runtime.Scheme
+	fieldOwner client.FieldOwner
+	gvk        schema.GroupVersionKind
+}
+
+var _ Publisher = &ExternalDNSPublisher{}
+
+// NewExternalDNSPublisher returns an ExternalDNSPublisher creating DNSEndpoints of the given GroupVersionKind.
+// The reader is used to list DNSEndpoints when unpublishing, so that they do not need to be cached.
+func NewExternalDNSPublisher(c client.Client, reader client.Reader, scheme *runtime.Scheme, fieldOwner client.FieldOwner, gvk schema.GroupVersionKind) *ExternalDNSPublisher {
+	return &ExternalDNSPublisher{
+		client:     c,
+		reader:     reader,
+		scheme:     scheme,
+		fieldOwner: fieldOwner,
+		gvk:        gvk,
+	}
+}
+
+// Publish applies the DNSEndpoint of the owner, with the labels and annotations of opts.
+// It returns ErrRecordSetConflict if the DNSEndpoint exists and is controlled by another resource,
+// or was released by an owner of another kind.
+func (p *ExternalDNSPublisher) Publish(ctx context.Context, owner client.Object, records []Record, opts PublishOptions) error {
+	de := externaldns.NewDNSEndpoint(p.gvk)
+	de.SetName(recordSetName(owner))
+	de.SetNamespace(owner.GetNamespace())
+	if err := p.claim(ctx, owner, de.GetName()); err != nil {
+		return err
+	}
+	de.SetLabels(opts.Labels)
+	de.SetAnnotations(opts.Annotations)
+	endpoints := make([]map[string]interface{}, 0, len(records))
+	for _, rec := range records {
+		endpoint := map[string]interface{}{
+			"dnsName":    rec.Name,
+			"recordTTL":  rec.TTL,
+			"recordType": rec.RecordType(),
+			"targets":    rec.Targets,
+		}
+		if rec.SetIdentifier != "" {
+			endpoint["setIdentifier"] = rec.SetIdentifier
+		}
+		if len(rec.ProviderSpecific) > 0 {
+			props := make([]map[string]interface{}, 0, len(rec.ProviderSpecific))
+			for _, prop := range rec.ProviderSpecific {
+				props = append(props, map[string]interface{}{
+					"name":  prop.Name,
+					"value": prop.Value,
+				})
+			}
+			endpoint["providerSpecific"] = props
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	de.UnstructuredContent()["spec"] = map[string]interface{}{
+		"endpoints": endpoints,
+	}
+	if err := ctrl.SetControllerReference(owner, de, p.scheme); err != nil {
+		return err
+	}
+	ac := client.ApplyConfigurationFromUnstructured(de)
+	return p.client.Apply(ctx, ac, p.fieldOwner, client.ForceOwnership)
+}
+
+// claim checks that the DNSEndpoint named name can be applied for the owner. A DNSEndpoint released by
+// an owner of the same kind, such as a previous incarnation of the owner, is adopted.
+func (p *ExternalDNSPublisher) claim(ctx context.Context, owner client.Object, name string) error {
+	existing := externaldns.NewDNSEndpoint(p.gvk)
+	err := p.reader.Get(ctx, client.ObjectKey{Namespace: owner.GetNamespace(), Name: name}, existing)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if metav1.IsControlledBy(existing, owner) {
+		return nil
+	}
+	if ref := metav1.GetControllerOf(existing); ref != nil {
+		return fmt.Errorf("%w: DNSEndpoint %s is controlled by %s %s", ErrRecordSetConflict, name, ref.Kind, ref.Name)
+	}
+	gvk, err := apiutil.GVKForObject(owner, p.scheme)
+	if err != nil {
+		return err
+	}
+	released, ok := existing.GetLabels()[ReleasedLabel]
+	if !ok || released != gvk.Kind {
+		return fmt.Errorf("%w: DNSEndpoint %s is not managed by a %s", ErrRecordSetConflict, name, gvk.Kind)
+	}
+	patch := client.MergeFrom(existing.DeepCopy())
+	labels := existing.GetLabels()
+	delete(labels, ReleasedLabel)
+	existing.SetLabels(labels)
+	return p.client.Patch(ctx, existing, patch)
+}
+
+// Unpublish deletes the DNSEndpoints controlled by the owner.
+func (p *ExternalDNSPublisher) Unpublish(ctx context.Context, owner client.Object, _ []string) error {
+	del := externaldns.NewDNSEndpointList(p.gvk)
+	lo := &client.ListOptions{Namespace: owner.GetNamespace()}
+	if err := p.reader.List(ctx, del, lo); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, de := range del.Items {
+		if !metav1.IsControlledBy(&de, owner) {
+			continue
+		}
+		if err := p.client.Delete(ctx, &de); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release removes the owner references to the owner from the DNSEndpoints it controls,
+// so that they are not garbage collected along with it, and labels them with ReleasedLabel.
+func (p *ExternalDNSPublisher) Release(ctx context.Context, owner client.Object) error {
+	gvk, err := apiutil.GVKForObject(owner, p.scheme)
+	if err != nil {
+		return err
+	}
+	del := externaldns.NewDNSEndpointList(p.gvk)
+	lo := &client.ListOptions{Namespace: owner.GetNamespace()}
+	if err := p.reader.List(ctx, del, lo); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	for _, de := range del.Items {
+		if !metav1.IsControlledBy(&de, owner) {
+			continue
+		}
+		patch := client.MergeFrom(de.DeepCopy())
+		if err := controllerutil.RemoveOwnerReference(owner, &de, p.scheme); err != nil {
+			return err
+		}
+		labels := de.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[ReleasedLabel] = gvk.Kind
+		de.SetLabels(labels)
+		if err := p.client.Patch(ctx, &de, patch); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
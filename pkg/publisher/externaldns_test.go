@@ -0,0 +1,83 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/hsn723/dkim-manager/pkg/externaldns"
+)
+
+func TestExternalDNSPublisherRelease(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	gvk := externaldns.DNSEndpoint().GroupVersionKind()
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "selector1", UID: "uid-selector1"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "selector2", UID: "uid-selector2"}}
+	newEndpoint := func(o *corev1.ConfigMap) client.Object {
+		de := externaldns.NewDNSEndpoint(gvk)
+		de.SetNamespace(o.Namespace)
+		de.SetName(o.Name)
+		de.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(o, corev1.SchemeGroupVersion.WithKind("ConfigMap"))})
+		return de
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newEndpoint(owner), newEndpoint(other)).Build()
+	p := NewExternalDNSPublisher(c, c, scheme, "dkim-manager", gvk)
+	ctx := context.Background()
+
+	require.NoError(t, p.Release(ctx, owner))
+	de := externaldns.NewDNSEndpoint(gvk)
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(owner), de))
+	assert.Empty(t, de.GetOwnerReferences())
+	assert.Equal(t, "ConfigMap", de.GetLabels()[ReleasedLabel])
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(other), de))
+	assert.True(t, metav1.IsControlledBy(de, other))
+
+	require.NoError(t, p.Unpublish(ctx, owner, nil))
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(owner), de), "released DNSEndpoints must not be deleted")
+	require.NoError(t, p.Unpublish(ctx, other, nil))
+	assert.Error(t, c.Get(ctx, client.ObjectKeyFromObject(other), de))
+}
+
+func TestExternalDNSPublisherConflict(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	gvk := externaldns.DNSEndpoint().GroupVersionKind()
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid-configmap"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid-secret"}}
+	recreated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid-recreated"}}
+	de := externaldns.NewDNSEndpoint(gvk)
+	de.SetNamespace(owner.Namespace)
+	de.SetName(owner.Name)
+	de.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(owner, corev1.SchemeGroupVersion.WithKind("ConfigMap"))})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(de).Build()
+	p := NewExternalDNSPublisher(c, c, scheme, "dkim-manager", gvk)
+	ctx := context.Background()
+	records := []Record{{Name: "foo.example.com", TTL: 3600, Targets: []string{"v=spf1 -all"}}}
+
+	err := p.Publish(ctx, secret, records, PublishOptions{})
+	assert.ErrorIs(t, err, ErrRecordSetConflict, "DNSEndpoints controlled by another resource must not be taken over")
+	err = p.Publish(ctx, recreated, records, PublishOptions{})
+	assert.ErrorIs(t, err, ErrRecordSetConflict)
+
+	require.NoError(t, p.Release(ctx, owner))
+	err = p.Publish(ctx, secret, records, PublishOptions{})
+	assert.ErrorIs(t, err, ErrRecordSetConflict, "DNSEndpoints released by another kind must not be adopted")
+	require.NoError(t, p.Publish(ctx, recreated, records, PublishOptions{}))
+	got := externaldns.NewDNSEndpoint(gvk)
+	require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(owner), got))
+	assert.True(t, metav1.IsControlledBy(got, recreated))
+	assert.NotContains(t, got.GetLabels(), ReleasedLabel)
+}
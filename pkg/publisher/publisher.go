@@ -0,0 +1,91 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Record types supported by publishers.
+const (
+	RecordTypeTXT   = "TXT"
+	RecordTypeCNAME = "CNAME"
+)
+
+// Record is a DNS record to publish.
+type Record struct {
+	// Name is the DNS name of the record, without trailing dot.
+	Name string
+	// Type is the record type, RecordTypeTXT or RecordTypeCNAME. Defaults to RecordTypeTXT.
+	Type string
+	// TTL is the record TTL in seconds.
+	TTL uint
+	// Targets are the record values. TXT values are in presentation format, such as the output of dkim.GenTXTValue,
+	// and CNAME values are DNS names.
+	Targets []string
+	// SetIdentifier distinguishes records sharing the same name, for providers supporting routing policies.
+	// Only used by publishers supporting it.
+	SetIdentifier string
+	// ProviderSpecific are properties specific to the DNS provider. Only used by publishers supporting them.
+	ProviderSpecific []ProviderSpecificProperty
+}
+
+// RecordType returns the type of the record.
+func (r Record) RecordType() string {
+	if r.Type == "" {
+		return RecordTypeTXT
+	}
+	return r.Type
+}
+
+// inZone returns true if name belongs to zone, which must be in canonical form.
+func inZone(name, zone string) bool {
+	fqdn := canonicalName(name)
+	return fqdn == zone || strings.HasSuffix(fqdn, "."+zone)
+}
+
+// ProviderSpecificProperty is a property specific to a DNS provider.
+type ProviderSpecificProperty struct {
+	Name  string
+	Value string
+}
+
+// PublishOptions are settings for publishers creating Kubernetes resources to publish records.
+type PublishOptions struct {
+	// Labels are added to the created resources.
+	Labels map[string]string
+	// Annotations are added to the created resources.
+	Annotations map[string]string
+}
+
+// RecordSetNamer is implemented by owners whose records are not published under their own name,
+// so that owners of different kinds sharing a name do not overwrite each other's records.
+type RecordSetNamer interface {
+	// RecordSetName returns the name of the resources or entries holding the records of the owner.
+	RecordSetName() string
+}
+
+// ErrRecordSetConflict is returned by Publish when the resources holding the records of the owner
+// already exist and belong to another resource.
+var ErrRecordSetConflict = errors.New("record set belongs to another resource")
+
+// recordSetName returns the name under which the records of owner are kept.
+func recordSetName(owner client.Object) string {
+	if n, ok := owner.(RecordSetNamer); ok {
+		return n.RecordSetName()
+	}
+	return owner.GetName()
+}
+
+// Publisher publishes DNS records on behalf of a resource.
+type Publisher interface {
+	// Publish ensures that the given records, and only them, are published for the owner.
+	Publish(ctx context.Context, owner client.Object, records []Record, opts PublishOptions) error
+	// Unpublish removes the records published for the owner under the given names.
+	Unpublish(ctx context.Context, owner client.Object, names []string) error
+	// Release leaves the records published for the owner in place, detaching them from the owner
+	// so that they are not removed along with it.
+	Release(ctx context.Context, owner client.Object) error
+}
@@ -0,0 +1,161 @@
+package publisher
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// RFC2136Options configures an RFC2136Publisher.
+type RFC2136Options struct {
+	// Server is the address of the nameserver accepting updates, as host or host:port.
+	Server string
+	// Zone is the zone to update. All records must belong to it.
+	Zone string
+	// TSIGKey authenticates the updates. Nil sends unsigned updates.
+	TSIGKey *TSIGKey
+	// Timeout bounds each update exchange.
+	Timeout time.Duration
+}
+
+// RFC2136Publisher publishes records by sending dynamic DNS updates, as described in RFC 2136,
+// to a nameserver such as BIND, Knot or PowerDNS.
+type RFC2136Publisher struct {
+	server  string
+	zone    string
+	tsigKey *TSIGKey
+	timeout time.Duration
+}
+
+var _ Publisher = &RFC2136Publisher{}
+
+// NewRFC2136Publisher returns an RFC2136Publisher.
+func NewRFC2136Publisher(opts RFC2136Options) (*RFC2136Publisher, error) {
+	if opts.Server == "" {
+		return nil, fmt.Errorf("RFC 2136 server must not be empty")
+	}
+	if opts.Zone == "" {
+		return nil, fmt.Errorf("RFC 2136 zone must not be empty")
+	}
+	if opts.TSIGKey != nil {
+		if err := opts.TSIGKey.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	server := opts.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &RFC2136Publisher{
+		server:  server,
+		zone:    canonicalName(opts.Zone),
+		tsigKey: opts.TSIGKey,
+		timeout: opts.Timeout,
+	}, nil
+}
+
+// Publish replaces the TXT and CNAME record sets under the names of the given records.
+// CNAME records outside of the zone are skipped, as they delegate records of other domains
+// to the zone and are managed by the owners of these domains.
+func (p *RFC2136Publisher) Publish(ctx context.Context, _ client.Object, records []Record, _ PublishOptions) error {
+	m := new(dns.Msg)
+	m.SetUpdate(p.zone)
+	for _, rec := range records {
+		if rec.RecordType() == RecordTypeCNAME && !inZone(rec.Name, p.zone) {
+			continue
+		}
+		name, err := p.recordName(rec.Name)
+		if err != nil {
+			return err
+		}
+		m.RemoveRRset(rrSets(name))
+		for _, target := range rec.Targets {
+			hdr := dns.RR_Header{Name: name, Class: dns.ClassINET, Ttl: uint32(rec.TTL)}
+			if rec.RecordType() == RecordTypeCNAME {
+				hdr.Rrtype = dns.TypeCNAME
+				m.Insert([]dns.RR{&dns.CNAME{Hdr: hdr, Target: canonicalName(target)}})
+				continue
+			}
+			hdr.Rrtype = dns.TypeTXT
+			m.Insert([]dns.RR{&dns.TXT{Hdr: hdr, Txt: txtStrings(target)}})
+		}
+	}
+	return p.send(ctx, m)
+}
+
+// Unpublish deletes the TXT and CNAME record sets under the given names.
+// Names outside of the zone are skipped, as no record can have been published under them.
+func (p *RFC2136Publisher) Unpublish(ctx context.Context, _ client.Object, names []string) error {
+	m := new(dns.Msg)
+	m.SetUpdate(p.zone)
+	for _, n := range names {
+		if !inZone(n, p.zone) {
+			continue
+		}
+		name, err := p.recordName(n)
+		if err != nil {
+			return err
+		}
+		m.RemoveRRset(rrSets(name))
+	}
+	return p.send(ctx, m)
+}
+
+// Release does nothing, as the records are not removed along with the owner.
+func (p *RFC2136Publisher) Release(context.Context, client.Object) error {
+	return nil
+}
+
+func (p *RFC2136Publisher) recordName(name string) (string, error) {
+	if !inZone(name, p.zone) {
+		return "", fmt.Errorf("record %s does not belong to zone %s", name, p.zone)
+	}
+	return canonicalName(name), nil
+}
+
+// txtStrings returns the character-strings of a TXT value in presentation format, escaped as expected by dns.TXT.
+func txtStrings(value string) []string {
+	txt := dkim.SplitTXTValue(value)
+	for i, t := range txt {
+		txt[i] = strings.ReplaceAll(t, `\`, `\\`)
+	}
+	return txt
+}
+
+// rrSets returns the TXT and CNAME record sets under name, to be deleted.
+func rrSets(name string) []dns.RR {
+	return []dns.RR{
+		&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT}},
+		&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME}},
+	}
+}
+
+// send sends the update over TCP, signing it and verifying the signature of the response with the TSIG key if set.
+func (p *RFC2136Publisher) send(ctx context.Context, m *dns.Msg) error {
+	c := &dns.Client{Net: "tcp", Timeout: p.timeout}
+	if k := p.tsigKey; k != nil {
+		name := canonicalName(k.Name)
+		m.SetTsig(name, tsigAlgorithms[canonicalName(k.Algorithm)], tsigFudge, time.Now().Unix())
+		c.TsigSecret = map[string]string{name: base64.StdEncoding.EncodeToString(k.Secret)}
+	}
+	resp, _, err := c.ExchangeContext(ctx, m, p.server)
+	if err != nil {
+		return fmt.Errorf("failed to send update to %s: %w", p.server, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("update rejected by %s: %s", p.server, dns.RcodeToString[resp.Rcode])
+	}
+	// Unsigned responses are not verified by the client.
+	if p.tsigKey != nil && resp.IsTsig() == nil {
+		return fmt.Errorf("failed to authenticate update response from %s: response is not signed", p.server)
+	}
+	return nil
+}
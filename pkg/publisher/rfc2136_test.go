@@ -0,0 +1,238 @@
+package publisher
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateServer is a minimal nameserver applying RFC 2136 updates to TXT and CNAME records in memory.
+type updateServer struct {
+	key *TSIGKey
+	// corruptResponses makes the server sign responses with a wrong secret.
+	corruptResponses bool
+
+	mu      sync.Mutex
+	records map[string][][]string
+	cnames  map[string]string
+}
+
+func (s *updateServer) start(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.records = map[string][][]string{}
+	s.cnames = map[string]string{}
+	srv := &dns.Server{
+		Listener: l,
+		Net:      "tcp",
+		Handler:  dns.HandlerFunc(s.handle),
+		// The default accept function rejects every opcode but QUERY and NOTIFY.
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+	}
+	if s.key != nil {
+		srv.TsigSecret = map[string]string{canonicalName(s.key.Name): base64.StdEncoding.EncodeToString(s.key.Secret)}
+	}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return l.Addr().String()
+}
+
+func (s *updateServer) handle(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	tsig := req.IsTsig()
+	switch {
+	case s.key != nil && (tsig == nil || w.TsigStatus() != nil):
+		resp.Rcode = dns.RcodeNotAuth
+	case req.Opcode != dns.OpcodeUpdate:
+		resp.Rcode = dns.RcodeRefused
+	default:
+		if err := s.apply(req); err != nil {
+			resp.Rcode = dns.RcodeRefused
+		}
+	}
+	if s.key == nil || tsig == nil || w.TsigStatus() != nil {
+		_ = w.WriteMsg(resp)
+		return
+	}
+	resp.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsigFudge, time.Now().Unix())
+	if !s.corruptResponses {
+		_ = w.WriteMsg(resp)
+		return
+	}
+	buf, _, err := dns.TsigGenerate(resp, base64.StdEncoding.EncodeToString([]byte("wrong")), tsig.MAC, false)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(buf)
+}
+
+func (s *updateServer) apply(req *dns.Msg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rr := range req.Ns {
+		h := rr.Header()
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeCNAME {
+				delete(s.cnames, h.Name)
+			} else {
+				delete(s.records, h.Name)
+			}
+		case dns.ClassINET:
+			switch rr := rr.(type) {
+			case *dns.CNAME:
+				s.cnames[h.Name] = rr.Target
+			case *dns.TXT:
+				s.records[h.Name] = append(s.records[h.Name], rr.Txt)
+			default:
+				return dns.ErrRdata
+			}
+		default:
+			return dns.ErrRdata
+		}
+	}
+	return nil
+}
+
+func (s *updateServer) get(name string) [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[name]
+}
+
+func (s *updateServer) getCNAME(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cnames[name]
+}
+
+func testTSIGKey() *TSIGKey {
+	return &TSIGKey{
+		Name:      "dkim-manager",
+		Algorithm: "hmac-sha256",
+		Secret:    []byte("0123456789abcdef0123456789abcdef"),
+	}
+}
+
+func TestRFC2136Publish(t *testing.T) {
+	t.Parallel()
+
+	srv := &updateServer{key: testTSIGKey()}
+	addr := srv.start(t)
+	p, err := NewRFC2136Publisher(RFC2136Options{Server: addr, Zone: "example.com", TSIGKey: testTSIGKey(), Timeout: time.Second})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	err = p.Publish(ctx, nil, []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=abc\""}},
+	}, PublishOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"v=DKIM1; k=ed25519;", "p=abc"}}, srv.get("selector1._domainkey.example.com."))
+
+	err = p.Publish(ctx, nil, []Record{
+		{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"v=DKIM1; k=ed25519;\" \"p=def\""}},
+	}, PublishOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"v=DKIM1; k=ed25519;", "p=def"}}, srv.get("selector1._domainkey.example.com."))
+
+	err = p.Unpublish(ctx, nil, []string{"selector1._domainkey.example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, srv.get("selector1._domainkey.example.com."))
+}
+
+func TestRFC2136PublishCNAME(t *testing.T) {
+	t.Parallel()
+
+	srv := &updateServer{key: testTSIGKey()}
+	addr := srv.start(t)
+	p, err := NewRFC2136Publisher(RFC2136Options{Server: addr, Zone: "example.com", TSIGKey: testTSIGKey(), Timeout: time.Second})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	err = p.Publish(ctx, nil, []Record{
+		{Name: "selector1._domainkey.example.com", Type: RecordTypeCNAME, TTL: 3600, Targets: []string{"selector1._domainkey.example.com.dkim.example.net"}},
+		{Name: "selector1._domainkey.example.org", Type: RecordTypeCNAME, TTL: 3600, Targets: []string{"selector1._domainkey.example.org.example.com"}},
+		{Name: "selector1._domainkey.example.org.example.com", TTL: 3600, Targets: []string{"\"p=abc\""}},
+	}, PublishOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "selector1._domainkey.example.com.dkim.example.net.", srv.getCNAME("selector1._domainkey.example.com."))
+	assert.Empty(t, srv.getCNAME("selector1._domainkey.example.org."))
+	assert.Equal(t, [][]string{{"p=abc"}}, srv.get("selector1._domainkey.example.org.example.com."))
+
+	err = p.Unpublish(ctx, nil, []string{"selector1._domainkey.example.com", "selector1._domainkey.example.org", "selector1._domainkey.example.org.example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, srv.getCNAME("selector1._domainkey.example.com."))
+	assert.Empty(t, srv.get("selector1._domainkey.example.org.example.com."))
+}
+
+func TestRFC2136Errors(t *testing.T) {
+	t.Parallel()
+
+	wrongKey := testTSIGKey()
+	wrongKey.Secret = []byte("wrong")
+	record := []Record{{Name: "selector1._domainkey.example.com", TTL: 3600, Targets: []string{"\"p=abc\""}}}
+
+	cases := []struct {
+		title   string
+		server  *updateServer
+		key     *TSIGKey
+		records []Record
+	}{
+		{
+			title:   "WrongKey",
+			server:  &updateServer{key: testTSIGKey()},
+			key:     wrongKey,
+			records: record,
+		},
+		{
+			title:   "Unsigned",
+			server:  &updateServer{key: testTSIGKey()},
+			records: record,
+		},
+		{
+			title:   "CorruptResponse",
+			server:  &updateServer{key: testTSIGKey(), corruptResponses: true},
+			key:     testTSIGKey(),
+			records: record,
+		},
+		{
+			title:   "OutsideZone",
+			server:  &updateServer{key: testTSIGKey()},
+			key:     testTSIGKey(),
+			records: []Record{{Name: "selector1._domainkey.example.org", Targets: []string{"\"p=abc\""}}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			addr := tc.server.start(t)
+			p, err := NewRFC2136Publisher(RFC2136Options{Server: addr, Zone: "example.com", TSIGKey: tc.key, Timeout: time.Second})
+			require.NoError(t, err)
+			assert.Error(t, p.Publish(context.Background(), nil, tc.records, PublishOptions{}))
+		})
+	}
+}
+
+func TestNewRFC2136Publisher(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRFC2136Publisher(RFC2136Options{Zone: "example.com"})
+	assert.Error(t, err)
+	_, err = NewRFC2136Publisher(RFC2136Options{Server: "192.0.2.1"})
+	assert.Error(t, err)
+	_, err = NewRFC2136Publisher(RFC2136Options{Server: "192.0.2.1", Zone: "example.com", TSIGKey: &TSIGKey{Name: "key", Algorithm: "hmac-md4", Secret: []byte("x")}})
+	assert.Error(t, err)
+	p, err := NewRFC2136Publisher(RFC2136Options{Server: "192.0.2.1", Zone: "Example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1:53", p.server)
+	assert.Equal(t, "example.com.", p.zone)
+}
@@ -0,0 +1,48 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// tsigFudge is the time difference, in seconds, allowed between the signer and the verifier.
+const tsigFudge = 300
+
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1.":   dns.HmacSHA1,
+	"hmac-sha224.": dns.HmacSHA224,
+	"hmac-sha256.": dns.HmacSHA256,
+	"hmac-sha384.": dns.HmacSHA384,
+	"hmac-sha512.": dns.HmacSHA512,
+}
+
+// TSIGKey is a shared secret used to authenticate DNS messages as described in RFC 8945.
+type TSIGKey struct {
+	// Name is the name of the key, as configured on the nameserver.
+	Name string
+	// Algorithm is the HMAC algorithm name, e.g. hmac-sha256.
+	Algorithm string
+	// Secret is the decoded shared secret.
+	Secret []byte
+}
+
+// Validate checks that the key name and algorithm are supported.
+func (k *TSIGKey) Validate() error {
+	if k.Name == "" {
+		return errors.New("TSIG key name must not be empty")
+	}
+	if _, ok := tsigAlgorithms[canonicalName(k.Algorithm)]; !ok {
+		return fmt.Errorf("unsupported TSIG algorithm %s", k.Algorithm)
+	}
+	if len(k.Secret) == 0 {
+		return errors.New("TSIG secret must not be empty")
+	}
+	return nil
+}
+
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
@@ -0,0 +1,269 @@
+package spf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Resolver looks up the DNS records needed to flatten SPF records. *net.Resolver implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Flatten replaces the includes of the record with the networks authorized by the included records,
+// so that receivers do not need to look them up. Included records are resolved recursively, following
+// their includes and redirects and resolving their a and mx mechanisms.
+//
+// Only records whose matching terms all pass can be flattened, as the order in which other
+// qualifiers apply is lost. Records containing ptr or exists mechanisms, or macros, cannot be flattened.
+func Flatten(ctx context.Context, resolver Resolver, r Record) (Record, error) {
+	f := &flattener{resolver: resolver}
+	flattened := Record{Mechanisms: slices.Clone(r.Mechanisms), All: r.All}
+	for _, include := range r.Includes {
+		terms, err := f.flatten(ctx, include, nil)
+		if err != nil {
+			return Record{}, err
+		}
+		for _, term := range terms {
+			if !slices.Contains(flattened.Mechanisms, term) {
+				flattened.Mechanisms = append(flattened.Mechanisms, term)
+			}
+		}
+	}
+	return flattened, nil
+}
+
+// CountLookups returns the number of DNS lookups the evaluation of the record causes, as counted against
+// MaxLookups by RFC 7208 section 4.6.4, including those of the records it includes and redirects to.
+// Included records are looked up with resolver. Includes whose domain contains macros are counted,
+// but cannot be followed.
+func CountLookups(ctx context.Context, resolver Resolver, r Record) (int, error) {
+	f := &flattener{resolver: resolver}
+	return f.countLookups(ctx, r.Terms(), nil)
+}
+
+// maxFlattenLookups bounds the number of DNS lookups done while flattening a record. It is higher than
+// MaxLookups, as flattening is precisely meant for records that would exceed it.
+const maxFlattenLookups = 100
+
+type flattener struct {
+	resolver Resolver
+	lookups  int
+}
+
+// lookup counts a DNS lookup, failing past maxFlattenLookups to bound the work done for misconfigured or hostile records.
+func (f *flattener) lookup(domain string) error {
+	f.lookups++
+	if f.lookups > maxFlattenLookups {
+		return fmt.Errorf("resolving %s requires more than %d DNS lookups", domain, maxFlattenLookups)
+	}
+	return nil
+}
+
+// flatten returns the ip4 and ip6 mechanisms equivalent to the SPF record of domain.
+// path lists the domains being flattened, to detect include loops.
+func (f *flattener) flatten(ctx context.Context, domain string, path []string) ([]string, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if slices.Contains(path, domain) {
+		return nil, fmt.Errorf("include loop detected: %s", strings.Join(append(path, domain), " -> "))
+	}
+	if strings.Contains(domain, "%") {
+		return nil, fmt.Errorf("cannot flatten %s: macros are not supported", domain)
+	}
+	path = append(path, domain)
+	terms, err := f.lookupRecord(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	var redirect string
+	for _, term := range terms {
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			continue
+		}
+		if strings.Contains(term, "%") {
+			return nil, fmt.Errorf("cannot flatten %s: macros are not supported in %q", domain, term)
+		}
+		qualifier, name, arg := parseMechanism(term)
+		if name == "all" {
+			if qualifier == '+' {
+				return nil, fmt.Errorf("cannot flatten %s: it authorizes all hosts", domain)
+			}
+			// The all mechanism ends the evaluation, and makes the redirect modifier ignored.
+			return out, nil
+		}
+		if qualifier != '+' {
+			return nil, fmt.Errorf("cannot flatten %s: %q does not pass", domain, term)
+		}
+		var mechanisms []string
+		switch name {
+		case "ip4", "ip6":
+			if !validIPNetwork(name, strings.TrimPrefix(arg, ":")) {
+				return nil, fmt.Errorf("invalid mechanism %q in the SPF record of %s", term, domain)
+			}
+			mechanisms = []string{name + arg}
+		case "a", "mx":
+			mechanisms, err = f.resolveHosts(ctx, domain, name, arg)
+		case "include":
+			mechanisms, err = f.flatten(ctx, strings.TrimPrefix(arg, ":"), path)
+		default:
+			return nil, fmt.Errorf("cannot flatten %s: %q cannot be flattened", domain, term)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range mechanisms {
+			if !slices.Contains(out, m) {
+				out = append(out, m)
+			}
+		}
+	}
+	if redirect == "" {
+		return out, nil
+	}
+	redirected, err := f.flatten(ctx, redirect, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range redirected {
+		if !slices.Contains(out, m) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// countLookups returns the number of DNS lookups caused by the terms of an SPF record, following includes and redirects.
+// path lists the domains being counted, to detect include loops.
+func (f *flattener) countLookups(ctx context.Context, terms []string, path []string) (int, error) {
+	hasAll := slices.ContainsFunc(terms, func(term string) bool {
+		_, name, _ := parseMechanism(term)
+		return name == "all"
+	})
+	n := 0
+	for _, term := range terms {
+		var target string
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			// The redirect modifier is ignored when the record has an all mechanism.
+			if !strings.EqualFold(name, "redirect") || hasAll {
+				continue
+			}
+			target = value
+		} else {
+			_, name, arg := parseMechanism(term)
+			if !slices.Contains(lookupMechanisms, name) {
+				continue
+			}
+			if name == "include" {
+				target = strings.TrimPrefix(arg, ":")
+			}
+		}
+		n++
+		if target == "" || strings.Contains(target, "%") {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSuffix(target, "."))
+		if slices.Contains(path, domain) {
+			return 0, fmt.Errorf("include loop detected: %s", strings.Join(append(path, domain), " -> "))
+		}
+		nested, err := f.lookupRecord(ctx, domain)
+		if err != nil {
+			return 0, err
+		}
+		count, err := f.countLookups(ctx, nested, append(path, domain))
+		if err != nil {
+			return 0, err
+		}
+		n += count
+	}
+	return n, nil
+}
+
+// lookupRecord returns the terms of the SPF record of domain, following the version.
+func (f *flattener) lookupRecord(ctx context.Context, domain string) ([]string, error) {
+	if err := f.lookup(domain); err != nil {
+		return nil, err
+	}
+	values, err := f.resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the SPF record of %s: %w", domain, err)
+	}
+	var records [][]string
+	for _, v := range values {
+		fields := strings.Fields(v)
+		if len(fields) > 0 && strings.EqualFold(fields[0], "v=spf1") {
+			records = append(records, fields[1:])
+		}
+	}
+	switch len(records) {
+	case 0:
+		return nil, fmt.Errorf("%s has no SPF record", domain)
+	case 1:
+		return records[0], nil
+	default:
+		return nil, fmt.Errorf("%s has more than one SPF record", domain)
+	}
+}
+
+// resolveHosts returns the ip4 and ip6 mechanisms equivalent to an a or mx mechanism of the SPF record of domain.
+func (f *flattener) resolveHosts(ctx context.Context, domain, name, arg string) ([]string, error) {
+	spec, v4, v6, err := splitDualCIDR(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s mechanism in the SPF record of %s: %w", name, domain, err)
+	}
+	target := domain
+	if spec != "" {
+		target = strings.TrimPrefix(spec, ":")
+	}
+	hosts := []string{target}
+	if name == "mx" {
+		if err := f.lookup(target); err != nil {
+			return nil, err
+		}
+		mxs, err := f.resolver.LookupMX(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the MX records of %s: %w", target, err)
+		}
+		hosts = hosts[:0]
+		for _, mx := range mxs {
+			hosts = append(hosts, mx.Host)
+		}
+	}
+	var out []string
+	for _, host := range hosts {
+		if err := f.lookup(host); err != nil {
+			return nil, err
+		}
+		addrs, err := f.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up the addresses of %s: %w", host, err)
+		}
+		for _, addr := range addrs {
+			out = append(out, networkMechanism(addr.IP, v4, v6))
+		}
+	}
+	return out, nil
+}
+
+// networkMechanism returns the ip4 or ip6 mechanism matching the network of ip with the given prefix length.
+func networkMechanism(ip net.IP, v4, v6 int) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		if v4 == 32 {
+			return "ip4:" + ip4.String()
+		}
+		return "ip4:" + (&net.IPNet{IP: ip4.Mask(net.CIDRMask(v4, 32)), Mask: net.CIDRMask(v4, 32)}).String()
+	}
+	if v6 == 128 {
+		return "ip6:" + ip.String()
+	}
+	return "ip6:" + (&net.IPNet{IP: ip.Mask(net.CIDRMask(v6, 128)), Mask: net.CIDRMask(v6, 128)}).String()
+}
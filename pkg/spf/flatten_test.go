@@ -0,0 +1,168 @@
+package spf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver answers lookups from in-memory records.
+type fakeResolver struct {
+	txt   map[string][]string
+	addrs map[string][]string
+	mx    map[string][]string
+}
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	values, ok := r.txt[name]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	return values, nil
+}
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	values, ok := r.addrs[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	addrs := make([]net.IPAddr, 0, len(values))
+	for _, v := range values {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(v)})
+	}
+	return addrs, nil
+}
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	values, ok := r.mx[name]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	mxs := make([]*net.MX, 0, len(values))
+	for _, v := range values {
+		mxs = append(mxs, &net.MX{Host: v, Pref: 10})
+	}
+	return mxs, nil
+}
+
+func TestFlatten(t *testing.T) {
+	t.Parallel()
+
+	resolver := fakeResolver{
+		txt: map[string][]string{
+			"_spf.example.net":   {"google-site-verification=abc", "v=spf1 include:_nets1.example.net include:_nets2.example.net ~all"},
+			"_nets1.example.net": {"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 ~all"},
+			"_nets2.example.net": {"v=spf1 ip4:192.0.2.0/24 a:relay.example.net/28 -all ip4:198.51.100.1"},
+			"mail.example.org":   {"v=spf1 mx redirect=_spf.example.org"},
+			"_spf.example.org":   {"v=spf1 ip4:203.0.113.1 a//64 -all"},
+		},
+		addrs: map[string][]string{
+			"relay.example.net": {"198.51.100.77"},
+			"mx1.example.org":   {"203.0.113.10", "2001:db8:1::10"},
+			"_spf.example.org":  {"2001:db8:2::1"},
+		},
+		mx: map[string][]string{
+			"mail.example.org": {"mx1.example.org"},
+		},
+	}
+
+	r := Record{
+		Mechanisms: []string{"-ip4:192.0.2.99", "mx"},
+		Includes:   []string{"_spf.example.net", "mail.example.org."},
+		All:        AllFail,
+	}
+	flattened, err := Flatten(context.Background(), resolver, r)
+	require.NoError(t, err)
+	assert.Equal(t, Record{
+		Mechanisms: []string{
+			"-ip4:192.0.2.99", "mx",
+			"ip4:192.0.2.0/24", "ip6:2001:db8::/32", "ip4:198.51.100.64/28",
+			"ip4:203.0.113.10", "ip6:2001:db8:1::10", "ip4:203.0.113.1", "ip6:2001:db8:2::/64",
+		},
+		All: AllFail,
+	}, flattened)
+	assert.Equal(t, 1, flattened.Lookups())
+	assert.Equal(t, []string{"_spf.example.net", "mail.example.org."}, r.Includes)
+}
+
+func TestFlattenErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		title string
+		txt   map[string][]string
+	}{
+		{title: "NoRecord", txt: map[string][]string{"_spf.example.net": {"v=DMARC1; p=none;"}}},
+		{title: "NotFound", txt: map[string][]string{}},
+		{title: "MultipleRecords", txt: map[string][]string{"_spf.example.net": {"v=spf1 -all", "v=spf1 ~all"}}},
+		{title: "Loop", txt: map[string][]string{
+			"_spf.example.net":  {"v=spf1 include:_loop.example.net -all"},
+			"_loop.example.net": {"v=spf1 include:_spf.example.net -all"},
+		}},
+		{title: "PassAll", txt: map[string][]string{"_spf.example.net": {"v=spf1 +all"}}},
+		{title: "NonPassMechanism", txt: map[string][]string{"_spf.example.net": {"v=spf1 -ip4:192.0.2.1 ip4:192.0.2.0/24 -all"}}},
+		{title: "Exists", txt: map[string][]string{"_spf.example.net": {"v=spf1 exists:example.net -all"}}},
+		{title: "Macro", txt: map[string][]string{"_spf.example.net": {"v=spf1 include:%{d}.example.org -all"}}},
+		{title: "InvalidNetwork", txt: map[string][]string{"_spf.example.net": {"v=spf1 ip4:192.0.2.300 -all"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			_, err := Flatten(context.Background(), fakeResolver{txt: tc.txt}, Record{Includes: []string{"_spf.example.net"}})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFlattenLookupLimit(t *testing.T) {
+	t.Parallel()
+
+	txt := map[string][]string{}
+	for i := range maxFlattenLookups {
+		txt[fmt.Sprintf("_spf%d.example.net", i)] = []string{fmt.Sprintf("v=spf1 include:_spf%d.example.net", i+1)}
+	}
+	_, err := Flatten(context.Background(), fakeResolver{txt: txt}, Record{Includes: []string{"_spf0.example.net"}})
+	assert.ErrorContains(t, err, "more than 100 DNS lookups")
+}
+
+func TestCountLookups(t *testing.T) {
+	t.Parallel()
+
+	resolver := fakeResolver{
+		txt: map[string][]string{
+			"_spf.example.net":   {"v=spf1 include:_nets1.example.net include:_nets2.example.net ~all"},
+			"_nets1.example.net": {"v=spf1 ip4:192.0.2.0/24 mx a:relay.example.net ~all"},
+			"_nets2.example.net": {"v=spf1 exists:%{i}.example.net redirect=_nets1.example.net"},
+			"_all.example.net":   {"v=spf1 -all redirect=_spf.example.net"},
+			"_loop.example.net":  {"v=spf1 include:_loop.example.net -all"},
+		},
+	}
+	cases := []struct {
+		title    string
+		record   Record
+		expected int
+	}{
+		{title: "NoIncludes", record: Record{Mechanisms: []string{"ip4:192.0.2.1", "mx"}, All: AllFail}, expected: 1},
+		// include:_spf, then include:_nets1 with mx and a, and include:_nets2 with exists and a redirect to _nets1.
+		{title: "Nested", record: Record{Includes: []string{"_spf.example.net"}}, expected: 1 + 1 + 2 + 1 + 1 + 1 + 2},
+		{title: "IgnoredRedirect", record: Record{Includes: []string{"_all.example.net"}}, expected: 1},
+		{title: "Macro", record: Record{Includes: []string{"%{d}.example.net"}}, expected: 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			n, err := CountLookups(context.Background(), resolver, tc.record)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, n)
+		})
+	}
+
+	_, err := CountLookups(context.Background(), resolver, Record{Includes: []string{"_loop.example.net"}})
+	assert.ErrorContains(t, err, "include loop")
+	_, err = CountLookups(context.Background(), resolver, Record{Includes: []string{"_missing.example.net"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,197 @@
+package spf
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// MaxLookups is the maximum number of DNS lookups the evaluation of an SPF record may cause,
+// as defined in RFC 7208 section 4.6.4.
+const MaxLookups = 10
+
+const (
+	AllFail     = "-all"
+	AllSoftFail = "~all"
+	AllNeutral  = "?all"
+	AllPass     = "+all"
+)
+
+var (
+	allTerms = []string{AllFail, AllSoftFail, AllNeutral, AllPass}
+	// lookupMechanisms are the mechanisms causing DNS lookups.
+	lookupMechanisms = []string{"a", "mx", "ptr", "exists", "include"}
+)
+
+// Record holds the terms of an SPF record, as defined in RFC 7208 section 4.6.
+type Record struct {
+	// Mechanisms are the mechanisms listed before the includes, e.g. ip4:192.0.2.0/24 or mx.
+	Mechanisms []string
+	// Includes are the domains whose SPF records are included, listed as include mechanisms.
+	Includes []string
+	// All is the final all mechanism, e.g. ~all. Omitted when empty.
+	All string
+}
+
+// Terms returns the terms of the record following the version, in order.
+func (r Record) Terms() []string {
+	terms := slices.Clone(r.Mechanisms)
+	for _, include := range r.Includes {
+		terms = append(terms, "include:"+include)
+	}
+	if r.All != "" {
+		terms = append(terms, r.All)
+	}
+	return terms
+}
+
+// Lookups returns the number of DNS lookups caused by the terms of the record itself,
+// not counting the ones of included records.
+func (r Record) Lookups() int {
+	n := 0
+	for _, term := range r.Terms() {
+		_, name, _ := parseMechanism(term)
+		if slices.Contains(lookupMechanisms, name) {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate checks that the record only contains valid mechanisms.
+func (r Record) Validate() error {
+	for _, m := range r.Mechanisms {
+		if err := ValidateMechanism(m); err != nil {
+			return err
+		}
+	}
+	for _, include := range r.Includes {
+		if err := validateDomainSpec(include); err != nil {
+			return fmt.Errorf("invalid include %q: %w", include, err)
+		}
+	}
+	if r.All != "" && !slices.Contains(allTerms, r.All) {
+		return fmt.Errorf("invalid all mechanism %q", r.All)
+	}
+	return nil
+}
+
+// ValidateMechanism checks that a term is a mechanism other than all and include, optionally prefixed by a qualifier.
+func ValidateMechanism(term string) error {
+	_, name, arg := parseMechanism(term)
+	switch name {
+	case "ip4", "ip6":
+		if !validIPNetwork(name, strings.TrimPrefix(arg, ":")) {
+			return fmt.Errorf("mechanism %q does not contain a valid %s network", term, name)
+		}
+	case "a", "mx":
+		spec, _, _, err := splitDualCIDR(arg)
+		if err != nil {
+			return fmt.Errorf("invalid mechanism %q: %w", term, err)
+		}
+		if spec != "" {
+			if err := validateDomainSpec(strings.TrimPrefix(spec, ":")); err != nil {
+				return fmt.Errorf("invalid mechanism %q: %w", term, err)
+			}
+		}
+	case "ptr":
+		if arg != "" {
+			if err := validateDomainSpec(strings.TrimPrefix(arg, ":")); err != nil {
+				return fmt.Errorf("invalid mechanism %q: %w", term, err)
+			}
+		}
+	case "include":
+		return fmt.Errorf("mechanism %q must be listed as an include", term)
+	case "exists":
+		if err := validateDomainSpec(strings.TrimPrefix(arg, ":")); err != nil {
+			return fmt.Errorf("invalid mechanism %q: %w", term, err)
+		}
+	case "all":
+		return fmt.Errorf("mechanism %q must be set as the all mechanism of the record", term)
+	default:
+		return fmt.Errorf("unknown mechanism %q", term)
+	}
+	return nil
+}
+
+// GenTXTValue generates the SPF record in presentation format.
+func (r Record) GenTXTValue() string {
+	return dkim.FormatTXTValue(strings.Join(append([]string{"v=spf1"}, r.Terms()...), " "))
+}
+
+// parseMechanism splits a mechanism into its qualifier, its lowercased name and the rest
+// of the term, starting with the : or / separating it from the name.
+func parseMechanism(term string) (byte, string, string) {
+	qualifier := byte('+')
+	if term != "" && strings.IndexByte("+-~?", term[0]) >= 0 {
+		qualifier = term[0]
+		term = term[1:]
+	}
+	i := strings.IndexAny(term, ":/")
+	if i < 0 {
+		return qualifier, strings.ToLower(term), ""
+	}
+	return qualifier, strings.ToLower(term[:i]), term[i:]
+}
+
+// splitDualCIDR splits the argument of an a or mx mechanism into its domain-spec, and its
+// IPv4 and IPv6 prefix lengths, which default to 32 and 128.
+func splitDualCIDR(arg string) (string, int, int, error) {
+	v4, v6 := 32, 128
+	var err error
+	if i := strings.Index(arg, "//"); i >= 0 {
+		if v6, err = parsePrefixLength(arg[i+2:], 128); err != nil {
+			return "", 0, 0, err
+		}
+		arg = arg[:i]
+	}
+	if i := strings.IndexByte(arg, '/'); i >= 0 {
+		if v4, err = parsePrefixLength(arg[i+1:], 32); err != nil {
+			return "", 0, 0, err
+		}
+		arg = arg[:i]
+	}
+	return arg, v4, v6, nil
+}
+
+func parsePrefixLength(s string, maxLength int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > maxLength || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("invalid prefix length %q", s)
+	}
+	return n, nil
+}
+
+func validIPNetwork(name, network string) bool {
+	addr, prefix, hasPrefix := strings.Cut(network, "/")
+	ip := net.ParseIP(addr)
+	if ip == nil || (name == "ip4") != (ip.To4() != nil) {
+		return false
+	}
+	if !hasPrefix {
+		return true
+	}
+	maxLength := 128
+	if name == "ip4" {
+		maxLength = 32
+	}
+	_, err := parsePrefixLength(prefix, maxLength)
+	return err == nil
+}
+
+// validateDomainSpec checks that a domain-spec can be listed in an SPF record. Macros are allowed.
+func validateDomainSpec(spec string) error {
+	if spec == "" {
+		return fmt.Errorf("domain must not be empty")
+	}
+	for _, c := range spec {
+		if c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return fmt.Errorf("domain %q contains invalid character %q", spec, c)
+		}
+	}
+	return nil
+}
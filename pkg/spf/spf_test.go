@@ -0,0 +1,91 @@
+package spf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenTXTValue(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title    string
+		record   Record
+		expected string
+	}{
+		{
+			title:    "Empty",
+			record:   Record{},
+			expected: `"v=spf1"`,
+		},
+		{
+			title: "AllTerms",
+			record: Record{
+				Mechanisms: []string{"ip4:192.0.2.0/24", "mx"},
+				Includes:   []string{"_spf.example.net"},
+				All:        AllFail,
+			},
+			expected: `"v=spf1 ip4:192.0.2.0/24 mx include:_spf.example.net -all"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, tc.record.GenTXTValue())
+		})
+	}
+}
+
+func TestLookups(t *testing.T) {
+	t.Parallel()
+	r := Record{
+		Mechanisms: []string{"ip4:192.0.2.1", "a", "-mx:example.net/24", "ip6:2001:db8::/32", "exists:%{i}.example.com"},
+		Includes:   []string{"_spf.example.net", "_spf.example.org"},
+		All:        AllSoftFail,
+	}
+	assert.Equal(t, 5, r.Lookups())
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title  string
+		record Record
+		valid  bool
+	}{
+		{
+			title: "Valid",
+			record: Record{
+				Mechanisms: []string{
+					"ip4:192.0.2.1", "ip4:192.0.2.0/24", "-ip6:2001:db8::/32", "a", "a/24", "a:mail.example.com//64",
+					"~mx:example.com/24//64", "ptr", "?exists:%{i}._spf.example.com",
+				},
+				Includes: []string{"_spf.example.net"},
+				All:      AllNeutral,
+			},
+			valid: true,
+		},
+		{title: "IPv6InIP4", record: Record{Mechanisms: []string{"ip4:2001:db8::1"}}},
+		{title: "IPv4InIP6", record: Record{Mechanisms: []string{"ip6:192.0.2.1"}}},
+		{title: "MissingNetwork", record: Record{Mechanisms: []string{"ip4"}}},
+		{title: "InvalidPrefixLength", record: Record{Mechanisms: []string{"ip4:192.0.2.0/33"}}},
+		{title: "InvalidDualPrefixLength", record: Record{Mechanisms: []string{"a//129"}}},
+		{title: "MissingDomain", record: Record{Mechanisms: []string{"exists"}}},
+		{title: "Include", record: Record{Mechanisms: []string{"include:_spf.example.net"}}},
+		{title: "All", record: Record{Mechanisms: []string{"-all"}}},
+		{title: "Modifier", record: Record{Mechanisms: []string{"redirect=_spf.example.net"}}},
+		{title: "InvalidInclude", record: Record{Includes: []string{"_spf example.net"}}},
+		{title: "InvalidAll", record: Record{All: "all"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := tc.record.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
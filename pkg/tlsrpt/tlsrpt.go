@@ -0,0 +1,63 @@
+package tlsrpt
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hsn723/dkim-manager/pkg/dkim"
+)
+
+// Record holds the fields of a TLS-RPT record, as defined in RFC 8460 section 3.
+type Record struct {
+	// ReportURIs are the mailto or https URIs reports are sent to (rua=).
+	ReportURIs []string
+}
+
+// RecordName returns the DNS name under which the TLS-RPT record of the domain is published.
+func RecordName(domain string) string {
+	return "_smtp._tls." + domain
+}
+
+// Validate checks that the record lists at least one valid report URI.
+func (r Record) Validate() error {
+	if len(r.ReportURIs) == 0 {
+		return fmt.Errorf("at least one report URI is required")
+	}
+	for _, uri := range r.ReportURIs {
+		if err := ValidateReportURI(uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateReportURI checks that a report URI is a mailto or https URI that can be listed in a TLS-RPT record
+// without escaping.
+func ValidateReportURI(uri string) error {
+	if strings.ContainsAny(uri, " \t,;!\"\\") {
+		return fmt.Errorf("report URI %q contains characters not allowed in TLS-RPT records", uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid report URI %q: %w", uri, err)
+	}
+	switch u.Scheme {
+	case "mailto":
+		if local, domain, ok := strings.Cut(u.Opaque, "@"); !ok || local == "" || domain == "" {
+			return fmt.Errorf("report URI %q does not contain a valid email address", uri)
+		}
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("report URI %q does not contain a host", uri)
+		}
+	default:
+		return fmt.Errorf("report URI %q must be a mailto or https URI", uri)
+	}
+	return nil
+}
+
+// GenTXTValue generates the TLS-RPT record in presentation format.
+func (r Record) GenTXTValue() string {
+	return dkim.FormatTXTValue("v=TLSRPTv1; rua=" + strings.Join(r.ReportURIs, ",") + ";")
+}
@@ -0,0 +1,45 @@
+package tlsrpt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "_smtp._tls.example.com", RecordName("example.com"))
+}
+
+func TestGenTXTValue(t *testing.T) {
+	t.Parallel()
+	r := Record{ReportURIs: []string{"mailto:tlsrpt@example.com", "https://reports.example.com/v1/tlsrpt"}}
+	assert.Equal(t, `"v=TLSRPTv1; rua=mailto:tlsrpt@example.com,https://reports.example.com/v1/tlsrpt;"`, r.GenTXTValue())
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		title string
+		uris  []string
+		valid bool
+	}{
+		{title: "Valid", uris: []string{"mailto:tlsrpt@example.com", "https://reports.example.com/v1/tlsrpt"}, valid: true},
+		{title: "Empty"},
+		{title: "HTTP", uris: []string{"http://reports.example.com/"}},
+		{title: "MissingAddress", uris: []string{"mailto:example.com"}},
+		{title: "MissingHost", uris: []string{"https:///tlsrpt"}},
+		{title: "Separator", uris: []string{"mailto:tlsrpt@example.com,mailto:other@example.com"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			err := Record{ReportURIs: tc.uris}.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}